@@ -0,0 +1,324 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+// Format identifies issue-migrator archives in their manifest
+const Format = "issue-migrator-archive"
+
+// Version is the archive layout written by this package. Version 1 manifests
+// only held the names of the labels and milestones in use.
+const Version = 2
+
+const manifestName = "manifest.json"
+
+// An archive is a zip file holding the exported issues of one project:
+//
+//	manifest.json                     Manifest
+//	issues/<number>.json              Issue with its comments
+//	attachments/<number>/<n>-<name>   downloaded attachment files
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	Format     string      `json:"format"`
+	Version    int         `json:"version"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Source     Source      `json:"source"`
+	Issues     []int       `json:"issues"` // numbers of the exported issues
+	Labels     []Label     `json:"labels"`
+	Milestones []Milestone `json:"milestones"`
+}
+
+// Label is a label of the exported project, or one only found on its issues
+type Label struct {
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"` // hex RGB without "#"
+	Description string `json:"description,omitempty"`
+}
+
+// UnmarshalJSON reads a label, or the name of one as in version 1 manifests
+func (l *Label) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*l = Label{}
+		return json.Unmarshal(data, &l.Name)
+	}
+	type plain Label
+	return json.Unmarshal(data, (*plain)(l))
+}
+
+// Milestone is a milestone of the exported project, or one only found on its issues
+type Milestone struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	State       string     `json:"state,omitempty"` // "open" or "closed"; empty if unknown
+}
+
+// UnmarshalJSON reads a milestone, or the title of one as in version 1 manifests
+func (m *Milestone) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*m = Milestone{}
+		return json.Unmarshal(data, &m.Title)
+	}
+	type plain Milestone
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// Source identifies the tracker and project an archive was exported from
+type Source struct {
+	Platform string `json:"platform"` // e.g. "github"
+	Name     string `json:"name"`     // e.g. "GitHub"
+	Project  string `json:"project"`  // as used in migration markers
+}
+
+// Issue is an exported issue with its comments and attachment files
+type Issue struct {
+	Number       int          `json:"number"`
+	Title        string       `json:"title"`
+	Body         string       `json:"body"`
+	State        string       `json:"state"` // "open" or "closed"
+	Labels       []string     `json:"labels"`
+	Milestone    string       `json:"milestone,omitempty"`
+	Author       string       `json:"author"`
+	Assignees    []string     `json:"assignees,omitempty"`
+	URL          string       `json:"url"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	ClosedAt     *time.Time   `json:"closed_at,omitempty"`
+	DueDate      *time.Time   `json:"due_date,omitempty"`
+	Weight       *int         `json:"weight,omitempty"`        // GitLab only
+	Confidential bool         `json:"confidential,omitempty"`  // GitLab only
+	HealthStatus string       `json:"health_status,omitempty"` // GitLab only
+	Epic         string       `json:"epic,omitempty"`          // GitLab only
+	Comments     []Comment    `json:"comments"`
+	Events       []Event      `json:"events,omitempty"` // history of the issue, oldest first
+	Attachments  []Attachment `json:"attachments"`      // files linked from the body or the comments
+}
+
+// Comment is an exported comment
+type Comment struct {
+	ID         int64     `json:"id"`
+	Body       string    `json:"body"`
+	Author     string    `json:"author"`
+	URL        string    `json:"url"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	System     bool      `json:"system,omitempty"`
+	InReplyTo  int64     `json:"in_reply_to,omitempty"` // previous comment in the thread of a reply
+	Resolved   bool      `json:"resolved,omitempty"`    // first comment of a resolved thread
+	ResolvedBy string    `json:"resolved_by,omitempty"`
+}
+
+// Event is an exported change in the history of an issue
+type Event struct {
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Subject   string    `json:"subject,omitempty"`
+	Previous  string    `json:"previous,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Attachment is a downloaded file stored in the archive
+type Attachment struct {
+	URL      string `json:"url"` // as linked from the issue and comment bodies
+	Filename string `json:"filename"`
+	Path     string `json:"path"` // location of the file inside the archive
+	IsImage  bool   `json:"is_image"`
+	Size     int64  `json:"size"`
+}
+
+// Writer writes an archive. Attachments of an issue are added before the
+// issue itself; Close writes the manifest.
+type Writer struct {
+	path       string
+	file       *os.File
+	zip        *zip.Writer
+	manifest   Manifest
+	labels     map[string]Label
+	milestones map[string]Milestone
+	files      int
+}
+
+// Create starts an archive at path. The file is written under a temporary
+// name and only appears at path once Close succeeds.
+func Create(filePath string, source Source) (*Writer, error) {
+	file, err := os.Create(filePath + ".partial")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	return &Writer{
+		path: filePath,
+		file: file,
+		zip:  zip.NewWriter(file),
+		manifest: Manifest{
+			Format:  Format,
+			Version: Version,
+			Source:  source,
+			Issues:  []int{},
+		},
+		labels:     make(map[string]Label),
+		milestones: make(map[string]Milestone),
+	}, nil
+}
+
+// AddAttachment stores a file for an issue and returns its path in the archive
+func (w *Writer) AddAttachment(issueNumber int, filename string, data []byte) (string, error) {
+	w.files++
+	name := path.Join("attachments", fmt.Sprint(issueNumber), fmt.Sprintf("%d-%s", w.files, path.Base(filename)))
+	entry, err := w.zip.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := entry.Write(data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// AddIssue stores an issue and its comments
+func (w *Writer) AddIssue(issue *Issue) error {
+	if err := w.writeJSON(path.Join("issues", fmt.Sprintf("%d.json", issue.Number)), issue); err != nil {
+		return err
+	}
+	w.manifest.Issues = append(w.manifest.Issues, issue.Number)
+	for _, label := range issue.Labels {
+		if _, ok := w.labels[label]; !ok {
+			w.labels[label] = Label{Name: label}
+		}
+	}
+	if _, ok := w.milestones[issue.Milestone]; !ok && issue.Milestone != "" {
+		w.milestones[issue.Milestone] = Milestone{Title: issue.Milestone}
+	}
+	return nil
+}
+
+// AddLabel records a label definition of the project
+func (w *Writer) AddLabel(label Label) {
+	w.labels[label.Name] = label
+}
+
+// AddMilestone records a milestone of the project
+func (w *Writer) AddMilestone(milestone Milestone) {
+	w.milestones[milestone.Title] = milestone
+}
+
+// Close writes the manifest and moves the finished archive into place
+func (w *Writer) Close() error {
+	w.manifest.ExportedAt = time.Now().UTC()
+	w.manifest.Labels = make([]Label, 0, len(w.labels))
+	for _, name := range sortedKeys(w.labels) {
+		w.manifest.Labels = append(w.manifest.Labels, w.labels[name])
+	}
+	w.manifest.Milestones = make([]Milestone, 0, len(w.milestones))
+	for _, title := range sortedKeys(w.milestones) {
+		w.manifest.Milestones = append(w.manifest.Milestones, w.milestones[title])
+	}
+
+	err := w.writeJSON(manifestName, w.manifest)
+	if err == nil {
+		err = w.zip.Close()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return os.Rename(w.file.Name(), w.path)
+}
+
+func (w *Writer) writeJSON(name string, value interface{}) error {
+	entry, err := w.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// Reader reads an archive written by Writer
+type Reader struct {
+	zip      *zip.ReadCloser
+	files    map[string]*zip.File
+	Manifest Manifest
+}
+
+// Open opens an archive and reads its manifest
+func Open(filePath string) (*Reader, error) {
+	zipReader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	r := &Reader{
+		zip:   zipReader,
+		files: make(map[string]*zip.File, len(zipReader.File)),
+	}
+	for _, file := range zipReader.File {
+		r.files[file.Name] = file
+	}
+
+	if err := r.readJSON(manifestName, &r.Manifest); err != nil {
+		zipReader.Close()
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	if r.Manifest.Format != Format || r.Manifest.Version > Version {
+		zipReader.Close()
+		return nil, fmt.Errorf("unsupported archive format %q version %d", r.Manifest.Format, r.Manifest.Version)
+	}
+	return r, nil
+}
+
+// Issue reads an exported issue with its comments
+func (r *Reader) Issue(number int) (*Issue, error) {
+	var issue Issue
+	if err := r.readJSON(path.Join("issues", fmt.Sprintf("%d.json", number)), &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// ReadFile returns the content of an attachment file
+func (r *Reader) ReadFile(name string) ([]byte, error) {
+	file, ok := r.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the archive", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Close closes the archive file
+func (r *Reader) Close() error {
+	return r.zip.Close()
+}
+
+func (r *Reader) readJSON(name string, out interface{}) error {
+	data, err := r.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func sortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/archive"
+	"github.com/issue-migrator/backend/models"
+)
+
+// GetArchiveIssues lists the issues stored in an offline archive
+func GetArchiveIssues(c *gin.Context) {
+	var req models.ArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fmt.Printf("[ARCHIVE] Reading issues from %s\n", req.ArchivePath)
+	provider, err := newArchiveProvider(models.Endpoint{Type: "archive", ArchivePath: req.ArchivePath})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer provider.Close()
+
+	issues, err := provider.ListIssues(time.Time{})
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to read archived issues: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read archived issues: %v", err)})
+		return
+	}
+
+	convertedIssues := make([]models.Issue, len(issues))
+	for i, issue := range issues {
+		convertedIssues[i] = models.Issue{
+			ID:          issue.Number,
+			Title:       issue.Title,
+			Description: issue.Body,
+			State:       issue.State,
+			Labels:      issue.Labels,
+			Milestone:   issue.Milestone,
+			Author:      issue.Author,
+			Assignees:   issue.Assignees,
+			DueDate:     issue.DueDate,
+			CreatedAt:   issue.CreatedAt,
+			UpdatedAt:   issue.UpdatedAt,
+			URL:         issue.URL,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"issues": convertedIssues,
+		"count":  len(convertedIssues),
+		"source": provider.reader.Manifest.Source,
+	})
+}
+
+// UploadArchive stores an archive uploaded as the multipart field "file" on
+// the backend host and returns the archive_path to migrate from
+func UploadArchive(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	dir := filepath.Join(exportDir(), "imports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create import directory: %v", err)})
+		return
+	}
+	archivePath := filepath.Join(dir, generateJobID()+".zip")
+	if err := c.SaveUploadedFile(file, archivePath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to store archive: %v", err)})
+		return
+	}
+
+	reader, err := archive.Open(archivePath)
+	if err != nil {
+		os.Remove(archivePath)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer reader.Close()
+
+	fmt.Printf("[ARCHIVE] Stored %s with %d issue(s) from %s %s\n", archivePath, len(reader.Manifest.Issues),
+		reader.Manifest.Source.Name, reader.Manifest.Source.Project)
+	c.JSON(http.StatusOK, gin.H{
+		"archive_path": archivePath,
+		"source":       reader.Manifest.Source,
+		"count":        len(reader.Manifest.Issues),
+	})
+}
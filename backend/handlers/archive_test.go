@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+func TestMigrateFromArchive(t *testing.T) {
+	archived := exportTestArchive(t, testExportSource(), []int{1, 2})
+	target := newFakeProvider("github", "owner/repo")
+	req := models.MigrationRequest{
+		Source:   models.Endpoint{Type: "archive", ArchivePath: "issues.zip"},
+		Target:   models.Endpoint{Type: "github", Owner: "owner", Repo: "repo"},
+		IssueIDs: []int{1, 2},
+	}
+
+	result := migrateIssues(req, newJob(req), archived, target)
+	if len(result.Failed) > 0 || len(result.Success) != 2 {
+		t.Fatalf("result %+v, want both issues migrated", result)
+	}
+
+	tests := []struct {
+		name     string
+		source   int
+		title    string
+		state    string
+		comments int
+		contains []string // parts of the target body
+	}{
+		{
+			name:     "issue with an attachment and a thread",
+			source:   1,
+			title:    "Crash on start",
+			state:    "open",
+			comments: 2,
+			contains: []string{"https://github.example.com/owner/repo/uploads/"},
+		},
+		{
+			name:     "closed issue with metadata",
+			source:   2,
+			title:    "Plan the release",
+			state:    "closed",
+			contains: []string{"Steps to follow", "Release 1.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var number int
+			for _, status := range result.Success {
+				if status.OriginalID == tt.source {
+					number = status.NewID
+				}
+			}
+			issue, err := target.GetIssue(number)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if issue.Title != tt.title || issue.State != tt.state {
+				t.Errorf("target issue %q (%s), want %q (%s)", issue.Title, issue.State, tt.title, tt.state)
+			}
+			// The markers are those of a migration straight from the exported project
+			marker := issueMarker("gitlab", "group/project", tt.source)
+			for _, part := range append(tt.contains, marker) {
+				if !strings.Contains(issue.Body, part) {
+					t.Errorf("target body does not contain %q:\n%s", part, issue.Body)
+				}
+			}
+			if strings.Contains(issue.Body, testAttachment) {
+				t.Errorf("target body still links to the source attachment:\n%s", issue.Body)
+			}
+			if got := len(target.comments[number]); got != tt.comments {
+				t.Errorf("%d comment(s), want %d", got, tt.comments)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/models"
+)
+
+// GetAzureIssues lists the work items of an Azure DevOps project
+func GetAzureIssues(c *gin.Context) {
+	var req models.AzureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fmt.Printf("[AZURE] Fetching work items for %s/%s\n", req.Owner, req.Repo)
+	provider := newAzureProvider(models.Endpoint{
+		Type:    "azure",
+		BaseURL: req.BaseURL,
+		Owner:   req.Owner,
+		Repo:    req.Repo,
+		Token:   req.Token,
+	})
+	issues, err := provider.ListIssues(time.Time{})
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to list Azure DevOps work items: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch Azure DevOps work items: %v", err)})
+		return
+	}
+
+	convertedIssues := make([]models.Issue, len(issues))
+	for i, issue := range issues {
+		convertedIssues[i] = models.Issue{
+			ID:          issue.Number,
+			Title:       issue.Title,
+			Description: issue.Body,
+			State:       issue.State,
+			Labels:      issue.Labels,
+			Milestone:   issue.Milestone,
+			Author:      issue.Author,
+			Assignees:   issue.Assignees,
+			DueDate:     issue.DueDate,
+			CreatedAt:   issue.CreatedAt,
+			UpdatedAt:   issue.UpdatedAt,
+			URL:         issue.URL,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"issues": convertedIssues,
+		"count":  len(convertedIssues),
+	})
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	htmlSpaceRegex      = regexp.MustCompile(`[ \t\r\n]+`)
+	htmlBlankLinesRegex = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToMarkdown converts the HTML of Azure DevOps rich-text fields and
+// comments to Markdown. Markup without a Markdown equivalent keeps its text;
+// comments, which may hold migration markers, are kept as they are.
+func htmlToMarkdown(text string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(text), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return text
+	}
+	r := &htmlRenderer{}
+	out := r.blocks(nodes)
+	return strings.TrimSpace(htmlBlankLinesRegex.ReplaceAllString(out, "\n\n"))
+}
+
+// htmlRenderer renders parsed HTML nodes as Markdown
+type htmlRenderer struct{}
+
+// blocks renders sibling nodes, separating block elements by blank lines
+func (r *htmlRenderer) blocks(nodes []*html.Node) string {
+	var b strings.Builder
+	var inline []*html.Node
+	flush := func() {
+		if text := strings.TrimSpace(r.inline(inline)); text != "" {
+			b.WriteString(text + "\n\n")
+		}
+		inline = nil
+	}
+	for _, node := range nodes {
+		if !htmlIsBlock(node) {
+			inline = append(inline, node)
+			continue
+		}
+		flush()
+		if block := r.block(node); block != "" {
+			b.WriteString(block + "\n\n")
+		}
+	}
+	flush()
+	return b.String()
+}
+
+func (r *htmlRenderer) block(node *html.Node) string {
+	switch node.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(node.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(r.inline(htmlChildren(node)))
+	case atom.Pre:
+		code := strings.TrimSuffix(htmlText(node), "\n")
+		return "```\n" + code + "\n```"
+	case atom.Blockquote:
+		return prefixLines(strings.TrimSpace(r.blocks(htmlChildren(node))), "> ", "> ")
+	case atom.Ul, atom.Ol:
+		return r.list(node, 0)
+	case atom.Table:
+		return r.table(node)
+	case atom.Hr:
+		return "---"
+	default:
+		// p, div and any other container
+		return strings.TrimSpace(r.blocks(htmlChildren(node)))
+	}
+}
+
+func (r *htmlRenderer) list(node *html.Node, depth int) string {
+	var lines []string
+	index := 1
+	for item := node.FirstChild; item != nil; item = item.NextSibling {
+		if item.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if node.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+		var content []*html.Node
+		var nested []string
+		for child := item.FirstChild; child != nil; child = child.NextSibling {
+			if child.DataAtom == atom.Ul || child.DataAtom == atom.Ol {
+				nested = append(nested, r.list(child, depth+1))
+				continue
+			}
+			content = append(content, child)
+		}
+		text := strings.TrimSpace(r.blocks(content))
+		indent := strings.Repeat("  ", depth)
+		lines = append(lines, prefixLines(text, indent+marker, indent+strings.Repeat(" ", len(marker))))
+		lines = append(lines, nested...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *htmlRenderer) table(node *html.Node) string {
+	var rows [][]string
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.DataAtom != atom.Tr {
+				visit(child)
+				continue
+			}
+			var cells []string
+			for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+					text := strings.TrimSpace(r.inline(htmlChildren(cell)))
+					text = strings.ReplaceAll(text, "  \n", "<br>")
+					cells = append(cells, strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", `\|`))
+				}
+			}
+			rows = append(rows, cells)
+		}
+	}
+	visit(node)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	lines := []string{"| " + strings.Join(rows[0], " | ") + " |"}
+	separator := make([]string, len(rows[0]))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	lines = append(lines, "| "+strings.Join(separator, " | ")+" |")
+	for _, row := range rows[1:] {
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *htmlRenderer) inline(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case html.TextNode:
+			b.WriteString(htmlSpaceRegex.ReplaceAllString(node.Data, " "))
+			continue
+		case html.CommentNode:
+			b.WriteString("<!--" + node.Data + "-->")
+			continue
+		case html.ElementNode:
+		default:
+			continue
+		}
+
+		switch node.DataAtom {
+		case atom.Br:
+			// A hard line break; a bare newline would join the lines
+			b.WriteString("  \n")
+		case atom.Strong, atom.B:
+			b.WriteString(htmlWrap(r.inline(htmlChildren(node)), "**"))
+		case atom.Em, atom.I:
+			b.WriteString(htmlWrap(r.inline(htmlChildren(node)), "_"))
+		case atom.S, atom.Strike, atom.Del:
+			b.WriteString(htmlWrap(r.inline(htmlChildren(node)), "~~"))
+		case atom.Code:
+			b.WriteString("`" + htmlText(node) + "`")
+		case atom.A:
+			text := strings.TrimSpace(r.inline(htmlChildren(node)))
+			href := htmlAttr(node, "href")
+			switch {
+			case href == "":
+				b.WriteString(text)
+			case text == "" || text == href:
+				b.WriteString("<" + href + ">")
+			default:
+				b.WriteString(fmt.Sprintf("[%s](%s)", text, href))
+			}
+		case atom.Img:
+			b.WriteString(fmt.Sprintf("![%s](%s)", htmlAttr(node, "alt"), htmlAttr(node, "src")))
+		default:
+			if htmlIsBlock(node) {
+				b.WriteString("\n" + r.block(node) + "\n")
+			} else {
+				b.WriteString(r.inline(htmlChildren(node)))
+			}
+		}
+	}
+	return b.String()
+}
+
+// htmlWrap surrounds text with an emphasis marker, keeping surrounding
+// whitespace outside of it so the Markdown stays valid
+func htmlWrap(text string, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + marker + trimmed + marker + text[start+len(trimmed):]
+}
+
+func htmlIsBlock(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	switch node.DataAtom {
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Pre, atom.Blockquote, atom.Ul, atom.Ol, atom.Table, atom.Hr:
+		return true
+	}
+	return false
+}
+
+func htmlChildren(node *html.Node) []*html.Node {
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, child)
+	}
+	return children
+}
+
+// htmlText returns the text of a node and its descendants with line breaks kept
+func htmlText(node *html.Node) string {
+	var b strings.Builder
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteString("\n")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(node)
+	return b.String()
+}
+
+func htmlAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import "testing"
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"paragraphs", "<p>a</p><p>b</p>", "a\n\nb"},
+		{"line break", "<div>one<br>two</div>", "one  \ntwo"},
+		{"trailing line break", "<div>one<br></div>", "one"},
+		{"emphasis", "<b>bold</b> <i>it</i> <s>gone</s> <code>x</code>", "**bold** _it_ ~~gone~~ `x`"},
+		{"link", `<a href="https://example.com">docs</a> <a href="https://example.com">https://example.com</a>`, "[docs](https://example.com) <https://example.com>"},
+		{"image", `<img src="https://example.com/a.png" alt="shot">`, "![shot](https://example.com/a.png)"},
+		{"heading", "<h2>Steps</h2>", "## Steps"},
+		{"code block", "<pre>a\n  b</pre>", "```\na\n  b\n```"},
+		{"quote", "<blockquote><p>a</p><p>b</p></blockquote>", "> a\n>\n> b"},
+		{"lists", "<ul><li>a<ol><li>b</li></ol></li></ul>", "- a\n  1. b"},
+		{"table", "<table><tr><th>A</th></tr><tr><td>x|y<br>z</td></tr></table>", "| A |\n| --- |\n| x\\|y<br>z |"},
+		{"comment", "<p>text</p><!-- migrated-from: github:o/r#1 -->", "text\n\n<!-- migrated-from: github:o/r#1 -->"},
+		{"inline comment", "<p>a <!-- marker --> b</p>", "a <!-- marker --> b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToMarkdown(tt.html); got != tt.want {
+				t.Errorf("htmlToMarkdown(%q)\n got: %q\nwant: %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
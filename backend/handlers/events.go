@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/models"
+)
+
+// Event types streamed for a migration job
+const (
+	EventIssueStarted       = "issue_started"
+	EventAttachmentUploaded = "attachment_uploaded"
+	EventCommentCreated     = "comment_created"
+	EventIssueCompleted     = "issue_completed"
+	EventIssueFailed        = "issue_failed"
+	EventJobFinished        = "job_finished"
+)
+
+// sseKeepAlive is how often a comment line is written to keep proxies from closing idle streams
+const sseKeepAlive = 15 * time.Second
+
+// emit records an event on the job and wakes up every stream waiting for it
+func (j *Job) emit(event models.MigrationEvent) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.appendEventLocked(event)
+}
+
+// appendEventLocked adds an event while j.mu is held
+func (j *Job) appendEventLocked(event models.MigrationEvent) {
+	event.Seq = len(j.events) + 1
+	event.JobID = j.state.ID
+	event.Time = time.Now().UTC()
+	j.events = append(j.events, event)
+
+	close(j.notify)
+	j.notify = make(chan struct{})
+}
+
+// eventsSince returns the events after seq, whether the job is finished,
+// and a channel that is closed when the next event arrives
+func (j *Job) eventsSince(seq int) ([]models.MigrationEvent, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if seq < 0 {
+		seq = 0
+	}
+	var pending []models.MigrationEvent
+	if seq < len(j.events) {
+		pending = append(pending, j.events[seq:]...)
+	}
+	return pending, j.state.Status == JobCompleted, j.notify
+}
+
+// attachmentUploaded reports a re-uploaded attachment for an issue
+func (j *Job) attachmentUploaded(issueID int, originalURL string, newURL string) {
+	j.emit(models.MigrationEvent{
+		Type:    EventAttachmentUploaded,
+		IssueID: issueID,
+		URL:     newURL,
+		Message: originalURL,
+	})
+}
+
+// commentCreated reports a comment written on the target issue
+func (j *Job) commentCreated(issueID int, newID int, url string) {
+	j.emit(models.MigrationEvent{
+		Type:    EventCommentCreated,
+		IssueID: issueID,
+		NewID:   newID,
+		URL:     url,
+	})
+}
+
+// StreamJobEvents streams the events of a migration job as Server-Sent Events.
+// Clients reconnecting with a Last-Event-ID header only receive the events they missed.
+func StreamJobEvents(c *gin.Context) {
+	job, ok := getJob(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	lastSeq := 0
+	if lastID := c.GetHeader("Last-Event-ID"); lastID != "" {
+		if seq, err := strconv.Atoi(lastID); err == nil {
+			lastSeq = seq
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable nginx response buffering
+
+	fmt.Printf("[EVENTS] Client subscribed to job %s (after event %d)\n", job.ID(), lastSeq)
+
+	ctx := c.Request.Context()
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		events, finished, wait := job.eventsSince(lastSeq)
+		for _, event := range events {
+			c.Render(-1, sse.Event{
+				Id:    strconv.Itoa(event.Seq),
+				Event: event.Type,
+				Data:  event,
+			})
+			lastSeq = event.Seq
+		}
+		c.Writer.Flush()
+
+		if finished {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("[EVENTS] Client disconnected from job %s\n", job.ID())
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case <-wait:
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/archive"
+	"github.com/issue-migrator/backend/models"
+)
+
+var jobIDRegex = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// exportDir returns the directory export archives are written to
+func exportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return "exports"
+}
+
+// exportPath returns the location of the archive written by an export job
+func exportPath(jobID string) string {
+	return filepath.Join(exportDir(), jobID+".zip")
+}
+
+// ExportIssues starts a background job that writes the issues of a project,
+// with their comments and attachment files, to a zip archive. The archive
+// can be downloaded with DownloadExport once the job has finished.
+func ExportIssues(c *gin.Context) {
+	var req models.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, err := newProvider(req.Source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source: " + err.Error()})
+		return
+	}
+	if err := os.MkdirAll(exportDir(), 0755); err != nil {
+		closeProviders(source)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create export directory: %v", err)})
+		return
+	}
+
+	migration := models.MigrationRequest{
+		Direction: req.Source.Type + "-to-archive",
+		Source:    req.Source,
+		IssueIDs:  req.IssueIDs,
+	}
+	job := newJob(migration)
+	fmt.Printf("[EXPORT] Queued job %s for %s %s\n", job.ID(), source.Name(), source.Project())
+
+	go runMigrationJob(job, migration, func(req models.MigrationRequest, job *Job) models.MigrationResult {
+		return exportIssues(req, job, source)
+	}, source)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id": job.ID(),
+		"status": JobQueued,
+	})
+}
+
+// DownloadExport sends the archive written by a finished export job
+func DownloadExport(c *gin.Context) {
+	id := c.Param("id")
+	if !jobIDRegex.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+	archivePath := exportPath(id)
+	if _, err := os.Stat(archivePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Archive not found; the export may still be running"})
+		return
+	}
+	c.FileAttachment(archivePath, fmt.Sprintf("issues-%s.zip", id))
+}
+
+// exportIssues writes the requested issues, or every issue of the source
+// when none are requested, to the job's archive
+func exportIssues(req models.MigrationRequest, job *Job, source Provider) models.MigrationResult {
+	result := models.MigrationResult{
+		Success: []models.MigrationStatus{},
+		Failed:  []models.MigrationStatus{},
+	}
+
+	issueIDs := req.IssueIDs
+	if len(issueIDs) == 0 {
+		fmt.Printf("[EXPORT] Listing all %s issues\n", source.Name())
+		issues, err := source.ListIssues(time.Time{})
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to list %s issues: %v\n", source.Name(), err)
+			job.setError(err)
+			return result
+		}
+		for _, issue := range issues {
+			issueIDs = append(issueIDs, issue.Number)
+		}
+		job.setTotal(len(issueIDs))
+	}
+
+	writer, err := archive.Create(exportPath(job.ID()), archive.Source{
+		Platform: source.Platform(),
+		Name:     source.Name(),
+		Project:  source.Project(),
+	})
+	if err != nil {
+		job.setError(err)
+		return result
+	}
+	exportDefinitions(writer, source)
+
+	for _, issueID := range issueIDs {
+		job.issueStarted(issueID)
+		if err := exportIssue(writer, job, source, issueID); err != nil {
+			fmt.Printf("[ERROR] Failed to export %s issue #%d: %v\n", source.Name(), issueID, err)
+			failure := models.MigrationStatus{
+				OriginalID: issueID,
+				Error:      err.Error(),
+			}
+			result.Failed = append(result.Failed, failure)
+			job.issueFailed(failure)
+			continue
+		}
+		success := models.MigrationStatus{
+			OriginalID: issueID,
+			NewID:      issueID,
+		}
+		result.Success = append(result.Success, success)
+		job.issueSucceeded(success)
+	}
+
+	if err := writer.Close(); err != nil {
+		fmt.Printf("[ERROR] Failed to finish archive of job %s: %v\n", job.ID(), err)
+		job.setError(err)
+		return result
+	}
+	job.setArchive("/api/exports/" + job.ID())
+	fmt.Printf("[EXPORT] Wrote %d issue(s) to %s\n", len(result.Success), exportPath(job.ID()))
+	return result
+}
+
+// exportDefinitions adds the labels and milestones of the source project to
+// the archive, so they keep their colors, descriptions and due dates when the
+// archive is imported. Without them the archive only names those in use.
+func exportDefinitions(writer *archive.Writer, source Provider) {
+	labels, err := source.ListLabels()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list %s labels, only exporting the names of those in use: %v\n", source.Name(), err)
+	}
+	for _, label := range labels {
+		writer.AddLabel(archive.Label{Name: label.Name, Color: label.Color, Description: label.Description})
+	}
+
+	tracker, ok := source.(milestoneTracker)
+	if !ok {
+		return
+	}
+	milestones, err := tracker.ListMilestones()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list %s milestones, only exporting the titles of those in use: %v\n", source.Name(), err)
+	}
+	for _, milestone := range milestones {
+		writer.AddMilestone(archive.Milestone{
+			Title:       milestone.Title,
+			Description: milestone.Description,
+			DueDate:     milestone.DueDate,
+			State:       milestone.State,
+		})
+	}
+}
+
+// exportIssue adds one issue with its comments and downloaded attachments to the archive
+func exportIssue(writer *archive.Writer, job *Job, source Provider, issueID int) error {
+	issue, err := source.GetIssue(issueID)
+	if err != nil {
+		return err
+	}
+	comments, err := source.ListComments(issueID, time.Time{})
+	if err != nil {
+		return fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	exported := &archive.Issue{
+		Number:       issue.Number,
+		Title:        issue.Title,
+		State:        issue.State,
+		Labels:       issue.Labels,
+		Milestone:    issue.Milestone,
+		Author:       issue.Author,
+		Assignees:    issue.Assignees,
+		URL:          issue.URL,
+		CreatedAt:    issue.CreatedAt,
+		UpdatedAt:    issue.UpdatedAt,
+		ClosedAt:     issue.ClosedAt,
+		DueDate:      issue.DueDate,
+		Weight:       issue.Weight,
+		Confidential: issue.Confidential,
+		HealthStatus: issue.HealthStatus,
+		Epic:         issue.Epic,
+		Comments:     []archive.Comment{},
+		Attachments:  []archive.Attachment{},
+	}
+	if exported.Labels == nil {
+		exported.Labels = []string{}
+	}
+
+	stored := make(map[string]bool)
+	// storeAttachments downloads the attachments of a body into the archive;
+	// files that cannot be downloaded keep pointing to the source
+	storeAttachments := func(body string) (string, error) {
+		body, attachments := source.FindAttachments(body)
+		for _, attachment := range attachments {
+			if stored[attachment.URL] {
+				continue
+			}
+			data, err := source.DownloadAttachment(attachment)
+			if err != nil {
+				fmt.Printf("[WARNING] Cannot download attachment %s: %v\n", attachment.URL, err)
+				continue
+			}
+			filename := withDetectedExtension(attachment.Filename, data)
+			archivePath, err := writer.AddAttachment(issue.Number, filename, data)
+			if err != nil {
+				return "", err
+			}
+			stored[attachment.URL] = true
+			exported.Attachments = append(exported.Attachments, archive.Attachment{
+				URL:      attachment.URL,
+				Filename: filename,
+				Path:     archivePath,
+				IsImage:  attachment.IsImage || isImageURL(filename),
+				Size:     int64(len(data)),
+			})
+			job.attachmentUploaded(issueID, attachment.URL, archivePath)
+		}
+		return body, nil
+	}
+
+	if exported.Body, err = storeAttachments(issue.Body); err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		body, err := storeAttachments(comment.Body)
+		if err != nil {
+			return err
+		}
+		exported.Comments = append(exported.Comments, archive.Comment{
+			ID:         comment.ID,
+			Body:       body,
+			Author:     comment.Author,
+			URL:        comment.URL,
+			CreatedAt:  comment.CreatedAt,
+			UpdatedAt:  comment.UpdatedAt,
+			System:     comment.System,
+			InReplyTo:  comment.InReplyTo,
+			Resolved:   comment.Resolved,
+			ResolvedBy: comment.ResolvedBy,
+		})
+	}
+
+	if history, ok := source.(historyTracker); ok {
+		events, err := history.ListEvents(issueID)
+		if err != nil {
+			return fmt.Errorf("failed to list events: %w", err)
+		}
+		for _, event := range events {
+			exported.Events = append(exported.Events, archive.Event{
+				Action:    event.Action,
+				Actor:     event.Actor,
+				Subject:   event.Subject,
+				Previous:  event.Previous,
+				CreatedAt: event.CreatedAt,
+			})
+		}
+	}
+
+	fmt.Printf("[EXPORT] Exported issue #%d with %d comment(s), %d event(s) and %d attachment(s)\n",
+		issueID, len(exported.Comments), len(exported.Events), len(exported.Attachments))
+	return writer.AddIssue(exported)
+}
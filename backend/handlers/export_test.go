@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+const testAttachment = "https://gitlab.example.com/group/project/uploads/abc/trace.log"
+
+// testExportSource returns a GitLab-like source with an open issue that has
+// an attachment and a thread, and a closed issue with every metadata field
+func testExportSource() *fakeProvider {
+	created := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	closed := created.Add(48 * time.Hour)
+	due := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	weight := 3
+
+	source := newFakeProvider("gitlab", "group/project")
+	source.labels = []TrackerLabel{{Name: "bug", Color: "d73a4a", Description: "Something is broken"}}
+	source.files[testAttachment] = []byte("panic: boom\n")
+	source.putIssue(&TrackerIssue{
+		Number:    1,
+		Title:     "Crash on start",
+		Body:      "See [trace.log](" + testAttachment + ")",
+		State:     "open",
+		Labels:    []string{"bug", "area/ui"},
+		Milestone: "1.0",
+		Author:    "alice",
+		Assignees: []string{"bob"},
+		CreatedAt: created,
+		UpdatedAt: created.Add(time.Hour),
+	})
+	source.putComment(1, &TrackerComment{ID: 10, Body: "Which version?", Author: "bob", CreatedAt: created, UpdatedAt: created})
+	source.putComment(1, &TrackerComment{ID: 11, Body: "The latest", Author: "alice", CreatedAt: created, UpdatedAt: created, InReplyTo: 10, Resolved: true, ResolvedBy: "bob"})
+	source.putIssue(&TrackerIssue{
+		Number:       2,
+		Title:        "Plan the release",
+		Body:         "Steps to follow",
+		State:        "closed",
+		Labels:       []string{},
+		Author:       "carol",
+		CreatedAt:    created,
+		UpdatedAt:    closed,
+		ClosedAt:     &closed,
+		DueDate:      &due,
+		Weight:       &weight,
+		Confidential: true,
+		HealthStatus: "at_risk",
+		Epic:         "Release 1.0",
+	})
+	return source
+}
+
+// exportTestArchive exports the issues of a source to a temporary directory
+// and opens the archive
+func exportTestArchive(t *testing.T, source Provider, issueIDs []int) *archiveProvider {
+	t.Helper()
+	t.Setenv("EXPORT_DIR", t.TempDir())
+	req := models.MigrationRequest{Direction: "gitlab-to-archive", IssueIDs: issueIDs}
+	job := newJob(req)
+
+	result := exportIssues(req, job, source)
+	if len(result.Failed) > 0 || job.Snapshot().Error != "" {
+		t.Fatalf("export failed: %+v %s", result.Failed, job.Snapshot().Error)
+	}
+
+	provider, err := newArchiveProvider(models.Endpoint{Type: "archive", ArchivePath: exportPath(job.ID())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	return provider
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	source := testExportSource()
+	archived := exportTestArchive(t, source, nil)
+
+	if archived.Platform() != "gitlab" || archived.Project() != "group/project" {
+		t.Errorf("archive of %s %s, want gitlab group/project", archived.Platform(), archived.Project())
+	}
+	wantLabels := []TrackerLabel{{Name: "area/ui"}, {Name: "bug", Color: "d73a4a", Description: "Something is broken"}}
+	if labels, _ := archived.ListLabels(); !reflect.DeepEqual(labels, wantLabels) {
+		t.Errorf("labels %+v, want %+v", labels, wantLabels)
+	}
+
+	tests := []struct {
+		name        string
+		number      int
+		attachments [][]byte
+	}{
+		{"issue with an attachment and a thread", 1, [][]byte{[]byte("panic: boom\n")}},
+		{"closed issue with metadata", 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, _ := source.GetIssue(tt.number)
+			got, err := archived.GetIssue(tt.number)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("issue\n got: %+v\nwant: %+v", got, want)
+			}
+
+			wantComments, _ := source.ListComments(tt.number, time.Time{})
+			gotComments, err := archived.ListComments(tt.number, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotComments, wantComments) {
+				t.Errorf("comments\n got: %+v\nwant: %+v", gotComments, wantComments)
+			}
+
+			_, attachments := archived.FindAttachments(got.Body)
+			if len(attachments) != len(tt.attachments) {
+				t.Fatalf("found %d attachment(s), want %d", len(attachments), len(tt.attachments))
+			}
+			for i, attachment := range attachments {
+				data, err := archived.DownloadAttachment(attachment)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, tt.attachments[i]) {
+					t.Errorf("attachment %s holds %q, want %q", attachment.URL, data, tt.attachments[i])
+				}
+			}
+		})
+	}
+}
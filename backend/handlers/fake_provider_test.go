@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue-migrator/backend/markdown"
+	"github.com/issue-migrator/backend/store"
+)
+
+// fakeProvider is an in-memory tracker. It records every write in order and
+// fails the methods named in failures.
+type fakeProvider struct {
+	platform string
+	project  string
+	issues   map[int]*TrackerIssue
+	comments map[int][]*TrackerComment
+	labels   []TrackerLabel
+	files    map[string][]byte // attachment URL -> content
+	failures map[string]error  // method name -> error it returns
+	writes   []string          // e.g. "create #1", "update #1", "comment #1"
+	nextID   int64
+}
+
+func newFakeProvider(platform string, project string) *fakeProvider {
+	return &fakeProvider{
+		platform: platform,
+		project:  project,
+		issues:   make(map[int]*TrackerIssue),
+		comments: make(map[int][]*TrackerComment),
+		files:    make(map[string][]byte),
+		failures: make(map[string]error),
+	}
+}
+
+// putIssue stores an issue as it is
+func (p *fakeProvider) putIssue(issue *TrackerIssue) {
+	if issue.State == "" {
+		issue.State = "open"
+	}
+	if issue.URL == "" {
+		issue.URL = p.issueURL(issue.Number)
+	}
+	p.issues[issue.Number] = issue
+}
+
+// putComment stores a comment on an issue as it is
+func (p *fakeProvider) putComment(number int, comment *TrackerComment) {
+	p.comments[number] = append(p.comments[number], comment)
+}
+
+func (p *fakeProvider) issueURL(number int) string {
+	return fmt.Sprintf("https://%s.example.com/%s/issues/%d", p.platform, p.project, number)
+}
+
+func (p *fakeProvider) Platform() string { return p.platform }
+
+func (p *fakeProvider) Name() string { return "Fake " + p.platform }
+
+func (p *fakeProvider) Project() string { return p.project }
+
+func (p *fakeProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	if err := p.failures["ListIssues"]; err != nil {
+		return nil, err
+	}
+	var numbers []int
+	for number, issue := range p.issues {
+		if !issue.UpdatedAt.Before(since) {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	issues := make([]*TrackerIssue, len(numbers))
+	for i, number := range numbers {
+		issues[i] = p.copyIssue(number)
+	}
+	return issues, nil
+}
+
+func (p *fakeProvider) GetIssue(number int) (*TrackerIssue, error) {
+	if err := p.failures["GetIssue"]; err != nil {
+		return nil, err
+	}
+	if _, ok := p.issues[number]; !ok {
+		return nil, fmt.Errorf("issue #%d not found", number)
+	}
+	return p.copyIssue(number), nil
+}
+
+func (p *fakeProvider) copyIssue(number int) *TrackerIssue {
+	issue := *p.issues[number]
+	issue.Labels = slices.Clone(issue.Labels)
+	return &issue
+}
+
+func (p *fakeProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	if err := p.failures["FindIssueByMarker"]; err != nil {
+		return nil, err
+	}
+	for number, issue := range p.issues {
+		if strings.Contains(issue.Body, marker) {
+			return p.copyIssue(number), nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *fakeProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	if err := p.failures["CreateIssue"]; err != nil {
+		return nil, err
+	}
+	number := len(p.issues) + 1
+	for p.issues[number] != nil {
+		number++
+	}
+	p.putIssue(&TrackerIssue{
+		Number:    number,
+		Title:     input.Title,
+		Body:      input.Body,
+		Labels:    input.Labels,
+		Assignees: input.Assignees,
+		Milestone: input.Milestone,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	})
+	p.writes = append(p.writes, fmt.Sprintf("create #%d", number))
+	return p.copyIssue(number), nil
+}
+
+func (p *fakeProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	if err := p.failures["UpdateIssue"]; err != nil {
+		return nil, err
+	}
+	issue, ok := p.issues[number]
+	if !ok {
+		return nil, fmt.Errorf("issue #%d not found", number)
+	}
+	if input.Title != "" {
+		issue.Title = input.Title
+	}
+	issue.Body = input.Body
+	if input.Labels != nil {
+		issue.Labels = input.Labels
+	}
+	if input.State != "" {
+		issue.State = input.State
+	}
+	issue.UpdatedAt = time.Now().UTC()
+	p.writes = append(p.writes, fmt.Sprintf("update #%d", number))
+	return p.copyIssue(number), nil
+}
+
+func (p *fakeProvider) SetState(number int, state string) error {
+	if err := p.failures["SetState"]; err != nil {
+		return err
+	}
+	issue, ok := p.issues[number]
+	if !ok {
+		return fmt.Errorf("issue #%d not found", number)
+	}
+	issue.State = state
+	issue.UpdatedAt = time.Now().UTC()
+	p.writes = append(p.writes, fmt.Sprintf("%s #%d", state, number))
+	return nil
+}
+
+func (p *fakeProvider) ListLabels() ([]TrackerLabel, error) {
+	return p.labels, nil
+}
+
+func (p *fakeProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	if err := p.failures["ListComments"]; err != nil {
+		return nil, err
+	}
+	var comments []*TrackerComment
+	for _, comment := range p.comments[number] {
+		if !comment.UpdatedAt.Before(since) {
+			copied := *comment
+			comments = append(comments, &copied)
+		}
+	}
+	return comments, nil
+}
+
+func (p *fakeProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	if err := p.failures["AddComment"]; err != nil {
+		return nil, err
+	}
+	if _, ok := p.issues[number]; !ok {
+		return nil, fmt.Errorf("issue #%d not found", number)
+	}
+	p.nextID++
+	comment := &TrackerComment{
+		ID:        1000 + p.nextID,
+		Body:      body,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	p.putComment(number, comment)
+	p.writes = append(p.writes, fmt.Sprintf("comment #%d", number))
+	copied := *comment
+	return &copied, nil
+}
+
+// FindAttachments returns the linked files stored on this tracker
+func (p *fakeProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	var attachments []AttachmentInfo
+	for _, link := range markdown.Links(body) {
+		if _, ok := p.files[link.URL]; ok {
+			attachments = append(attachments, AttachmentInfo{
+				URL:          link.URL,
+				Filename:     path.Base(link.URL),
+				IsImage:      link.Image,
+				OriginalText: link.Source,
+			})
+		}
+	}
+	return body, attachments
+}
+
+func (p *fakeProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	data, ok := p.files[attachment.URL]
+	if !ok {
+		return nil, fmt.Errorf("no file at %s", attachment.URL)
+	}
+	return data, nil
+}
+
+func (p *fakeProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	if err := p.failures["UploadAttachment"]; err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("https://%s.example.com/%s/uploads/%d/%s", p.platform, p.project, len(p.files)+1, filename)
+	p.files[url] = data
+	p.writes = append(p.writes, fmt.Sprintf("upload #%d", number))
+	return url, nil
+}
+
+// useTestStore points the state store to a new database for the duration of a test
+func useTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	s, err := store.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := stateStore
+	SetStateStore(s)
+	t.Cleanup(func() {
+		SetStateStore(previous)
+		s.Close()
+	})
+	return s
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// History event actions
+const (
+	HistoryLabeled      = "labeled"
+	HistoryUnlabeled    = "unlabeled"
+	HistoryClosed       = "closed"
+	HistoryReopened     = "reopened"
+	HistoryAssigned     = "assigned"
+	HistoryUnassigned   = "unassigned"
+	HistoryMilestoned   = "milestoned"
+	HistoryDemilestoned = "demilestoned"
+	HistoryRenamed      = "renamed"
+	HistoryReferenced   = "referenced" // from another issue, merge request or commit
+)
+
+// issueHistory returns the events of a source issue since the given time, or
+// nil if the source has no history
+func issueHistory(source Provider, issueID int, since time.Time) []*TrackerEvent {
+	history, ok := source.(historyTracker)
+	if !ok {
+		return nil
+	}
+	events, err := history.ListEvents(issueID)
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list the events of %s issue #%d: %v\n", source.Name(), issueID, err)
+		return nil
+	}
+	var recent []*TrackerEvent
+	for _, event := range events {
+		if !event.CreatedAt.Before(since) {
+			recent = append(recent, event)
+		}
+	}
+	return recent
+}
+
+// copyHistory adds the events of a source issue since the given time to the
+// target issue as one history comment, unless the target issue has it
+// already. The comment is recorded for the rewriting of its references.
+func copyHistory(source Provider, target Provider, issueID int, targetNumber int, since time.Time, targetMarkers map[string]bool, users *userMap, references *referenceRewriter) {
+	events := issueHistory(source, issueID, since)
+	if len(events) == 0 {
+		return
+	}
+	marker := historyMarker(source.Platform(), source.Project(), issueID, events[0].CreatedAt)
+	if targetMarkers[markerToken(marker)] {
+		fmt.Printf("[STATE] The history of %s issue #%d was already migrated, skipping\n", source.Name(), issueID)
+		return
+	}
+	fmt.Printf("[MIGRATE] Adding %d event(s) of %s issue #%d to %s issue #%d\n", len(events), source.Name(), issueID, target.Name(), targetNumber)
+	body := historyBody(events, source, users)
+	comment, err := target.AddComment(targetNumber, marker+"\n"+body)
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to add the history comment: %v\n", err)
+		return
+	}
+	references.commentWritten(targetNumber, comment.ID, marker+"\n", body)
+}
+
+// historyBody renders events as a table with a row per event
+func historyBody(events []*TrackerEvent, source Provider, users *userMap) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "### 🕓 History from %s\n\n", source.Name())
+	body.WriteString("| Date | User | Event |\n| --- | --- | --- |\n")
+	for _, event := range events {
+		actor := "—"
+		if event.Actor != "" {
+			actor = users.credit(event.Actor, source)
+		}
+		fmt.Fprintf(&body, "| %s | %s | %s |\n",
+			event.CreatedAt.Format("2006-01-02 15:04 UTC"),
+			tableCell(actor),
+			tableCell(describeEvent(event, source, users)))
+	}
+	return body.String()
+}
+
+// describeEvent phrases an event for the history table
+func describeEvent(event *TrackerEvent, source Provider, users *userMap) string {
+	switch event.Action {
+	case HistoryLabeled:
+		return fmt.Sprintf("added the label `%s`", event.Subject)
+	case HistoryUnlabeled:
+		return fmt.Sprintf("removed the label `%s`", event.Subject)
+	case HistoryClosed:
+		return "closed the issue"
+	case HistoryReopened:
+		return "reopened the issue"
+	case HistoryAssigned:
+		return "assigned " + users.credit(event.Subject, source)
+	case HistoryUnassigned:
+		return "unassigned " + users.credit(event.Subject, source)
+	case HistoryMilestoned:
+		return fmt.Sprintf("added the milestone **%s**", event.Subject)
+	case HistoryDemilestoned:
+		return fmt.Sprintf("removed the milestone **%s**", event.Subject)
+	case HistoryRenamed:
+		return fmt.Sprintf("changed the title from **%s** to **%s**", event.Previous, event.Subject)
+	case HistoryReferenced:
+		return "referenced this issue in " + event.Subject
+	}
+	return event.Action
+}
+
+// tableCell keeps text on one line and from ending a Markdown table cell
+func tableCell(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", "\\|")
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/models"
+)
+
+// GetJiraIssues lists the issues of a Jira project matching the optional JQL
+func GetJiraIssues(c *gin.Context) {
+	var req models.JiraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fmt.Printf("[JIRA] Fetching issues for project %s on %s\n", req.ProjectKey, req.BaseURL)
+	provider := newJiraProvider(models.Endpoint{
+		Type:       "jira",
+		BaseURL:    req.BaseURL,
+		ProjectKey: req.ProjectKey,
+		JQL:        req.JQL,
+		Email:      req.Email,
+		Token:      req.Token,
+	})
+	issues, err := provider.ListIssues(time.Time{})
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to list Jira issues: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch Jira issues: %v", err)})
+		return
+	}
+
+	convertedIssues := make([]models.Issue, len(issues))
+	for i, issue := range issues {
+		convertedIssues[i] = models.Issue{
+			ID:          issue.Number,
+			Title:       issue.Title,
+			Description: issue.Body,
+			State:       issue.State,
+			Labels:      issue.Labels,
+			Milestone:   issue.Milestone,
+			Author:      issue.Author,
+			Assignees:   issue.Assignees,
+			DueDate:     issue.DueDate,
+			CreatedAt:   issue.CreatedAt,
+			UpdatedAt:   issue.UpdatedAt,
+			URL:         issue.URL,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"issues": convertedIssues,
+		"count":  len(convertedIssues),
+	})
+}
@@ -0,0 +1,539 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jiraAttachmentLinks resolves attachment filenames referenced in Jira
+// markup to Markdown links and records which attachments were referenced
+type jiraAttachmentLinks struct {
+	attachments []jiraAttachment
+	referenced  map[string]bool
+}
+
+func newJiraAttachmentLinks(attachments []jiraAttachment) *jiraAttachmentLinks {
+	return &jiraAttachmentLinks{
+		attachments: attachments,
+		referenced:  make(map[string]bool),
+	}
+}
+
+// link returns the Markdown image or link for an attached file
+func (l *jiraAttachmentLinks) link(filename string) (string, bool) {
+	for _, attachment := range l.attachments {
+		if attachment.Filename != filename {
+			continue
+		}
+		l.referenced[attachment.ID] = true
+		if strings.HasPrefix(attachment.MimeType, "image/") || isImageURL(filename) {
+			return fmt.Sprintf("![%s](%s)", filename, attachment.Content), true
+		}
+		return fmt.Sprintf("[%s](%s)", filename, attachment.Content), true
+	}
+	return "", false
+}
+
+// unreferenced returns links to the attachments that the markup did not embed
+func (l *jiraAttachmentLinks) unreferenced() []string {
+	var links []string
+	for _, attachment := range l.attachments {
+		if l.referenced[attachment.ID] {
+			continue
+		}
+		if link, ok := l.link(attachment.Filename); ok {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+var (
+	jiraCodeStartRegex = regexp.MustCompile(`^\{(code|noformat)(?::([^}]*))?\}(.*)$`)
+	jiraHeadingRegex   = regexp.MustCompile(`^h([1-6])\.\s+(.*)$`)
+	jiraQuoteLineRegex = regexp.MustCompile(`^bq\.\s+(.*)$`)
+	jiraListRegex      = regexp.MustCompile(`^([*#-]+)\s+(.*)$`)
+	jiraRuleRegex      = regexp.MustCompile(`^-{4,}$`)
+	jiraPanelRegex     = regexp.MustCompile(`\{panel(?::[^}]*)?\}`)
+
+	jiraMonoRegex     = regexp.MustCompile(`\{\{(.+?)\}\}`)
+	jiraColorRegex    = regexp.MustCompile(`\{color(?::[^}]*)?\}|\{anchor:[^}]*\}`)
+	jiraImageRegex    = regexp.MustCompile(`!((?:https?://)?[^\s!|]+\.[A-Za-z0-9]+)(?:\|[^!]*)?!`)
+	jiraLinkRegex     = regexp.MustCompile(`\[([^\[\]|]*)\|([^\[\]]+)\]|\[([~^][^\[\]]+|(?:https?|mailto):[^\[\]]+)\]`)
+	jiraURLRegex      = regexp.MustCompile(`https?://[^\s<>\x00]+`)
+	jiraBoldRegex     = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*($|[^\w*])`)
+	jiraStrikeRegex   = regexp.MustCompile(`(^|[^\w-])-([^-\s](?:[^-]*[^-\s])?)-($|[^\w-])`)
+	jiraInsertRegex   = regexp.MustCompile(`(^|[^\w+])\+([^+\s](?:[^+]*[^+\s])?)\+($|[^\w+])`)
+	jiraSupRegex      = regexp.MustCompile(`\^([^^\s]+)\^`)
+	jiraSubRegex      = regexp.MustCompile(`~([^~\s]+)~`)
+	jiraCiteRegex     = regexp.MustCompile(`\?\?([^?\s](?:[^?]*[^?\s])?)\?\?`)
+	placeholderRegex  = regexp.MustCompile("\x00(\\d+)\x00")
+	tableHeaderPrefix = "||"
+)
+
+// jiraWikiToMarkdown converts Jira wiki markup, as returned by Jira Server
+// and REST API v2, to Markdown
+func jiraWikiToMarkdown(text string, links *jiraAttachmentLinks) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var out []string
+	var codeTag string // closing tag of the open code block, if any
+	quoted := false
+	quoteEnded := false // a quote ended and needs a blank line before the next text
+	inTable := false
+
+	emit := func(line string) {
+		if quoteEnded && line != "" {
+			out = append(out, "")
+		}
+		quoteEnded = false
+		if quoted {
+			line = "> " + line
+		}
+		out = append(out, line)
+	}
+
+	for _, line := range lines {
+		if codeTag != "" {
+			if idx := strings.Index(line, codeTag); idx != -1 {
+				if before := line[:idx]; strings.TrimSpace(before) != "" {
+					emit(before)
+				}
+				emit("```")
+				codeTag = ""
+				continue
+			}
+			emit(line)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(jiraPanelRegex.ReplaceAllString(line, ""))
+		if inTable && !strings.HasPrefix(trimmed, "|") {
+			inTable = false
+		}
+
+		if match := jiraCodeStartRegex.FindStringSubmatch(trimmed); match != nil {
+			tag := "{" + match[1] + "}"
+			emit("```" + jiraCodeLanguage(match[2]))
+			rest := match[3]
+			if idx := strings.Index(rest, tag); idx != -1 {
+				if code := rest[:idx]; code != "" {
+					emit(code)
+				}
+				emit("```")
+				continue
+			}
+			if rest != "" {
+				emit(rest)
+			}
+			codeTag = tag
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "{quote}") {
+			trimmed = strings.TrimPrefix(trimmed, "{quote}")
+			if strings.HasSuffix(trimmed, "{quote}") {
+				quoted = true
+				emit(jiraInline(strings.TrimSuffix(trimmed, "{quote}"), links))
+				quoted = false
+				quoteEnded = true
+				continue
+			}
+			quoted = !quoted
+			if trimmed == "" {
+				quoteEnded = !quoted
+				continue
+			}
+		}
+		closeQuote := false
+		if quoted && strings.HasSuffix(trimmed, "{quote}") {
+			trimmed = strings.TrimSuffix(trimmed, "{quote}")
+			closeQuote = true
+		}
+
+		switch {
+		case jiraRuleRegex.MatchString(trimmed):
+			emit("---")
+		case jiraHeadingRegex.MatchString(trimmed):
+			match := jiraHeadingRegex.FindStringSubmatch(trimmed)
+			level, _ := strconv.Atoi(match[1])
+			emit(strings.Repeat("#", level) + " " + jiraInline(match[2], links))
+		case jiraQuoteLineRegex.MatchString(trimmed):
+			match := jiraQuoteLineRegex.FindStringSubmatch(trimmed)
+			// Consecutive bq. lines form one quote
+			quoteEnded = false
+			emit("> " + jiraInline(match[1], links))
+			quoteEnded = true
+		case jiraListRegex.MatchString(trimmed):
+			match := jiraListRegex.FindStringSubmatch(trimmed)
+			emit(jiraListPrefix(match[1]) + jiraInline(match[2], links))
+		case strings.HasPrefix(trimmed, "|"):
+			header := strings.HasPrefix(trimmed, tableHeaderPrefix)
+			cells := jiraTableCells(jiraInline(trimmed, links), header)
+			if !inTable && !header {
+				// Markdown tables need a header row
+				emit("|" + strings.Repeat("  |", len(cells)))
+				emit("|" + strings.Repeat(" --- |", len(cells)))
+			}
+			emit("| " + strings.Join(cells, " | ") + " |")
+			if header {
+				emit("|" + strings.Repeat(" --- |", len(cells)))
+			}
+			inTable = true
+		default:
+			emit(jiraInline(trimmed, links))
+		}
+
+		if closeQuote {
+			quoted = false
+			quoteEnded = true
+		}
+	}
+	if codeTag != "" {
+		out = append(out, "```")
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// jiraCodeLanguage returns the language of a {code} macro, e.g. {code:java}
+// or {code:language=java|title=Main.java}
+func jiraCodeLanguage(params string) string {
+	for _, param := range strings.Split(params, "|") {
+		if !strings.Contains(param, "=") {
+			return strings.TrimSpace(param)
+		}
+		if strings.HasPrefix(param, "language=") {
+			return strings.TrimPrefix(param, "language=")
+		}
+	}
+	return ""
+}
+
+// jiraListPrefix converts list markers such as "*#" to an indented Markdown bullet
+func jiraListPrefix(markers string) string {
+	var indent strings.Builder
+	for _, marker := range markers[:len(markers)-1] {
+		if marker == '#' {
+			indent.WriteString("   ")
+		} else {
+			indent.WriteString("  ")
+		}
+	}
+	if markers[len(markers)-1] == '#' {
+		return indent.String() + "1. "
+	}
+	return indent.String() + "- "
+}
+
+// jiraTableCells splits a table row such as "||a||b||" or "|a|b|" into cells
+func jiraTableCells(row string, header bool) []string {
+	separator := "|"
+	if header {
+		separator = tableHeaderPrefix
+	}
+	row = strings.TrimSuffix(strings.TrimPrefix(row, separator), separator)
+	cells := strings.Split(row, separator)
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+// jiraInline converts the inline markup of a single line
+func jiraInline(text string, links *jiraAttachmentLinks) string {
+	// Converted code, images and links are replaced with placeholders so the
+	// emphasis rules below cannot alter their contents
+	var protected []string
+	protect := func(s string) string {
+		protected = append(protected, s)
+		return fmt.Sprintf("\x00%d\x00", len(protected)-1)
+	}
+
+	text = jiraMonoRegex.ReplaceAllStringFunc(text, func(match string) string {
+		return protect("`" + jiraMonoRegex.FindStringSubmatch(match)[1] + "`")
+	})
+	text = jiraColorRegex.ReplaceAllString(text, "")
+	text = jiraImageRegex.ReplaceAllStringFunc(text, func(match string) string {
+		source := jiraImageRegex.FindStringSubmatch(match)[1]
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			return protect(fmt.Sprintf("![](%s)", source))
+		}
+		if link, ok := links.link(source); ok {
+			return protect(link)
+		}
+		return match
+	})
+	text = jiraLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		parts := jiraLinkRegex.FindStringSubmatch(match)
+		label, target := parts[1], parts[2]
+		if parts[3] != "" {
+			label, target = "", parts[3]
+		}
+		switch {
+		case strings.HasPrefix(target, "~"):
+			user := strings.TrimPrefix(strings.TrimPrefix(target, "~"), "accountid:")
+			return protect("@" + user)
+		case strings.HasPrefix(target, "^"):
+			if link, ok := links.link(strings.TrimPrefix(target, "^")); ok {
+				return protect(link)
+			}
+			return match
+		case label == "":
+			return protect("<" + target + ">")
+		default:
+			return protect(fmt.Sprintf("[%s](%s)", label, target))
+		}
+	})
+	text = jiraURLRegex.ReplaceAllStringFunc(text, protect)
+
+	// Superscript and subscript are written inside words, as in x^2^ and
+	// H~2~O; subscript goes first since ~~ marks strikethrough in Markdown
+	text = jiraSupRegex.ReplaceAllString(text, "<sup>$1</sup>")
+	text = jiraSubRegex.ReplaceAllString(text, "<sub>$1</sub>")
+	for i := 0; i < 2; i++ {
+		// Adjacent spans share a boundary character, so a second pass catches the rest
+		text = jiraBoldRegex.ReplaceAllString(text, "$1\x01$2\x01$3")
+		text = jiraStrikeRegex.ReplaceAllString(text, "$1~~$2~~$3")
+		text = jiraInsertRegex.ReplaceAllString(text, "$1<ins>$2</ins>$3")
+	}
+	text = strings.ReplaceAll(text, "\x01", "**")
+	text = jiraCiteRegex.ReplaceAllString(text, "<cite>$1</cite>")
+	text = strings.ReplaceAll(text, `\\`, "<br>")
+
+	return placeholderRegex.ReplaceAllStringFunc(text, func(match string) string {
+		index, _ := strconv.Atoi(placeholderRegex.FindStringSubmatch(match)[1])
+		return protected[index]
+	})
+}
+
+// adfNode is a node of the Atlassian Document Format used by Jira Cloud
+// (REST API v3) for descriptions and comments
+type adfNode struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text"`
+	Attrs   map[string]interface{} `json:"attrs"`
+	Marks   []adfNode              `json:"marks"`
+	Content []adfNode              `json:"content"`
+}
+
+// adfToMarkdown converts an ADF document to Markdown
+func adfToMarkdown(doc *adfNode, links *jiraAttachmentLinks) string {
+	r := &adfRenderer{links: links}
+	return strings.TrimSpace(r.blocks(doc.Content, "\n\n"))
+}
+
+type adfRenderer struct {
+	links *jiraAttachmentLinks
+}
+
+func (r *adfRenderer) blocks(nodes []adfNode, separator string) string {
+	var parts []string
+	for i := range nodes {
+		if block := r.block(&nodes[i]); block != "" {
+			parts = append(parts, block)
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+func (r *adfRenderer) block(node *adfNode) string {
+	switch node.Type {
+	case "paragraph":
+		return r.inline(node.Content)
+	case "heading":
+		level := adfInt(node.Attrs, "level")
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		return strings.Repeat("#", level) + " " + r.inline(node.Content)
+	case "bulletList", "orderedList":
+		return r.list(node)
+	case "codeBlock":
+		var code strings.Builder
+		for _, child := range node.Content {
+			code.WriteString(child.Text)
+		}
+		return "```" + adfString(node.Attrs, "language") + "\n" + code.String() + "\n```"
+	case "blockquote", "panel":
+		return prefixLines(r.blocks(node.Content, "\n\n"), "> ", "> ")
+	case "expand", "nestedExpand":
+		body := r.blocks(node.Content, "\n\n")
+		if title := adfString(node.Attrs, "title"); title != "" {
+			return "**" + title + "**\n\n" + body
+		}
+		return body
+	case "rule":
+		return "---"
+	case "mediaSingle", "mediaGroup":
+		return r.blocks(node.Content, "\n")
+	case "media":
+		return r.media(node)
+	case "table":
+		return r.table(node)
+	case "blockCard", "embedCard":
+		if url := adfString(node.Attrs, "url"); url != "" {
+			return "<" + url + ">"
+		}
+		return ""
+	default:
+		if node.Text != "" {
+			return node.Text
+		}
+		return r.blocks(node.Content, "\n\n")
+	}
+}
+
+func (r *adfRenderer) list(node *adfNode) string {
+	start := 1
+	if order := adfInt(node.Attrs, "order"); order > 0 {
+		start = order
+	}
+	items := make([]string, 0, len(node.Content))
+	for i := range node.Content {
+		marker := "- "
+		if node.Type == "orderedList" {
+			marker = fmt.Sprintf("%d. ", start+i)
+		}
+		body := r.blocks(node.Content[i].Content, "\n")
+		items = append(items, prefixLines(body, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (r *adfRenderer) table(node *adfNode) string {
+	var rows []string
+	for i, row := range node.Content {
+		cells := make([]string, len(row.Content))
+		for j := range row.Content {
+			cell := r.blocks(row.Content[j].Content, "<br>")
+			cell = strings.ReplaceAll(cell, "\n", "<br>")
+			cells[j] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			// The first row is the header, whether or not Jira marked it as one
+			rows = append(rows, "|"+strings.Repeat(" --- |", len(cells)))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (r *adfRenderer) media(node *adfNode) string {
+	if adfString(node.Attrs, "type") == "external" {
+		return fmt.Sprintf("![](%s)", adfString(node.Attrs, "url"))
+	}
+	// Media nodes reference the media service rather than the attachment, but
+	// Jira stores the attachment's filename as alt text
+	if link, ok := r.links.link(adfString(node.Attrs, "alt")); ok {
+		return link
+	}
+	return ""
+}
+
+func (r *adfRenderer) inline(nodes []adfNode) string {
+	var text strings.Builder
+	for i := range nodes {
+		node := &nodes[i]
+		switch node.Type {
+		case "text":
+			text.WriteString(adfMarked(node.Text, node.Marks))
+		case "hardBreak":
+			text.WriteString("  \n")
+		case "mention":
+			mention := adfString(node.Attrs, "text")
+			if !strings.HasPrefix(mention, "@") {
+				mention = "@" + mention
+			}
+			text.WriteString(mention)
+		case "emoji":
+			if emoji := adfString(node.Attrs, "text"); emoji != "" {
+				text.WriteString(emoji)
+			} else {
+				text.WriteString(adfString(node.Attrs, "shortName"))
+			}
+		case "inlineCard":
+			text.WriteString("<" + adfString(node.Attrs, "url") + ">")
+		case "status":
+			text.WriteString("`" + adfString(node.Attrs, "text") + "`")
+		case "date":
+			if ms, err := strconv.ParseInt(adfString(node.Attrs, "timestamp"), 10, 64); err == nil {
+				text.WriteString(time.UnixMilli(ms).UTC().Format("2006-01-02"))
+			}
+		case "media", "mediaInline":
+			text.WriteString(r.media(node))
+		default:
+			text.WriteString(r.inline(node.Content))
+		}
+	}
+	return text.String()
+}
+
+// adfMarked applies text marks; Markdown emphasis cannot start or end with
+// whitespace, so surrounding spaces are kept outside the markers
+func adfMarked(text string, marks []adfNode) string {
+	core := strings.TrimSpace(text)
+	if core == "" || len(marks) == 0 {
+		return text
+	}
+	lead := text[:strings.Index(text, core)]
+	trail := text[len(lead)+len(core):]
+
+	var href string
+	for _, mark := range marks {
+		switch mark.Type {
+		case "code":
+			core = "`" + core + "`"
+		case "strong":
+			core = "**" + core + "**"
+		case "em":
+			core = "_" + core + "_"
+		case "strike":
+			core = "~~" + core + "~~"
+		case "underline":
+			core = "<ins>" + core + "</ins>"
+		case "link":
+			href = adfString(mark.Attrs, "href")
+		}
+	}
+	if href != "" {
+		core = fmt.Sprintf("[%s](%s)", core, href)
+	}
+	return lead + core + trail
+}
+
+// prefixLines prefixes the first line of a block with first and all
+// following non-empty lines with rest
+func prefixLines(block string, first string, rest string) string {
+	lines := strings.Split(block, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case line != "":
+			lines[i] = rest + line
+		case strings.TrimSpace(rest) != "":
+			lines[i] = strings.TrimRight(rest, " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func adfString(attrs map[string]interface{}, key string) string {
+	switch value := attrs[key].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func adfInt(attrs map[string]interface{}, key string) int {
+	if value, ok := attrs[key].(float64); ok {
+		return int(value)
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var jiraTestAttachments = []jiraAttachment{
+	{ID: "1", Filename: "screen.png", Content: "https://jira.example.com/secure/attachment/1/screen.png", MimeType: "image/png"},
+	{ID: "2", Filename: "build.log", Content: "https://jira.example.com/secure/attachment/2/build.log", MimeType: "text/plain"},
+}
+
+func TestJiraWikiToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		wiki string
+		want string
+	}{
+		{"heading", "h2. Steps", "## Steps"},
+		{"bold", "a *bold* word", "a **bold** word"},
+		{"strikethrough", "a -gone- word", "a ~~gone~~ word"},
+		{"inserted", "a +new+ word", "a <ins>new</ins> word"},
+		{"superscript", "x^2^ + y^2^", "x<sup>2</sup> + y<sup>2</sup>"},
+		{"subscript", "H~2~O", "H<sub>2</sub>O"},
+		{"citation", "??Someone??", "<cite>Someone</cite>"},
+		{"monospace", "run {{make *all*}}", "run `make *all*`"},
+		{"line break", `one\\two`, "one<br>two"},
+		{"color", "{color:red}alert{color}", "alert"},
+		{"rule", "a\n----\nb", "a\n---\nb"},
+		{"code block", "{code:java}\nint x = 1;\n{code}", "```java\nint x = 1;\n```"},
+		{"code block parameters", "{code:title=Main.java|language=go}x := *y*{code}", "```go\nx := *y*\n```"},
+		{"noformat", "{noformat}\n*raw*\n{noformat}", "```\n*raw*\n```"},
+		{"quote block", "{quote}\nfirst\nsecond\n{quote}\nafter", "> first\n> second\n\nafter"},
+		{"single line quote", "{quote}q{quote}\nafter", "> q\n\nafter"},
+		{"quote closed on its last line", "{quote}\nq{quote}\nafter", "> q\n\nafter"},
+		{"bq", "bq. x\ny", "> x\n\ny"},
+		{"consecutive bq", "bq. a\nbq. b\nc", "> a\n> b\n\nc"},
+		{"bullet list", "* one\n** nested\n* two", "- one\n  - nested\n- two"},
+		{"numbered list", "# one\n## nested", "1. one\n   1. nested"},
+		{"table", "||A||B||\n|1|2|", "| A | B |\n| --- | --- |\n| 1 | 2 |"},
+		{"table without header", "|1|2|", "|  |  |\n| --- | --- |\n| 1 | 2 |"},
+		{"link", "[Docs|https://example.com/docs]", "[Docs](https://example.com/docs)"},
+		{"bare link", "[https://example.com]", "<https://example.com>"},
+		{"url", "see https://example.com/a-b-c-d", "see https://example.com/a-b-c-d"},
+		{"mention", "[~jdoe] and [~accountid:5b10]", "@jdoe and @5b10"},
+		{"attached image", "!screen.png|thumbnail!", "![screen.png](https://jira.example.com/secure/attachment/1/screen.png)"},
+		{"attachment link", "[^build.log]", "[build.log](https://jira.example.com/secure/attachment/2/build.log)"},
+		{"external image", "!https://example.com/a.png!", "![](https://example.com/a.png)"},
+		{"panel", "{panel:title=Note}\ntext\n{panel}", "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jiraWikiToMarkdown(tt.wiki, newJiraAttachmentLinks(jiraTestAttachments))
+			if got != tt.want {
+				t.Errorf("jiraWikiToMarkdown(%q)\n got: %q\nwant: %q", tt.wiki, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		adf  string
+		want string
+	}{
+		{"paragraphs", `[{"type":"paragraph","content":[{"type":"text","text":"a"}]},{"type":"paragraph","content":[{"type":"text","text":"b"}]}]`, "a\n\nb"},
+		{"marks", `[{"type":"paragraph","content":[{"type":"text","text":"bold ","marks":[{"type":"strong"}]},{"type":"text","text":"code","marks":[{"type":"code"}]},{"type":"text","text":" struck","marks":[{"type":"strike"}]}]}]`, "**bold** `code` ~~struck~~"},
+		{"link", `[{"type":"paragraph","content":[{"type":"text","text":"docs","marks":[{"type":"link","attrs":{"href":"https://example.com"}}]}]}]`, "[docs](https://example.com)"},
+		{"heading", `[{"type":"heading","attrs":{"level":3},"content":[{"type":"text","text":"Steps"}]}]`, "### Steps"},
+		{"bullet list", `[{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]},{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}]}]`, "- one\n- two"},
+		{"ordered list", `[{"type":"orderedList","attrs":{"order":3},"content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"three"}]}]}]}]`, "3. three"},
+		{"code block", `[{"type":"codeBlock","attrs":{"language":"go"},"content":[{"type":"text","text":"x := 1"}]}]`, "```go\nx := 1\n```"},
+		{"blockquote", `[{"type":"blockquote","content":[{"type":"paragraph","content":[{"type":"text","text":"a"}]},{"type":"paragraph","content":[{"type":"text","text":"b"}]}]}]`, "> a\n>\n> b"},
+		{"hard break", `[{"type":"paragraph","content":[{"type":"text","text":"a"},{"type":"hardBreak"},{"type":"text","text":"b"}]}]`, "a  \nb"},
+		{"mention", `[{"type":"paragraph","content":[{"type":"mention","attrs":{"text":"@Jane"}}]}]`, "@Jane"},
+		{"rule", `[{"type":"rule"}]`, "---"},
+		{"table", `[{"type":"table","content":[{"type":"tableRow","content":[{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"A"}]}]}]},{"type":"tableRow","content":[{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"a|b"}]}]}]}]}]`, "| A |\n| --- |\n| a\\|b |"},
+		{"attached media", `[{"type":"mediaSingle","content":[{"type":"media","attrs":{"type":"file","alt":"screen.png"}}]}]`, "![screen.png](https://jira.example.com/secure/attachment/1/screen.png)"},
+		{"date", `[{"type":"paragraph","content":[{"type":"date","attrs":{"timestamp":"1700000000000"}}]}]`, "2023-11-14"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &adfNode{Type: "doc"}
+			if err := json.Unmarshal([]byte(tt.adf), &doc.Content); err != nil {
+				t.Fatal(err)
+			}
+			got := adfToMarkdown(doc, newJiraAttachmentLinks(jiraTestAttachments))
+			if got != tt.want {
+				t.Errorf("adfToMarkdown(%s)\n got: %q\nwant: %q", tt.adf, got, tt.want)
+			}
+		})
+	}
+}
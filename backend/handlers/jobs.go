@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/models"
+)
+
+// Job status values
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+)
+
+// Issue progress status values
+const (
+	IssuePending = "pending"
+	IssueRunning = "running"
+	IssueSuccess = "success"
+	IssueFailed  = "failed"
+)
+
+// Job is a migration running in the background
+type Job struct {
+	mu     sync.Mutex
+	state  models.MigrationJob
+	index  map[int]int // original issue ID -> position in state.Progress
+	events []models.MigrationEvent
+	notify chan struct{} // closed and replaced whenever an event is added
+}
+
+// Finished jobs are kept for jobRetention so their result can be fetched, and
+// at most maxFinishedJobs of them are kept at once
+const (
+	jobRetention    = 24 * time.Hour
+	maxFinishedJobs = 200
+)
+
+// jobRegistry keeps the running jobs and the recently finished ones
+type jobRegistry struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+var jobs = &jobRegistry{jobs: make(map[string]*Job)}
+
+// newJob creates and registers a queued job for the given request
+func newJob(req models.MigrationRequest) *Job {
+	job := &Job{
+		state: models.MigrationJob{
+			ID:        generateJobID(),
+			Status:    JobQueued,
+			Direction: req.Direction,
+			Total:     len(req.IssueIDs),
+			Progress:  make([]models.IssueProgress, 0, len(req.IssueIDs)),
+			CreatedAt: time.Now().UTC(),
+		},
+		index:  make(map[int]int),
+		notify: make(chan struct{}),
+	}
+	for _, issueID := range req.IssueIDs {
+		if _, ok := job.index[issueID]; ok {
+			continue
+		}
+		job.index[issueID] = len(job.state.Progress)
+		job.state.Progress = append(job.state.Progress, models.IssueProgress{
+			OriginalID: issueID,
+			Status:     IssuePending,
+		})
+	}
+
+	jobs.mu.Lock()
+	jobs.prune(time.Now().UTC())
+	jobs.jobs[job.state.ID] = job
+	jobs.mu.Unlock()
+
+	return job
+}
+
+// prune removes finished jobs older than jobRetention and the oldest finished
+// jobs beyond maxFinishedJobs; the caller holds r.mu
+func (r *jobRegistry) prune(now time.Time) {
+	type finishedJob struct {
+		id string
+		at time.Time
+	}
+	var finished []finishedJob
+	for id, job := range r.jobs {
+		job.mu.Lock()
+		finishedAt := job.state.FinishedAt
+		job.mu.Unlock()
+		switch {
+		case finishedAt == nil:
+		case now.Sub(*finishedAt) > jobRetention:
+			delete(r.jobs, id)
+		default:
+			finished = append(finished, finishedJob{id: id, at: *finishedAt})
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].at.Before(finished[k].at) })
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(r.jobs, job.id)
+	}
+}
+
+// getJob looks up a job by ID
+func getJob(id string) (*Job, bool) {
+	jobs.mu.RLock()
+	defer jobs.mu.RUnlock()
+	job, ok := jobs.jobs[id]
+	return job, ok
+}
+
+// ID returns the job identifier
+func (j *Job) ID() string {
+	return j.state.ID
+}
+
+// Snapshot returns a copy of the job state that is safe to serialize
+func (j *Job) Snapshot() models.MigrationJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	snapshot := j.state
+	snapshot.Progress = append([]models.IssueProgress(nil), j.state.Progress...)
+	return snapshot
+}
+
+// start marks the job as running
+func (j *Job) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.state.Status = JobRunning
+	j.state.StartedAt = &now
+}
+
+// finish stores the final result and marks the job as completed
+func (j *Job) finish(result models.MigrationResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.state.Status = JobCompleted
+	j.state.FinishedAt = &now
+	j.state.Result = &result
+	if err != nil {
+		j.state.Error = err.Error()
+	}
+
+	// Record the final event under the same lock so streams never see a
+	// completed job without its job_finished event
+	j.appendEventLocked(models.MigrationEvent{
+		Type:    EventJobFinished,
+		Message: j.state.Error,
+		Result:  &result,
+	})
+}
+
+// setTotal updates the number of issues once a job has discovered them
+func (j *Job) setTotal(total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Total = total
+}
+
+// setError records a job-level error; the job still finishes with partial results
+func (j *Job) setError(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Error = err.Error()
+}
+
+// setArchive records where the archive written by an export job can be downloaded
+func (j *Job) setArchive(downloadPath string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Archive = downloadPath
+}
+
+// setPlan stores the plan produced by a dry run
+func (j *Job) setPlan(plan *models.MigrationPlan) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Plan = plan
+}
+
+// issueStarted marks a source issue as being migrated
+func (j *Job) issueStarted(issueID int) {
+	j.update(issueID, func(p *models.IssueProgress) {
+		p.Status = IssueRunning
+	})
+	j.emit(models.MigrationEvent{Type: EventIssueStarted, IssueID: issueID})
+}
+
+// issueSucceeded records the created target issue
+func (j *Job) issueSucceeded(status models.MigrationStatus) {
+	j.update(status.OriginalID, func(p *models.IssueProgress) {
+		p.Status = IssueSuccess
+		p.NewID = status.NewID
+		p.NewURL = status.NewURL
+	})
+	j.emit(models.MigrationEvent{
+		Type:    EventIssueCompleted,
+		IssueID: status.OriginalID,
+		NewID:   status.NewID,
+		URL:     status.NewURL,
+	})
+}
+
+// issueFailed records the error for a source issue
+func (j *Job) issueFailed(status models.MigrationStatus) {
+	j.update(status.OriginalID, func(p *models.IssueProgress) {
+		p.Status = IssueFailed
+		p.Error = status.Error
+	})
+	j.emit(models.MigrationEvent{
+		Type:    EventIssueFailed,
+		IssueID: status.OriginalID,
+		Message: status.Error,
+	})
+}
+
+func (j *Job) update(issueID int, fn func(p *models.IssueProgress)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	idx, ok := j.index[issueID]
+	if !ok {
+		idx = len(j.state.Progress)
+		j.index[issueID] = idx
+		j.state.Progress = append(j.state.Progress, models.IssueProgress{OriginalID: issueID})
+	}
+
+	p := &j.state.Progress[idx]
+	wasDone := p.Status == IssueSuccess || p.Status == IssueFailed
+	fn(p)
+	if !wasDone && (p.Status == IssueSuccess || p.Status == IssueFailed) {
+		j.state.Completed++
+	}
+}
+
+// GetJob returns the live progress of a migration job
+func GetJob(c *gin.Context) {
+	job, ok := getJob(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job.Snapshot())
+}
+
+// generateJobID generates a random job identifier
+func generateJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+// testJob returns a job that finished the given time before now, or a
+// running job for a negative age
+func testJob(id string, now time.Time, age time.Duration) *Job {
+	job := &Job{state: models.MigrationJob{ID: id, Status: JobRunning}}
+	if age >= 0 {
+		finishedAt := now.Add(-age)
+		job.state.Status = JobCompleted
+		job.state.FinishedAt = &finishedAt
+	}
+	return job
+}
+
+func TestJobRegistryPrune(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	many := make(map[string]time.Duration)
+	for i := 0; i < maxFinishedJobs+2; i++ {
+		many[fmt.Sprintf("job-%03d", i)] = time.Duration(i) * time.Minute
+	}
+
+	tests := []struct {
+		name string
+		jobs map[string]time.Duration // job ID -> time since it finished, negative while running
+		want []string
+	}{
+		{
+			name: "running jobs are kept",
+			jobs: map[string]time.Duration{"running": -1},
+			want: []string{"running"},
+		},
+		{
+			name: "finished jobs are kept for the retention period",
+			jobs: map[string]time.Duration{"recent": time.Hour, "retained": jobRetention},
+			want: []string{"recent", "retained"},
+		},
+		{
+			name: "finished jobs past the retention period are removed",
+			jobs: map[string]time.Duration{"expired": jobRetention + time.Second, "running": -1},
+			want: []string{"running"},
+		},
+		{
+			name: "the oldest finished jobs beyond the limit are removed",
+			jobs: many,
+			want: func() []string {
+				var kept []string
+				for i := 0; i < maxFinishedJobs; i++ {
+					kept = append(kept, fmt.Sprintf("job-%03d", i))
+				}
+				return kept
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &jobRegistry{jobs: make(map[string]*Job)}
+			for id, age := range tt.jobs {
+				registry.jobs[id] = testJob(id, now, age)
+			}
+
+			registry.prune(now)
+
+			var got []string
+			for id := range registry.jobs {
+				got = append(got, id)
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobProgress(t *testing.T) {
+	job := newJob(models.MigrationRequest{IssueIDs: []int{1, 2, 2, 3}})
+	job.start()
+	job.issueStarted(1)
+	job.issueSucceeded(models.MigrationStatus{OriginalID: 1, NewID: 10})
+	job.issueFailed(models.MigrationStatus{OriginalID: 2, Error: "boom"})
+	// A second outcome for the same issue is not counted twice
+	job.issueFailed(models.MigrationStatus{OriginalID: 2, Error: "boom again"})
+	job.finish(models.MigrationResult{}, nil)
+
+	snapshot := job.Snapshot()
+	if snapshot.Status != JobCompleted || snapshot.FinishedAt == nil {
+		t.Errorf("status %q, finished at %v; want a completed job", snapshot.Status, snapshot.FinishedAt)
+	}
+	if snapshot.Completed != 2 {
+		t.Errorf("completed = %d, want 2", snapshot.Completed)
+	}
+	want := []models.IssueProgress{
+		{OriginalID: 1, Status: IssueSuccess, NewID: 10},
+		{OriginalID: 2, Status: IssueFailed, Error: "boom again"},
+		{OriginalID: 3, Status: IssuePending},
+	}
+	if fmt.Sprint(snapshot.Progress) != fmt.Sprint(want) {
+		t.Errorf("progress %+v, want %+v", snapshot.Progress, want)
+	}
+	if found, ok := getJob(job.ID()); !ok || found != job {
+		t.Errorf("getJob(%q) did not return the job", job.ID())
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLabelColor is given to labels copied from trackers without label colors
+const defaultLabelColor = "ededed"
+
+// labelMap renames source labels on the target, e.g. "bug" to "type::bug".
+// Source labels mapped to the same name are merged into one target label. A
+// nil labelMap keeps every name.
+type labelMap map[string]string // lower-case source label -> target label
+
+func newLabelMap(mapping map[string]string) labelMap {
+	m := make(labelMap, len(mapping))
+	for from, to := range mapping {
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if from != "" && to != "" {
+			m[strings.ToLower(from)] = to
+		}
+	}
+	return m
+}
+
+// name returns the target name of a source label
+func (m labelMap) name(label string) string {
+	if target, ok := m[strings.ToLower(label)]; ok {
+		return target
+	}
+	return label
+}
+
+// rename returns the target names of the labels of a source issue, without
+// the duplicates left by merged labels
+func (m labelMap) rename(labels []string) []string {
+	if labels == nil {
+		return nil
+	}
+	renamed := make([]string, 0, len(labels))
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		target := m.name(label)
+		if seen[strings.ToLower(target)] {
+			continue
+		}
+		seen[strings.ToLower(target)] = true
+		renamed = append(renamed, target)
+	}
+	return renamed
+}
+
+// syncLabels copies the label definitions of the source to the target before
+// any issue is written. Missing labels are created with their source color
+// and description, and target labels whose color or description differ are
+// updated. Labels are renamed by the label mapping and the scoped label rule
+// of the translation. A merged label takes the definition of the source label with its
+// target name if there is one, and of the first source label mapped to it
+// otherwise. With dryRun set nothing is written. It returns the names of the
+// created and updated labels.
+func syncLabels(source Provider, target Provider, translation *translator, dryRun bool) ([]string, []string) {
+	editor, ok := target.(labelEditor)
+	if !ok {
+		fmt.Printf("[INFO] %s has no label definitions; labels are only copied by name\n", target.Name())
+		return nil, nil
+	}
+	defined, err := source.ListLabels()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list %s labels, labels are only copied by name: %v\n", source.Name(), err)
+		return nil, nil
+	}
+	existing, err := target.ListLabels()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list %s labels, labels are only copied by name: %v\n", target.Name(), err)
+		return nil, nil
+	}
+	current := make(map[string]TrackerLabel, len(existing))
+	for _, label := range existing {
+		current[strings.ToLower(label.Name)] = label
+	}
+
+	var order []string
+	wanted := make(map[string]TrackerLabel, len(defined))
+	for _, label := range defined {
+		name := translation.labelName(label.Name)
+		key := strings.ToLower(name)
+		if _, ok := wanted[key]; !ok {
+			order = append(order, key)
+		} else if !strings.EqualFold(label.Name, name) {
+			continue
+		}
+		label.Name = name
+		wanted[key] = label
+	}
+
+	var created, updated []string
+	for _, key := range order {
+		label := wanted[key]
+		existingLabel, ok := current[key]
+		if !ok {
+			if label.Color == "" {
+				label.Color = defaultLabelColor
+			}
+			if !dryRun {
+				if err := editor.CreateLabel(label); err != nil {
+					fmt.Printf("[WARNING] Failed to create %s label %q: %v\n", target.Name(), label.Name, err)
+					continue
+				}
+				fmt.Printf("[LABELS] Created %s label %q\n", target.Name(), label.Name)
+			}
+			created = append(created, label.Name)
+			continue
+		}
+
+		colorChanged := label.Color != "" && !strings.EqualFold(label.Color, existingLabel.Color)
+		descriptionChanged := label.Description != "" && label.Description != existingLabel.Description
+		if !colorChanged && !descriptionChanged {
+			continue
+		}
+		label.Name = existingLabel.Name
+		if label.Color == "" {
+			label.Color = existingLabel.Color
+		}
+		if label.Description == "" {
+			label.Description = existingLabel.Description
+		}
+		if !dryRun {
+			if err := editor.UpdateLabel(label); err != nil {
+				fmt.Printf("[WARNING] Failed to update %s label %q: %v\n", target.Name(), label.Name, err)
+				continue
+			}
+			fmt.Printf("[LABELS] Updated %s label %q\n", target.Name(), label.Name)
+		}
+		updated = append(updated, label.Name)
+	}
+	if !dryRun {
+		fmt.Printf("[LABELS] Created %d and updated %d %s label(s)\n", len(created), len(updated), target.Name())
+	}
+	return created, updated
+}
+
+// ParseLabelMapping converts an uploaded label mapping (multipart field
+// "file") into the label_mapping of a migration request. It accepts the same
+// CSV and JSON forms as ParseUserMapping, with label names instead of usernames.
+func ParseLabelMapping(c *gin.Context) {
+	mapping, filename, ok := readMappingUpload(c)
+	if !ok {
+		return
+	}
+	fmt.Printf("[LABELS] Parsed a mapping of %d label(s) from %s\n", len(mapping), filename)
+	c.JSON(http.StatusOK, gin.H{
+		"label_mapping": mapping,
+		"count":         len(mapping),
+	})
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import "github.com/issue-migrator/backend/markdown"
+
+// markdownDialect returns the Markdown flavor a tracker renders. GitLab has
+// its own; the other trackers are treated as GitHub Flavored Markdown.
+func markdownDialect(provider Provider) markdown.Dialect {
+	if provider.Platform() == "gitlab" {
+		return markdown.GLFM
+	}
+	return markdown.GFM
+}
+
+// convertMarkdown translates an issue body or comment from the Markdown
+// flavor of the source to that of the target
+func convertMarkdown(body string, source, target Provider) string {
+	return markdown.Convert(body, markdownDialect(source), markdownDialect(target))
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Hidden markers are HTML comments embedded in migrated issues and comments.
+// They identify the source object so a migration can be repeated without
+// creating duplicates:
+//
+//	<!-- issue-migrator:source=github:owner/repo#123 -->
+//	<!-- issue-migrator:comment=github:owner/repo#123/456 -->
+//	<!-- issue-migrator:history=github:owner/repo#123/1700000000 -->
+var markerRegex = regexp.MustCompile(`<!-- (issue-migrator:[^\s]+) -->`)
+
+var issueMarkerRegex = regexp.MustCompile(`<!-- issue-migrator:source=(\w+):(\S+)#(\d+) -->`)
+
+// issueMarker returns the hidden marker for a source issue
+func issueMarker(platform string, project string, issueID int) string {
+	return fmt.Sprintf("<!-- issue-migrator:source=%s:%s#%d -->", platform, project, issueID)
+}
+
+// commentMarker returns the hidden marker for a source comment
+func commentMarker(platform string, project string, issueID int, commentID int64) string {
+	return fmt.Sprintf("<!-- issue-migrator:comment=%s:%s#%d/%d -->", platform, project, issueID, commentID)
+}
+
+// historyMarker returns the hidden marker for the history comment of a source
+// issue, identified by the time of its first event so that a sync can add
+// the events since the previous run as another history comment
+func historyMarker(platform string, project string, issueID int, since time.Time) string {
+	return fmt.Sprintf("<!-- issue-migrator:history=%s:%s#%d/%d -->", platform, project, issueID, since.Unix())
+}
+
+// markerToken returns the searchable text inside a marker
+func markerToken(marker string) string {
+	if match := markerRegex.FindStringSubmatch(marker); len(match) > 1 {
+		return match[1]
+	}
+	return marker
+}
+
+// parseIssueMarker extracts the source issue from the marker in a migrated body
+func parseIssueMarker(body string) (platform string, project string, issueID int, ok bool) {
+	match := issueMarkerRegex.FindStringSubmatch(body)
+	if match == nil {
+		return "", "", 0, false
+	}
+	issueID, err := strconv.Atoi(match[3])
+	if err != nil {
+		return "", "", 0, false
+	}
+	return match[1], match[2], issueID, true
+}
+
+// hasMarker reports whether a body was written by the migrator
+func hasMarker(body string) bool {
+	return markerRegex.MatchString(body)
+}
+
+// stripMigrationHeader returns the original body of a migrated issue by
+// removing the marker and the header that ends with a horizontal rule
+func stripMigrationHeader(body string) string {
+	loc := issueMarkerRegex.FindStringIndex(body)
+	if loc == nil {
+		return body
+	}
+	rest := body[loc[1]:]
+	if idx := strings.Index(rest, "\n---\n\n"); idx != -1 {
+		return rest[idx+len("\n---\n\n"):]
+	}
+	return strings.TrimPrefix(rest, "\n")
+}
+
+// githubProject identifies a GitHub repository in markers
+func githubProject(owner string, repo string) string {
+	return owner + "/" + repo
+}
+
+// gitlabProject identifies a GitLab project in markers
+func gitlabProject(baseURL string, projectID int) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+	return fmt.Sprintf("%s/%d", strings.TrimSuffix(host, "/"), projectID)
+}
+
+// giteaProject identifies a Gitea or Forgejo repository in markers
+func giteaProject(baseURL string, owner string, repo string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(host, "/"), owner, repo)
+}
+
+// jiraProject identifies a Jira project in markers
+func jiraProject(baseURL string, projectKey string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(host, "/"), projectKey)
+}
+
+// bitbucketProject identifies a Bitbucket Cloud repository in markers
+func bitbucketProject(workspace string, repo string) string {
+	return workspace + "/" + repo
+}
+
+// azureProject identifies an Azure DevOps project in markers
+func azureProject(orgURL string, project string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(orgURL, "https://"), "http://")
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(host, "/"), project)
+}
+
+// collectMarkers returns the set of marker tokens found in the given bodies
+func collectMarkers(bodies ...string) map[string]bool {
+	markers := make(map[string]bool)
+	for _, body := range bodies {
+		for _, match := range markerRegex.FindAllStringSubmatch(body, -1) {
+			markers[match[1]] = true
+		}
+	}
+	return markers
+}
+
+// findGitLabIssueByMarker searches a GitLab project for an issue carrying the marker
+func findGitLabIssueByMarker(glClient *gitlab.Client, projectID int, marker string) (*gitlab.Issue, error) {
+	token := markerToken(marker)
+	opts := &gitlab.ListProjectIssuesOptions{
+		Search:      gitlab.Ptr(token),
+		In:          gitlab.Ptr("description"),
+		ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1},
+	}
+
+	for {
+		issues, resp, err := glClient.Issues.ListProjectIssues(projectID, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if strings.Contains(issue.Description, marker) {
+				return issue, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -36,7 +36,7 @@ func MigrateIssuesFinal(c *gin.Context) {
 	fmt.Printf("[MIGRATE] Source: %+v\n", req.Source)
 	fmt.Printf("[MIGRATE] Target: %+v\n", req.Target)
 	fmt.Printf("[MIGRATE] Issues to migrate: %v\n", req.IssueIDs)
-	slog.Info("xxxx222222444444xxxx", req.Direction)
+	slog.Info("xxxx222222444444xxxx", "direction", req.Direction)
 	log.Println("log println")
 
 	results := models.MigrationResult{
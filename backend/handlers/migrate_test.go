@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+	"github.com/issue-migrator/backend/store"
+)
+
+const testScreenshot = "https://github.example.com/owner/repo/files/shot.png"
+
+// testMigration returns a source with issue #1 and its comments 10 and 11,
+// an empty target and the request migrating the issue between them
+func testMigration() (*fakeProvider, *fakeProvider, models.MigrationRequest) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	source := newFakeProvider("github", "owner/repo")
+	source.files[testScreenshot] = []byte("\x89PNG\r\n\x1a\n")
+	source.putIssue(&TrackerIssue{
+		Number:    1,
+		Title:     "Crash on start",
+		Body:      "It crashes:\n\n![shot](" + testScreenshot + ")",
+		Author:    "alice",
+		CreatedAt: created,
+		UpdatedAt: created,
+	})
+	source.putComment(1, &TrackerComment{ID: 10, Body: "Same here", Author: "bob", CreatedAt: created, UpdatedAt: created})
+	source.putComment(1, &TrackerComment{ID: 11, Body: "Fixed?", Author: "carol", CreatedAt: created, UpdatedAt: created})
+
+	target := newFakeProvider("gitea", "org/repo")
+	req := models.MigrationRequest{
+		Source:   models.Endpoint{Type: "github", Owner: "owner", Repo: "repo"},
+		Target:   models.Endpoint{Type: "gitea", BaseURL: "https://gitea.example.com", Owner: "org", Repo: "repo"},
+		IssueIDs: []int{1},
+		Resume:   true,
+	}
+	return source, target, req
+}
+
+// recordState is the progress stored in an issue record
+type recordState struct {
+	TargetID  int
+	BodyDone  bool
+	Completed bool
+}
+
+func TestMigrateIssuesResume(t *testing.T) {
+	tests := []struct {
+		name string
+		// record is the state left by an earlier run; its target issue
+		// exists on the target
+		record     *store.IssueRecord
+		failures   map[string]error // failures of the target
+		wantWrites []string
+		wantFailed bool
+		want       recordState
+	}{
+		{
+			name:       "new issue",
+			wantWrites: []string{"create #1", "upload #1", "update #1", "comment #1", "comment #1"},
+			want:       recordState{TargetID: 1, BodyDone: true, Completed: true},
+		},
+		{
+			name:       "completed issue is skipped",
+			record:     &store.IssueRecord{SourceID: 1, TargetID: 1, BodyDone: true, Completed: true},
+			wantWrites: nil,
+			want:       recordState{TargetID: 1, BodyDone: true, Completed: true},
+		},
+		{
+			name:       "created issue only gets the comments not done",
+			record:     &store.IssueRecord{SourceID: 1, TargetID: 1, BodyDone: true, Comments: map[int64]int64{10: 1001}},
+			wantWrites: []string{"comment #1"},
+			want:       recordState{TargetID: 1, BodyDone: true, Completed: true},
+		},
+		{
+			name:       "created issue without its body gets the body again",
+			record:     &store.IssueRecord{SourceID: 1, TargetID: 1},
+			wantWrites: []string{"upload #1", "update #1", "comment #1", "comment #1"},
+			want:       recordState{TargetID: 1, BodyDone: true, Completed: true},
+		},
+		{
+			name:       "failed body update leaves the issue incomplete",
+			failures:   map[string]error{"UpdateIssue": errors.New("unavailable")},
+			wantWrites: []string{"create #1", "upload #1", "comment #1", "comment #1"},
+			wantFailed: true,
+			want:       recordState{TargetID: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := useTestStore(t)
+			source, target, req := testMigration()
+			migration := migrationKey(req)
+			if tt.record != nil {
+				record := *tt.record
+				if err := s.PutIssue(migration, &record); err != nil {
+					t.Fatal(err)
+				}
+				target.putIssue(&TrackerIssue{
+					Number: tt.record.TargetID,
+					Body:   issueMarker("github", "owner/repo", 1) + "\n\nIt crashes",
+				})
+			}
+			for method, err := range tt.failures {
+				target.failures[method] = err
+			}
+
+			result := migrateIssues(req, newJob(req), source, target)
+
+			if fmt.Sprint(target.writes) != fmt.Sprint(tt.wantWrites) {
+				t.Errorf("target writes %v, want %v", target.writes, tt.wantWrites)
+			}
+			if failed := len(result.Failed) > 0; failed != tt.wantFailed {
+				t.Errorf("failed = %v, want %v (result %+v)", failed, tt.wantFailed, result)
+			}
+			record, err := s.GetIssue(migration, 1)
+			if err != nil || record == nil {
+				t.Fatalf("no stored record: %v", err)
+			}
+			got := recordState{TargetID: record.TargetID, BodyDone: record.BodyDone, Completed: record.Completed}
+			if got != tt.want {
+				t.Errorf("record %+v, want %+v", got, tt.want)
+			}
+			if tt.record == nil || !tt.record.BodyDone {
+				if len(record.Attachments) != 1 {
+					t.Errorf("recorded attachments %v, want the screenshot", record.Attachments)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,12 +14,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/go-github/v57/github"
+	"github.com/issue-migrator/backend/markdown"
 	"github.com/issue-migrator/backend/models"
-	"github.com/xanzy/go-gitlab"
 )
 
-// MigrateWithFiles handles migration with file and image transfer
+// MigrateWithFiles starts a background migration with file and image transfer
+// and returns the job ID that can be polled via GetJob
 func MigrateWithFiles(c *gin.Context) {
 	var req models.MigrationRequest
 	log.Println("Starting migration with file support")
@@ -30,476 +29,532 @@ func MigrateWithFiles(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("[MIGRATE] Starting migration: %s\n", req.Direction)
-	fmt.Printf("[MIGRATE] Source: %+v\n", req.Source)
-	fmt.Printf("[MIGRATE] Target: %+v\n", req.Target)
-	fmt.Printf("[MIGRATE] Issues to migrate: %v\n", req.IssueIDs)
-
-	results := models.MigrationResult{
-		Success: []models.MigrationStatus{},
-		Failed:  []models.MigrationStatus{},
+	if req.Mode == "" {
+		req.Mode = ModeMigrate
 	}
-
-	switch req.Direction {
-	case "github-to-gitlab":
-		results = migrateGHtoGLWithFiles(req, c)
-	case "gitlab-to-github":
-		results = migrateGLtoGHWithFiles(req, c)
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid migration direction"})
+	if req.Mode != ModeMigrate && req.Mode != ModeSync {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid migration mode"})
+		return
+	}
+	if req.Mode == ModeMigrate && len(req.IssueIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "issue_ids is required"})
+		return
+	}
+	if req.DryRun && req.Mode != ModeMigrate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run is only supported in migrate mode"})
+		return
+	}
+	if req.SystemNotes != "" && req.SystemNotes != SystemNotesLog && req.SystemNotes != SystemNotesSkip {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid system_notes: use \"log\" or \"skip\""})
+		return
+	}
+	if err := validateTranslation(req.Translation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid translation: " + err.Error()})
 		return
 	}
 
-	fmt.Printf("[MIGRATE] Migration completed. Success: %d, Failed: %d\n",
-		len(results.Success), len(results.Failed))
-
-	c.JSON(http.StatusOK, results)
-}
-
-func migrateGHtoGLWithFiles(req models.MigrationRequest, ginCtx *gin.Context) models.MigrationResult {
-	result := models.MigrationResult{
-		Success: []models.MigrationStatus{},
-		Failed:  []models.MigrationStatus{},
+	req.Source.Type, req.Target.Type = endpointTypes(req)
+	source, err := newProvider(req.Source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source: " + err.Error()})
+		return
+	}
+	target, err := newProvider(req.Target)
+	if err != nil {
+		closeProviders(source)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target: " + err.Error()})
+		return
+	}
+	if sourceOnlyTypes[req.Target.Type] {
+		closeProviders(source, target)
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid target: %s is only supported as a migration source", req.Target.Type)})
+		return
+	}
+	if req.Direction == "" {
+		req.Direction = req.Source.Type + "-to-" + req.Target.Type
 	}
 
-	ghClient := github.NewClient(nil).WithAuthToken(req.Source.Token)
-	glClient, _ := gitlab.NewClient(req.Target.Token, gitlab.WithBaseURL(req.Target.BaseURL))
-	ctx := context.Background()
+	migrate := func(req models.MigrationRequest, job *Job) models.MigrationResult {
+		if req.Mode == ModeSync {
+			return syncIssues(req, job, source, target)
+		}
+		if req.DryRun {
+			return planMigration(req, job, source, target)
+		}
+		return migrateIssues(req, job, source, target)
+	}
 
-	for _, issueID := range req.IssueIDs {
-		fmt.Printf("[MIGRATE] Processing GitHub issue #%d\n", issueID)
+	job := newJob(req)
 
-		issue, _, err := ghClient.Issues.Get(ctx, req.Source.Owner, req.Source.Repo, issueID)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to fetch issue #%d: %v\n", issueID, err)
-			result.Failed = append(result.Failed, models.MigrationStatus{
-				OriginalID: issueID,
-				Error:      err.Error(),
-			})
-			continue
-		}
+	if req.DryRun {
+		fmt.Printf("[MIGRATE] Queued dry run %s: %s\n", job.ID(), req.Direction)
+	} else {
+		fmt.Printf("[MIGRATE] Queued job %s: %s (%s)\n", job.ID(), req.Direction, req.Mode)
+	}
+	fmt.Printf("[MIGRATE] Source: %+v\n", req.Source)
+	fmt.Printf("[MIGRATE] Target: %+v\n", req.Target)
+	fmt.Printf("[MIGRATE] Issues to migrate: %v\n", req.IssueIDs)
 
-		// Process all attachments (images and files) in issue body
-		fmt.Printf("[MIGRATE] Processing attachments in issue #%d body1111\n", issueID)
-		processedBody := processAttachments(
-			issue.GetBody(),
-			req.Target.ProjectID,
-			req.Target.Token,
-			req.Target.BaseURL,
-			req.Source.Token,
-		)
-
-		labels := make([]string, len(issue.Labels))
-		for i, label := range issue.Labels {
-			labels[i] = label.GetName()
-		}
+	go runMigrationJob(job, req, migrate, source, target)
 
-		// Create migration header with timestamp information
-		migrationHeader := fmt.Sprintf("### 🔄 Migrated from GitHub\n\n")
-		migrationHeader += fmt.Sprintf("**Original Issue:** %s\n", issue.GetHTMLURL())
-		migrationHeader += fmt.Sprintf("**Original Author:** @%s\n", issue.User.GetLogin())
-		migrationHeader += fmt.Sprintf("**Created:** %s\n", issue.GetCreatedAt().Format("2006-01-02 15:04:05 UTC"))
-		migrationHeader += fmt.Sprintf("**Last Updated:** %s\n", issue.GetUpdatedAt().Format("2006-01-02 15:04:05 UTC"))
-		if issue.GetState() == "closed" && issue.ClosedAt != nil {
-			migrationHeader += fmt.Sprintf("**Closed:** %s\n", issue.GetClosedAt().Format("2006-01-02 15:04:05 UTC"))
-		}
-		migrationHeader += fmt.Sprintf("**State:** %s\n\n", issue.GetState())
-		migrationHeader += "---\n\n"
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id": job.ID(),
+		"status": JobQueued,
+	})
+}
 
-		description := migrationHeader + processedBody
-		title := issue.GetTitle()
+// runMigrationJob executes a migration in the background and stores its result on the job.
+// The providers are closed once it is done.
+func runMigrationJob(job *Job, req models.MigrationRequest, migrate func(models.MigrationRequest, *Job) models.MigrationResult, providers ...Provider) {
+	results := models.MigrationResult{
+		Success: []models.MigrationStatus{},
+		Failed:  []models.MigrationStatus{},
+	}
+	defer closeProviders(providers...)
 
-		createOpts := &gitlab.CreateIssueOptions{
-			Title:       &title,
-			Description: &description,
-			Labels:      (*gitlab.Labels)(&labels),
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[ERROR] Panic recovered in migration job %s: %v\n", job.ID(), r)
+			job.finish(results, fmt.Errorf("internal error: %v", r))
 		}
+	}()
 
-		fmt.Printf("[MIGRATE] Creating GitLab issue for GitHub issue #%d\n", issueID)
-		newIssue, _, err := glClient.Issues.CreateIssue(req.Target.ProjectID, createOpts)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to create GitLab issue: %v\n", err)
-			result.Failed = append(result.Failed, models.MigrationStatus{
-				OriginalID: issueID,
-				Error:      err.Error(),
-			})
-			continue
-		}
+	job.start()
+	fmt.Printf("[MIGRATE] Starting job %s\n", job.ID())
 
-		fmt.Printf("[SUCCESS] Created GitLab issue #%d for GitHub issue #%d\n", newIssue.IID, issueID)
-
-		// Process comments
-		comments, _, err := ghClient.Issues.ListComments(ctx, req.Source.Owner, req.Source.Repo, issueID, nil)
-		if err == nil {
-			fmt.Printf("[MIGRATE] Processing %d comments for issue #%d\n", len(comments), issueID)
-			for i, comment := range comments {
-				fmt.Printf("[MIGRATE] Processing comment %d/%d\n", i+1, len(comments))
-				processedComment := processAttachments(
-					comment.GetBody(),
-					req.Target.ProjectID,
-					req.Target.Token,
-					req.Target.BaseURL,
-					req.Source.Token,
-				)
-				// Include comment timestamp
-				commentHeader := fmt.Sprintf("**@%s** commented on %s",
-					comment.User.GetLogin(),
-					comment.GetCreatedAt().Format("2006-01-02 15:04:05 UTC"))
-				if comment.UpdatedAt != nil && comment.GetUpdatedAt().After(comment.GetCreatedAt().Time) {
-					commentHeader += fmt.Sprintf(" _(edited %s)_", comment.GetUpdatedAt().Format("2006-01-02 15:04:05 UTC"))
-				}
-				body := fmt.Sprintf("%s\n\n%s", commentHeader, processedComment)
-				noteOpts := &gitlab.CreateIssueNoteOptions{
-					Body: &body,
-				}
-				_, _, err := glClient.Notes.CreateIssueNote(req.Target.ProjectID, newIssue.IID, noteOpts)
-				if err != nil {
-					fmt.Printf("[WARNING] Failed to create comment: %v\n", err)
-				}
-			}
-		}
+	results = migrate(req, job)
 
-		result.Success = append(result.Success, models.MigrationStatus{
-			OriginalID: issueID,
-			NewID:      newIssue.IID,
-			NewURL:     newIssue.WebURL,
-		})
-	}
+	fmt.Printf("[MIGRATE] Job %s completed. Success: %d, Failed: %d\n",
+		job.ID(), len(results.Success), len(results.Failed))
 
-	return result
+	job.finish(results, nil)
 }
 
-func migrateGLtoGHWithFiles(req models.MigrationRequest, ginCtx *gin.Context) models.MigrationResult {
+// migrateIssues copies the selected issues with their comments and
+// attachments from the source tracker to the target tracker
+func migrateIssues(req models.MigrationRequest, job *Job, source Provider, target Provider) models.MigrationResult {
 	result := models.MigrationResult{
 		Success: []models.MigrationStatus{},
 		Failed:  []models.MigrationStatus{},
 	}
-
-	glClient, _ := gitlab.NewClient(req.Source.Token, gitlab.WithBaseURL(req.Source.BaseURL))
-	ghClient := github.NewClient(nil).WithAuthToken(req.Target.Token)
-	ctx := context.Background()
-
-	fmt.Println("[INFO] GitLab to GitHub migration: Attempting to upload files to GitHub")
-	fmt.Println("[INFO] Note: GitHub upload API is unofficial and may require browser session")
+	users := newUserMap(req, source, target)
+	milestones := newMilestoneSync(source, target)
+	translation := newTranslator(req, source, target)
+	references := newReferenceRewriter(req, source, target)
+	if req.SyncLabels {
+		syncLabels(source, target, translation, false)
+	}
 
 	for _, issueID := range req.IssueIDs {
-		fmt.Printf("[MIGRATE] Processing GitLab issue #%d\n", issueID)
+		fmt.Printf("[MIGRATE] Processing %s issue #%d\n", source.Name(), issueID)
+		job.issueStarted(issueID)
+
+		state := loadIssueState(req, issueID)
+		if state.completed() {
+			fmt.Printf("[STATE] %s issue #%d was already migrated to #%d, skipping\n", source.Name(), issueID, state.record.TargetID)
+			success := state.status()
+			result.Success = append(result.Success, success)
+			job.issueSucceeded(success)
+			continue
+		}
+		tracker := newAttachmentTracker(state, job, issueID)
+		marker := issueMarker(source.Platform(), source.Project(), issueID)
+		// Markers of comments already on the target issue; only needed when it existed before this run
+		var targetMarkers map[string]bool
 
-		issue, _, err := glClient.Issues.GetIssue(req.Source.ProjectID, issueID)
+		issue, err := source.GetIssue(issueID)
 		if err != nil {
 			fmt.Printf("[ERROR] Failed to fetch issue #%d: %v\n", issueID, err)
-			result.Failed = append(result.Failed, models.MigrationStatus{
+			failure := models.MigrationStatus{
 				OriginalID: issueID,
 				Error:      err.Error(),
-			})
+			}
+			result.Failed = append(result.Failed, failure)
+			job.issueFailed(failure)
 			continue
 		}
 
-		// Don't process attachments yet - we need the issue number first
-		// We'll process them after creating the issue
-		processedBody := issue.Description
-
-		// Create migration header with detailed timestamp information
-		migrationHeader := fmt.Sprintf("### 🔄 Migrated from GitLab\n\n")
-		migrationHeader += fmt.Sprintf("**Original Issue:** %s\n", issue.WebURL)
-		migrationHeader += fmt.Sprintf("**Original Author:** @%s\n", issue.Author.Username)
-		migrationHeader += fmt.Sprintf("**Created:** %s\n", issue.CreatedAt.Format("2006-01-02 15:04:05 UTC"))
-		migrationHeader += fmt.Sprintf("**Last Updated:** %s\n", issue.UpdatedAt.Format("2006-01-02 15:04:05 UTC"))
-		if issue.State == "closed" && issue.ClosedAt != nil {
-			migrationHeader += fmt.Sprintf("**Closed:** %s\n", issue.ClosedAt.Format("2006-01-02 15:04:05 UTC"))
+		var migrationHeader, body string
+		if !state.bodyDone() {
+			// Create migration header with timestamp information, after the
+			// front matter of the translation rules
+			translated, frontMatter := translation.translate(issue)
+			issue = translated
+			migrationHeader = frontMatter + issueHeader(marker, source, issue, users)
+			body = users.rewriteMentions(convertMarkdown(issue.Body, source, target))
 		}
-		migrationHeader += fmt.Sprintf("**State:** %s\n\n", issue.State)
-		migrationHeader += "---\n\n"
 
-		body := migrationHeader + processedBody
+		if !state.created() {
+			input := issueInput(issue, migrationHeader+body, users, milestones)
+			warnConfidential(issue, source, target)
 
-		labels := make([]string, len(issue.Labels))
-		for i, label := range issue.Labels {
-			labels[i] = label
-		}
+			existing, err := target.FindIssueByMarker(marker)
+			if err != nil {
+				// Creating the issue without knowing could duplicate an earlier migration
+				fmt.Printf("[ERROR] Failed to search %s for an earlier migration of issue #%d: %v\n", target.Name(), issueID, err)
+				failure := models.MigrationStatus{
+					OriginalID: issueID,
+					Error:      fmt.Sprintf("failed to search %s: %v", target.Name(), err),
+				}
+				result.Failed = append(result.Failed, failure)
+				job.issueFailed(failure)
+				continue
+			}
 
-		createReq := &github.IssueRequest{
-			Title:  &issue.Title,
-			Body:   &body,
-			Labels: &labels,
-		}
+			var newIssue *TrackerIssue
+			if existing != nil {
+				fmt.Printf("[MIGRATE] %s issue #%d was already migrated to %s issue #%d, updating it\n", source.Name(), issueID, target.Name(), existing.Number)
+				newIssue, err = target.UpdateIssue(existing.Number, input)
+			} else {
+				fmt.Printf("[MIGRATE] Creating %s issue for %s issue #%d\n", target.Name(), source.Name(), issueID)
+				newIssue, err = users.createIssue(target, issue, input)
+				if err == nil && issue.State == "closed" {
+					if err := target.SetState(newIssue.Number, "closed"); err != nil {
+						fmt.Printf("[WARNING] Failed to close %s issue #%d: %v\n", target.Name(), newIssue.Number, err)
+					}
+				}
+			}
+			if err != nil {
+				fmt.Printf("[ERROR] Failed to create %s issue: %v\n", target.Name(), err)
+				failure := models.MigrationStatus{
+					OriginalID: issueID,
+					Error:      err.Error(),
+				}
+				result.Failed = append(result.Failed, failure)
+				job.issueFailed(failure)
+				continue
+			}
 
-		if strings.ToLower(issue.State) == "closed" {
-			state := "closed"
-			createReq.State = &state
+			fmt.Printf("[SUCCESS] Created %s issue #%d for %s issue #%d\n", target.Name(), newIssue.Number, source.Name(), issueID)
+			state.targetCreated(newIssue.Number, newIssue.URL)
+			if existing == nil {
+				targetMarkers = map[string]bool{}
+			}
 		}
-
-		fmt.Printf("[MIGRATE] Creating GitHub issue for GitLab issue #%d\n", issueID)
-		newIssue, _, err := ghClient.Issues.Create(ctx, req.Target.Owner, req.Target.Repo, createReq)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to create GitHub issue: %v\n", err)
-			result.Failed = append(result.Failed, models.MigrationStatus{
-				OriginalID: issueID,
-				Error:      err.Error(),
-			})
-			continue
+		targetNumber := state.record.TargetID
+		targetURL := state.record.TargetURL
+
+		// The issue is only complete once its body has its attachments and
+		// all of its comments are copied
+		var issueErrors []string
+		if !state.bodyDone() {
+			// Attachments are processed once the issue exists, since some
+			// trackers need the issue number to upload files
+			fmt.Printf("[MIGRATE] Processing attachments for issue #%d\n", targetNumber)
+			processedBody := transferAttachments(body, source, target, targetNumber, tracker)
+			bodyWritten := true
+			if processedBody != body {
+				fmt.Printf("[MIGRATE] Issue body changed after processing attachments, updating issue #%d\n", targetNumber)
+				if _, err := target.UpdateIssue(targetNumber, IssueInput{Body: migrationHeader + processedBody}); err != nil {
+					fmt.Printf("[WARNING] Failed to update issue with processed attachments: %v\n", err)
+					issueErrors = append(issueErrors, fmt.Sprintf("failed to update the body with its attachments: %v", err))
+					bodyWritten = false
+				}
+			}
+			if bodyWritten {
+				state.bodyWritten()
+				references.issueWritten(targetNumber, migrationHeader, processedBody)
+			}
 		}
 
-		fmt.Printf("[SUCCESS] Created GitHub issue #%d for GitLab issue #%d\n", newIssue.GetNumber(), issueID)
-
-		// Now process attachments with the actual issue number
-		fmt.Printf("[MIGRATE] Processing attachments for issue #%d\n", newIssue.GetNumber())
-		processedBodyWithAttachments := processGitLabToGitHub(issue.Description, req.Source.BaseURL, req.Source.ProjectID, req.Source.Token, req.Target.Token, req.Target.Session, req.Source.Session, req.Target.Owner, req.Target.Repo, newIssue.GetNumber())
+		if targetMarkers == nil {
+			targetMarkers, err = commentMarkers(target, targetNumber)
+			if err != nil {
+				fmt.Printf("[WARNING] Failed to list existing comments of %s issue #%d: %v\n", target.Name(), targetNumber, err)
+			}
+		}
 
-		// If attachments were processed and the body changed, update the issue
-		if processedBodyWithAttachments != issue.Description {
-			fmt.Printf("[MIGRATE] Issue body changed after processing attachments, updating issue #%d\n", newIssue.GetNumber())
-			updatedBody := migrationHeader + processedBodyWithAttachments
-			updateReq := &github.IssueRequest{
-				Body: &updatedBody,
+		comments, err := source.ListComments(issueID, time.Time{})
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to list comments of issue #%d: %v\n", issueID, err)
+			issueErrors = append(issueErrors, fmt.Sprintf("failed to list comments: %v", err))
+		}
+		comments = prepareComments(comments, req.SystemNotes, source, users)
+		fmt.Printf("[MIGRATE] Processing %d comments for issue #%d\n", len(comments), issueID)
+		for i, comment := range comments {
+			noteMarker := commentMarker(source.Platform(), source.Project(), issueID, comment.ID)
+			if state.commentDone(comment.ID) || targetMarkers[markerToken(noteMarker)] {
+				fmt.Printf("[STATE] Comment %d/%d was already migrated, skipping\n", i+1, len(comments))
+				continue
 			}
-			_, _, err = ghClient.Issues.Edit(ctx, req.Target.Owner, req.Target.Repo, newIssue.GetNumber(), updateReq)
+			processedComment := transferAttachments(users.rewriteMentions(convertMarkdown(comment.Body, source, target)), source, target, targetNumber, tracker)
+			// Include comment timestamp
+			header := commentHeader(noteMarker, source, comment, users)
+			newComment, err := users.addComment(target, targetNumber, comment, header+processedComment)
 			if err != nil {
-				fmt.Printf("[WARNING] Failed to update issue with processed attachments: %v\n", err)
-			} else {
-				fmt.Printf("[SUCCESS] Updated issue #%d with processed attachments\n", newIssue.GetNumber())
+				fmt.Printf("[WARNING] Failed to create comment: %v\n", err)
+				issueErrors = append(issueErrors, fmt.Sprintf("failed to create comment %d: %v", comment.ID, err))
+				continue
 			}
-		} else {
-			fmt.Printf("[INFO] No attachments were processed or body didn't change for issue #%d\n", newIssue.GetNumber())
+			state.commentCreated(comment.ID, newComment.ID)
+			references.commentWritten(targetNumber, newComment.ID, header, processedComment)
+			commentURL := newComment.URL
+			if commentURL == "" {
+				commentURL = targetURL
+			}
+			job.commentCreated(issueID, int(newComment.ID), commentURL)
+		}
+		if req.History {
+			copyHistory(source, target, issueID, targetNumber, time.Time{}, targetMarkers, users, references)
 		}
 
-		// Process notes with timestamps
-		notes, _, err := glClient.Notes.ListIssueNotes(req.Source.ProjectID, issueID, nil)
-		if err == nil {
-			fmt.Printf("[MIGRATE] Processing %d notes for issue #%d\n", len(notes), issueID)
-			for _, note := range notes {
-				processedNote := processGitLabToGitHub(note.Body, req.Source.BaseURL, req.Source.ProjectID, req.Source.Token, req.Target.Token, req.Target.Session, req.Source.Session, req.Target.Owner, req.Target.Repo, newIssue.GetNumber())
-				// Include note timestamp
-				commentHeader := fmt.Sprintf("**@%s** commented on %s",
-					note.Author.Username,
-					note.CreatedAt.Format("2006-01-02 15:04:05 UTC"))
-				if note.UpdatedAt != nil && note.UpdatedAt.After(*note.CreatedAt) {
-					commentHeader += fmt.Sprintf(" _(edited %s)_", note.UpdatedAt.Format("2006-01-02 15:04:05 UTC"))
-				}
-				body := fmt.Sprintf("%s\n\n%s", commentHeader, processedNote)
-				comment := &github.IssueComment{
-					Body: &body,
-				}
-				ghClient.Issues.CreateComment(ctx, req.Target.Owner, req.Target.Repo, newIssue.GetNumber(), comment)
+		if len(issueErrors) > 0 {
+			// Left incomplete so a resumed run finishes the body and copies the missing comments
+			fmt.Printf("[ERROR] Issue #%d was not fully copied to %s issue #%d: %d error(s)\n", issueID, target.Name(), targetNumber, len(issueErrors))
+			failure := models.MigrationStatus{
+				OriginalID: issueID,
+				NewID:      targetNumber,
+				NewURL:     targetURL,
+				Error:      strings.Join(issueErrors, "; "),
 			}
+			result.Failed = append(result.Failed, failure)
+			job.issueFailed(failure)
+			continue
 		}
 
-		result.Success = append(result.Success, models.MigrationStatus{
+		state.complete()
+		success := models.MigrationStatus{
 			OriginalID: issueID,
-			NewID:      newIssue.GetNumber(),
-			NewURL:     newIssue.GetHTMLURL(),
-		})
+			NewID:      targetNumber,
+			NewURL:     targetURL,
+		}
+		result.Success = append(result.Success, success)
+		job.issueSucceeded(success)
 	}
 
+	// References can only be rewritten once every issue has its target number
+	references.rewrite(result)
+	users.report(&result)
 	return result
 }
 
-// processAttachments handles both images and files
-func processAttachments(content string, projectID int, token string, baseURL string, sourceToken string) string {
-	if content == "" {
-		return content
+// issueInput builds the target issue for a translated source issue with the
+// given body, translating its assignees and creating its milestone on the target
+func issueInput(issue *TrackerIssue, body string, users *userMap, milestones *milestoneSync) IssueInput {
+	return IssueInput{
+		Title:        issue.Title,
+		Body:         body,
+		Labels:       issue.Labels,
+		State:        issue.State,
+		Assignees:    users.assignees(issue.Assignees),
+		Milestone:    milestones.ensure(issue.Milestone),
+		DueDate:      issue.DueDate,
+		Weight:       issue.Weight,
+		Confidential: issue.Confidential,
+		HealthStatus: issue.HealthStatus,
+		Epic:         issue.Epic,
 	}
+}
 
-	fmt.Println("[ATTACH] Scanning content for attachments...")
+// warnConfidential warns when a confidential issue is copied to a tracker
+// without confidential issues, where everyone with access can read it
+func warnConfidential(issue *TrackerIssue, source Provider, target Provider) {
+	if issue.Confidential && target.Platform() != "gitlab" {
+		fmt.Printf("[WARNING] %s issue #%d is confidential, but %s has no confidential issues\n", source.Name(), issue.Number, target.Name())
+	}
+}
 
-	// Find all attachment URLs (images and files)
-	attachmentURLs := findAllAttachments(content)
-	fmt.Printf("[ATTACH] Found %d attachment(s) to process\n", len(attachmentURLs))
+// issueHeader builds the migration header for an issue copied from the source
+// tracker; users, when non-nil, credits the author as their target user.
+// Milestone, due date, weight, confidentiality, health status and epic are
+// listed as well, since not every tracker has these fields.
+func issueHeader(marker string, source Provider, issue *TrackerIssue, users *userMap) string {
+	header := marker + "\n"
+	header += fmt.Sprintf("### 🔄 Migrated from %s\n\n", source.Name())
+	header += fmt.Sprintf("**Original Issue:** %s\n", issue.URL)
+	header += fmt.Sprintf("**Original Author:** %s\n", users.credit(issue.Author, source))
+	header += fmt.Sprintf("**Created:** %s\n", issue.CreatedAt.Format("2006-01-02 15:04:05 UTC"))
+	header += fmt.Sprintf("**Last Updated:** %s\n", issue.UpdatedAt.Format("2006-01-02 15:04:05 UTC"))
+	if issue.State == "closed" && issue.ClosedAt != nil {
+		header += fmt.Sprintf("**Closed:** %s\n", issue.ClosedAt.Format("2006-01-02 15:04:05 UTC"))
+	}
+	if issue.Milestone != "" {
+		header += fmt.Sprintf("**Milestone:** %s\n", issue.Milestone)
+	}
+	if issue.DueDate != nil {
+		header += fmt.Sprintf("**Due Date:** %s\n", issue.DueDate.Format("2006-01-02"))
+	}
+	if issue.Weight != nil {
+		header += fmt.Sprintf("**Weight:** %d\n", *issue.Weight)
+	}
+	if issue.Confidential {
+		header += "**Confidential:** yes\n"
+	}
+	if issue.HealthStatus != "" {
+		header += fmt.Sprintf("**Health Status:** %s\n", strings.ReplaceAll(issue.HealthStatus, "_", " "))
+	}
+	if issue.Epic != "" {
+		header += fmt.Sprintf("**Epic:** %s\n", issue.Epic)
+	}
+	header += fmt.Sprintf("**State:** %s\n\n", issue.State)
+	header += "---\n\n"
+	return header
+}
 
-	if len(attachmentURLs) == 0 {
+// commentHeader builds the attribution line for a copied comment, or the
+// title of an activity log built by prepareComments
+func commentHeader(marker string, source Provider, comment *TrackerComment, users *userMap) string {
+	if comment.System {
+		return fmt.Sprintf("%s\n**Activity on %s**\n\n", marker, source.Name())
+	}
+	header := fmt.Sprintf("**%s** commented on %s",
+		users.credit(comment.Author, source),
+		comment.CreatedAt.Format("2006-01-02 15:04:05 UTC"))
+	if comment.UpdatedAt.After(comment.CreatedAt) {
+		header += fmt.Sprintf(" _(edited %s)_", comment.UpdatedAt.Format("2006-01-02 15:04:05 UTC"))
+	}
+	return fmt.Sprintf("%s\n%s\n\n", marker, header)
+}
+
+// transferAttachments downloads the attachments of a body from the source,
+// uploads them to the target issue and rewrites their URLs. Attachments that
+// cannot be transferred keep pointing to the source.
+// tracker, when non-nil, supplies uploads from earlier runs and records new ones
+func transferAttachments(content string, source Provider, target Provider, targetNumber int, tracker *attachmentTracker) string {
+	if content == "" {
 		return content
 	}
 
-	urlMap := make(map[string]AttachmentInfo)
-	client := &http.Client{}
+	fmt.Println("[ATTACH] Scanning content for attachments...")
+	content, attachments := source.FindAttachments(content)
+	fmt.Printf("[ATTACH] Found %d attachment(s) to process\n", len(attachments))
+	if len(attachments) == 0 {
+		return content
+	}
 
-	for i, attachment := range attachmentURLs {
-		fmt.Printf("[ATTACH] Processing attachment %d/%d: %s\n", i+1, len(attachmentURLs), attachment.URL)
+	uploaded := make(map[string]AttachmentInfo)
+	for i, attachment := range attachments {
+		fmt.Printf("[ATTACH] Processing attachment %d/%d: %s\n", i+1, len(attachments), attachment.URL)
 
-		// Download attachment with authentication
-		req, err := http.NewRequest("GET", attachment.URL, nil)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to create request: %v\n", err)
+		if newURL, ok := tracker.lookup(attachment.URL); ok {
+			fmt.Printf("[STATE] Attachment was already uploaded: %s\n", newURL)
+			attachment.NewURL = newURL
+			attachment.IsImage = attachment.IsImage || isImageURL(newURL)
+			uploaded[attachment.URL] = attachment
 			continue
 		}
 
-		// Add GitHub authentication if needed
-		if strings.Contains(attachment.URL, "github.com") && sourceToken != "" {
-			req.Header.Set("Authorization", "Bearer "+sourceToken)
-			fmt.Printf("[ATTACH] Using GitHub authentication for download\n")
-		}
-
-		resp, err := client.Do(req)
+		data, err := source.DownloadAttachment(attachment)
 		if err != nil {
-			fmt.Printf("[ERROR] Failed to download attachment: %v\n", err)
-			continue
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			fmt.Printf("[ERROR] Download failed with status %d\n", resp.StatusCode)
-			resp.Body.Close()
+			fmt.Printf("[WARNING] Cannot download attachment %s: %v\n", attachment.URL, err)
+			fmt.Printf("[INFO] Keeping original URL: %s\n", attachment.URL)
 			continue
 		}
+		fmt.Printf("[ATTACH] Downloaded %d bytes from %s\n", len(data), source.Name())
 
-		data, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+		attachment.Filename = withDetectedExtension(attachment.Filename, data)
+		newURL, err := target.UploadAttachment(targetNumber, data, attachment.Filename)
 		if err != nil {
-			fmt.Printf("[ERROR] Failed to read attachment data: %v\n", err)
-			continue
-		}
-
-		fmt.Printf("[ATTACH] Downloaded %d bytes\n", len(data))
-
-		// Upload to GitLab
-		filename := getFilename(attachment.URL, attachment.OriginalText)
-		fmt.Printf("[ATTACH] Uploading as '%s' to GitLab project %d\n", filename, projectID)
-
-		newURL, err := uploadFileToGitLab(projectID, data, filename, token, baseURL)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to upload file: %v\n", err)
+			fmt.Printf("[WARNING] %s upload failed: %v\n", target.Name(), err)
+			fmt.Printf("[INFO] File will remain on %s: %s\n", source.Name(), attachment.URL)
 			continue
 		}
 
 		fmt.Printf("[SUCCESS] File uploaded successfully. New URL: %s\n", newURL)
+		tracker.record(attachment.URL, newURL)
 		attachment.NewURL = newURL
-		urlMap[attachment.URL] = attachment
+		attachment.IsImage = attachment.IsImage || isImageURL(attachment.Filename) || isImageURL(newURL)
+		uploaded[attachment.URL] = attachment
 	}
 
-	// Replace all old URLs with new ones
-	fmt.Printf("[ATTACH] Replacing %d attachment URL(s) in content\n", len(urlMap))
-	result := content
-
-	for oldURL, attachment := range urlMap {
-		// Convert to relative URL for GitLab
-		relativeURL := attachment.NewURL
-		if strings.Contains(attachment.NewURL, "/uploads/") {
-			parts := strings.Split(attachment.NewURL, "/uploads/")
-			if len(parts) > 1 {
-				relativeURL = "/uploads/" + parts[1]
-			}
-		}
+	fmt.Printf("[ATTACH] Replacing %d attachment URL(s) in content\n", len(uploaded))
+	return replaceAttachmentURLs(content, uploaded)
+}
 
-		// Handle different types of content
-		if attachment.IsImage {
-			// Convert HTML img tags to Markdown
-			if strings.Contains(attachment.OriginalText, "<img") {
-				imgTagRegex := regexp.MustCompile(`<img[^>]*\ssrc=["']` + regexp.QuoteMeta(oldURL) + `["'][^>]*>`)
-				matches := imgTagRegex.FindAllString(result, -1)
-				for _, match := range matches {
-					// Extract alt text
-					altRegex := regexp.MustCompile(`alt=["']([^"']*)["']`)
-					altMatch := altRegex.FindStringSubmatch(match)
-					altText := "Image"
-					if len(altMatch) > 1 && altMatch[1] != "" {
-						altText = altMatch[1]
-					}
-					// Replace with Markdown
-					markdownImg := fmt.Sprintf("![%s](%s)", altText, relativeURL)
-					result = strings.ReplaceAll(result, match, markdownImg)
-				}
-			} else {
-				// Already in Markdown format
-				result = strings.ReplaceAll(result, `(`+oldURL+`)`, `(`+relativeURL+`)`)
-			}
-		} else {
-			// For non-image files, create a link
-			if strings.Contains(attachment.OriginalText, "<a") {
-				// Replace HTML link
-				linkRegex := regexp.MustCompile(`<a[^>]*\shref=["']` + regexp.QuoteMeta(oldURL) + `["'][^>]*>([^<]*)</a>`)
-				matches := linkRegex.FindAllStringSubmatch(result, -1)
-				for _, match := range matches {
-					linkText := "Download"
-					if len(match) > 1 && match[1] != "" {
-						linkText = match[1]
-					}
-					markdownLink := fmt.Sprintf("[%s](%s)", linkText, relativeURL)
-					result = strings.ReplaceAll(result, match[0], markdownLink)
-				}
-			} else {
-				// Direct URL replacement
-				result = strings.ReplaceAll(result, oldURL, relativeURL)
-			}
-		}
+// withDetectedExtension adds an extension detected from the file content to
+// filenames that have none, such as GitLab's generic "Image"
+func withDetectedExtension(filename string, data []byte) string {
+	if strings.Contains(path.Base(filename), ".") && !strings.EqualFold(path.Base(filename), "image") {
+		return filename
 	}
+	detectedExt := detectFileExtension(data)
+	if detectedExt == "" {
+		return filename
+	}
+	if strings.EqualFold(path.Base(filename), "image") {
+		filename = "image" + detectedExt
+	} else if !strings.HasSuffix(filename, detectedExt) {
+		filename = filename + detectedExt
+	}
+	fmt.Printf("[ATTACH] Detected file type: %s, using filename: %s\n", detectedExt, filename)
+	return filename
+}
 
-	return result
+// replaceAttachmentURLs points the Markdown and HTML links to the uploaded
+// files; links in code are left alone
+func replaceAttachmentURLs(content string, uploaded map[string]AttachmentInfo) string {
+	return markdown.ReplaceLinks(content, func(link markdown.LinkRef) string {
+		attachment, ok := uploaded[link.URL]
+		if !ok {
+			return link.URL
+		}
+		fmt.Printf("[ATTACH] Processed replacement for %s -> %s (image: %v)\n", link.URL, attachment.NewURL, attachment.IsImage)
+		return attachment.NewURL
+	})
 }
 
 // AttachmentInfo holds information about an attachment
 type AttachmentInfo struct {
 	URL          string
 	NewURL       string
+	Filename     string
 	IsImage      bool
 	OriginalText string
 }
 
-// findAllAttachments finds all attachment URLs (images and files)
+// findAllAttachments finds all attachment URLs (images and files) in
+// Markdown and HTML links outside code, in the order they appear
 func findAllAttachments(content string) []AttachmentInfo {
 	var attachments []AttachmentInfo
 	seen := make(map[string]bool)
 
-	// Pattern 1: HTML img tags
-	imgTagRegex := regexp.MustCompile(`<img[^>]*\ssrc=["']([^"']+)["'][^>]*>`)
-	matches := imgTagRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range matches {
-		if len(match) > 1 && !seen[match[1]] && isValidURL(match[1]) {
-			attachments = append(attachments, AttachmentInfo{
-				URL:          match[1],
-				IsImage:      true,
-				OriginalText: match[0],
-			})
-			seen[match[1]] = true
-			fmt.Printf("[ATTACH] Found HTML img: %s\n", match[1])
+	for _, link := range markdown.Links(content) {
+		if seen[link.URL] || !isValidURL(link.URL) {
+			continue
 		}
-	}
-
-	// Pattern 2: Markdown images
-	mdImageRegex := regexp.MustCompile(`!\[([^\]]*)\]\(([^)]+)\)`)
-	matches = mdImageRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range matches {
-		if len(match) > 2 && !seen[match[2]] && isValidURL(match[2]) {
-			attachments = append(attachments, AttachmentInfo{
-				URL:          match[2],
-				IsImage:      true,
-				OriginalText: match[0],
-			})
-			seen[match[2]] = true
-			fmt.Printf("[ATTACH] Found Markdown img: %s\n", match[2])
+		if !link.Image && (link.Kind == markdown.PlainURL || !isFileURL(link.URL)) {
+			continue
 		}
+		attachments = append(attachments, AttachmentInfo{
+			URL:          link.URL,
+			IsImage:      link.Image,
+			OriginalText: link.Source,
+		})
+		seen[link.URL] = true
+		fmt.Printf("[ATTACH] Found %s: %s\n", linkDescription(link), link.URL)
 	}
 
-	// Pattern 3: HTML links to files
-	linkRegex := regexp.MustCompile(`<a[^>]*\shref=["']([^"']+)["'][^>]*>([^<]*)</a>`)
-	matches = linkRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range matches {
-		if len(match) > 1 && !seen[match[1]] && isValidURL(match[1]) && isFileURL(match[1]) {
-			attachments = append(attachments, AttachmentInfo{
-				URL:          match[1],
-				IsImage:      false,
-				OriginalText: match[0],
-			})
-			seen[match[1]] = true
-			fmt.Printf("[ATTACH] Found file link: %s\n", match[1])
-		}
-	}
+	return attachments
+}
 
-	// Pattern 4: Markdown links to files
-	mdLinkRegex := regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
-	matches = mdLinkRegex.FindAllStringSubmatch(content, -1)
-	for _, match := range matches {
-		if len(match) > 2 && !seen[match[2]] && !strings.HasPrefix(match[0], "!") &&
-			isValidURL(match[2]) && isFileURL(match[2]) {
-			attachments = append(attachments, AttachmentInfo{
-				URL:          match[2],
-				IsImage:      false,
-				OriginalText: match[0],
-			})
-			seen[match[2]] = true
-			fmt.Printf("[ATTACH] Found Markdown file link: %s\n", match[2])
+// attachmentLinks returns the links outside code whose URL matches pattern,
+// each URL once, in the order they appear
+func attachmentLinks(body string, pattern *regexp.Regexp) []markdown.LinkRef {
+	var links []markdown.LinkRef
+	seen := make(map[string]bool)
+	for _, link := range markdown.Links(body) {
+		if seen[link.URL] || !pattern.MatchString(link.URL) {
+			continue
 		}
+		seen[link.URL] = true
+		links = append(links, link)
 	}
+	return links
+}
 
-	return attachments
+// linkDescription names the syntax of a link for the logs
+func linkDescription(link markdown.LinkRef) string {
+	switch link.Kind {
+	case markdown.InlineImage:
+		return "Markdown img"
+	case markdown.Reference:
+		return "Markdown reference"
+	case markdown.HTMLLink:
+		return "file link"
+	case markdown.HTMLImage:
+		return "HTML img"
+	}
+	return "Markdown file link"
 }
 
 // isValidURL checks if URL is valid
@@ -680,288 +735,33 @@ func guessExtension(url string) string {
 	return ""
 }
 
-// processGitLabToGitHub attempts to download GitLab files and upload to GitHub
-func processGitLabToGitHub(content string, gitlabURL string, projectID int, gitlabToken string, githubToken string, githubSession string, gitlabSession string, githubOwner string, githubRepo string, issueNumber int) string {
-	if content == "" {
-		return content
-	}
-
-	// First ensure all URLs are absolute and fix any old format URLs
-	content = fixGitLabAttachmentURLs(content, gitlabURL, projectID)
-
-	// Find all GitLab attachment URLs
-	attachments := findGitLabAttachments(content, gitlabURL)
-
-	if len(attachments) == 0 {
-		return content
-	}
-
-	fmt.Printf("[ATTACH] Found %d GitLab attachments to process\n", len(attachments))
-
-	// Store URL mappings with associated metadata
-	type UploadedFile struct {
-		NewURL   string
-		Filename string
-		IsImage  bool
-	}
-	uploadedFiles := make(map[string]UploadedFile)
-	client := &http.Client{}
-
-	for _, attachment := range attachments {
-		fmt.Printf("[ATTACH] Processing GitLab attachment: %s\n", attachment.URL)
-
-		// Download from GitLab
-		req, err := http.NewRequest("GET", attachment.URL, nil)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to create request: %v\n", err)
-			continue
-		}
-
-		// Try using GitLab session cookie first (for /uploads/ endpoints)
-		if gitlabSession != "" {
-			req.Header.Set("Cookie", fmt.Sprintf("_gitlab_session=%s", gitlabSession))
-			fmt.Printf("[AUTH] Using GitLab session cookie for download\n")
-		} else if gitlabToken != "" {
-			// Fallback to API token (might work for some endpoints)
-			req.Header.Set("PRIVATE-TOKEN", gitlabToken)
-			fmt.Printf("[AUTH] Using GitLab API token for download\n")
-		}
-
-		resp, err := client.Do(req)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to download from GitLab: %v\n", err)
-			continue
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized {
-				fmt.Printf("[WARNING] Cannot download GitLab attachment (status %d): %s\n", resp.StatusCode, attachment.URL)
-				if gitlabSession == "" {
-					fmt.Printf("[INFO] GitLab /uploads/ URLs require browser session authentication.\n")
-					fmt.Printf("[INFO] To download attachments from private repos, provide a GitLab session cookie.\n")
-					fmt.Printf("[INFO] How to get GitLab session cookie:\n")
-					fmt.Printf("[INFO]   1. Log in to GitLab in your browser\n")
-					fmt.Printf("[INFO]   2. Open Developer Tools (F12)\n")
-					fmt.Printf("[INFO]   3. Go to Application/Storage -> Cookies\n")
-					fmt.Printf("[INFO]   4. Find and copy the '_gitlab_session' cookie value\n")
-				} else {
-					fmt.Printf("[INFO] Session cookie provided but still cannot access. The session may be expired or invalid.\n")
-				}
-				fmt.Printf("[INFO] Alternative workarounds:\n")
-				fmt.Printf("[INFO]   1. Make the GitLab project public temporarily during migration\n")
-				fmt.Printf("[INFO]   2. Manually download and re-upload attachments after migration\n")
-				// Keep the original URL in the content
-				fmt.Printf("[INFO] Keeping original GitLab URL: %s\n", attachment.URL)
-			} else {
-				fmt.Printf("[WARNING] GitLab download returned status %d for URL: %s\n", resp.StatusCode, attachment.URL)
-				fmt.Printf("[DEBUG] Response: %s\n", string(bodyBytes))
-			}
-			resp.Body.Close()
-			continue
-		}
-
-		data, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to read data: %v\n", err)
-			continue
-		}
-
-		fmt.Printf("[ATTACH] Downloaded %d bytes from GitLab\n", len(data))
-
-		// Detect file type and add extension if missing
-		filename := attachment.Filename
-		if !strings.Contains(filename, ".") || strings.HasSuffix(filename, "/Image") || strings.HasSuffix(filename, "/image") {
-			// No extension or generic "Image" name - detect from content
-			detectedExt := detectFileExtension(data)
-			if detectedExt != "" {
-				// If filename is just "Image", replace it with a better name
-				if filename == "Image" || filename == "image" || strings.HasSuffix(filename, "/Image") || strings.HasSuffix(filename, "/image") {
-					filename = "image" + detectedExt
-				} else if !strings.HasSuffix(filename, detectedExt) {
-					filename = filename + detectedExt
-				}
-				fmt.Printf("[ATTACH] Detected file type: %s, using filename: %s\n", detectedExt, filename)
-			}
-		}
-
-		// Try to upload to GitHub (experimental - often fails due to GitHub's security)
-		// Skip if no session provided since it won't work anyway
-		if githubSession == "" {
-			fmt.Printf("[INFO] Skipping GitHub upload (no session cookie provided)\n")
-			fmt.Printf("[INFO] File will remain on GitLab: %s\n", attachment.URL)
-			continue
-		}
-
-		// Try to get repository ID if we have owner and repo
-		var repoID string
-		if githubOwner != "" && githubRepo != "" && githubToken != "" {
-			repoID = getGitHubRepoID(githubOwner, githubRepo, githubToken)
-			if repoID != "" {
-				fmt.Printf("[INFO] Using repository ID: %s for uploads\n", repoID)
-			}
-		}
-
-		// Build the referer URL using the actual issue URL
-		var refererURL string
-		if issueNumber > 0 {
-			refererURL = fmt.Sprintf("https://github.com/%s/%s/issues/%d", githubOwner, githubRepo, issueNumber)
-		} else {
-			// Fallback to issues page if no issue number available
-			refererURL = fmt.Sprintf("https://github.com/%s/%s/issues", githubOwner, githubRepo)
-		}
-		githubURL, err := UploadToGitHubWithRepoAndReferer(data, filename, githubToken, githubSession, repoID, refererURL)
-		if err != nil {
-			fmt.Printf("[WARNING] GitHub upload failed: %v\n", err)
-
-			// Only show detailed message once
-			if !strings.Contains(content, "_GitHub_upload_notice_shown_") {
-				fmt.Printf("[INFO] ========================================\n")
-				fmt.Printf("[INFO] GitHub Upload Limitation:\n")
-				fmt.Printf("[INFO] GitHub's file upload API requires a complete browser session\n")
-				fmt.Printf("[INFO] including CSRF tokens and other security measures that cannot\n")
-				fmt.Printf("[INFO] be easily obtained programmatically.\n")
-				fmt.Printf("[INFO] \n")
-				fmt.Printf("[INFO] Current behavior:\n")
-				fmt.Printf("[INFO] - Files remain hosted on GitLab\n")
-				fmt.Printf("[INFO] - Links are preserved in migrated issues\n")
-				fmt.Printf("[INFO] - Images will display if GitLab repo is public\n")
-				fmt.Printf("[INFO] \n")
-				fmt.Printf("[INFO] Alternatives:\n")
-				fmt.Printf("[INFO] 1. Make GitLab repo public during migration\n")
-				fmt.Printf("[INFO] 2. Manually re-upload important files after migration\n")
-				fmt.Printf("[INFO] 3. Use GitHub Actions for automated uploads\n")
-				fmt.Printf("[INFO] ========================================\n")
-				content = content + "<!-- _GitHub_upload_notice_shown_ -->"
-			}
-
-			fmt.Printf("[INFO] File will remain on GitLab: %s\n", attachment.URL)
-			// Don't replace the URL, keep it pointing to GitLab
-			continue
-		}
-
-		fmt.Printf("[SUCCESS] Uploaded to GitHub: %s\n", githubURL)
-		fmt.Printf("[SUCCESS] Original URL: %s\n", attachment.URL)
-		fmt.Printf("[SUCCESS] Original filename: %s\n", filename)
-
-		// Determine if this is an image based on the filename
-		isImg := isImageURL(filename) || isImageURL(githubURL)
-
-		// Store the mapping with metadata for proper formatting
-		uploadedFiles[attachment.URL] = UploadedFile{
-			NewURL:   githubURL,
-			Filename: filename,
-			IsImage:  isImg,
-		}
-	}
-
-	// Replace successful uploads with proper formatting
-	result := content
-	fmt.Printf("[ATTACH] Replacing %d URLs in content with proper formatting\n", len(uploadedFiles))
-
-	// Process each attachment replacement
-	for oldURL, fileInfo := range uploadedFiles {
-		// Find all occurrences of this URL in the content
-		// We need to check the context to determine how it's currently formatted
-
-		// Pattern 1: Markdown images ![alt](url)
-		imagePattern := regexp.MustCompile(`!\[([^\]]*)\]\(` + regexp.QuoteMeta(oldURL) + `\)`)
-		result = imagePattern.ReplaceAllString(result, `![$1](`+fileInfo.NewURL+`)`)
-
-		// Pattern 2: Markdown links [text](url)
-		linkPattern := regexp.MustCompile(`\[([^\]]+)\]\(` + regexp.QuoteMeta(oldURL) + `\)`)
-		matches := linkPattern.FindAllStringSubmatch(result, -1)
-		for _, match := range matches {
-			if len(match) > 1 {
-				linkText := match[1]
-				// Keep the original link text
-				result = strings.ReplaceAll(result, match[0], fmt.Sprintf("[%s](%s)", linkText, fileInfo.NewURL))
-			}
-		}
-
-		// Pattern 3: HTML img tags
-		imgTagPattern := regexp.MustCompile(`<img[^>]*\ssrc=["']` + regexp.QuoteMeta(oldURL) + `["'][^>]*>`)
-		imgMatches := imgTagPattern.FindAllString(result, -1)
-		for _, match := range imgMatches {
-			// Extract alt text if available
-			altRegex := regexp.MustCompile(`alt=["']([^"']*)["']`)
-			altMatch := altRegex.FindStringSubmatch(match)
-			altText := "Image"
-			if len(altMatch) > 1 && altMatch[1] != "" {
-				altText = altMatch[1]
-			}
-			// Replace with markdown image syntax
-			result = strings.ReplaceAll(result, match, fmt.Sprintf("![%s](%s)", altText, fileInfo.NewURL))
-		}
-
-		// Pattern 4: Plain URLs that haven't been replaced yet
-		// This catches any remaining instances
-		remainingCount := strings.Count(result, oldURL)
-		if remainingCount > 0 {
-			// For plain URLs, wrap them in appropriate markdown based on file type
-			if fileInfo.IsImage {
-				// Replace with markdown image syntax
-				altText := "Image"
-				if fileInfo.Filename != "" && fileInfo.Filename != "image" {
-					altText = fileInfo.Filename
-				}
-				result = strings.ReplaceAll(result, oldURL, fmt.Sprintf("![%s](%s)", altText, fileInfo.NewURL))
-			} else {
-				// Replace with markdown link syntax using filename
-				linkText := fileInfo.Filename
-				if linkText == "" || linkText == "attachment" {
-					linkText = "Download File"
-				}
-				result = strings.ReplaceAll(result, oldURL, fmt.Sprintf("[%s](%s)", linkText, fileInfo.NewURL))
-			}
-		}
-
-		fmt.Printf("[ATTACH] Processed replacement for %s -> %s (image: %v)\n", oldURL, fileInfo.NewURL, fileInfo.IsImage)
-	}
-
-	// Log if content changed
-	if result != content {
-		fmt.Printf("[ATTACH] Content was modified with new URLs\n")
-	} else {
-		fmt.Printf("[ATTACH] WARNING: Content was NOT modified - URLs may not have been replaced\n")
-	}
-
-	return result
-}
-
-// GitLabAttachment represents a GitLab attachment
-type GitLabAttachment struct {
-	URL      string
-	Filename string
-}
-
-// findGitLabAttachments finds all GitLab attachment URLs
-func findGitLabAttachments(content string, gitlabURL string) []GitLabAttachment {
-	var attachments []GitLabAttachment
+// findGitLabAttachments finds the GitLab upload URLs in Markdown and HTML
+// links outside code, in the order they appear
+func findGitLabAttachments(content string, gitlabURL string) []AttachmentInfo {
+	var attachments []AttachmentInfo
 	seen := make(map[string]bool)
 
 	// Pattern for GitLab uploads (new format only, since we convert old to new)
 	// Format: https://gitlab.com/-/project/74006604/uploads/c3365884e9152d7384859c7ac5a16ff4/Image
-	projectUploadPattern := regexp.MustCompile(gitlabURL + `/-/project/(\d+)/uploads/([a-f0-9]+)/([^"'\s\)]+)`)
-
-	matches := projectUploadPattern.FindAllStringSubmatch(content, -1)
-	for _, match := range matches {
-		if len(match) > 3 {
-			fullURL := match[0]
-			projectID := match[1]
-			hash := match[2]
-			filename := match[3]
-
-			if !seen[fullURL] {
-				attachments = append(attachments, GitLabAttachment{
-					URL:      fullURL,
-					Filename: filename,
-				})
-				seen[fullURL] = true
-				fmt.Printf("[ATTACH] Found GitLab attachment: project=%s, hash=%s, file=%s, url=%s\n", projectID, hash, filename, fullURL)
-			}
+	projectUploadPattern := regexp.MustCompile(`^` + regexp.QuoteMeta(gitlabURL) + `/-/project/(\d+)/uploads/([a-f0-9]+)/([^?#]+)`)
+
+	for _, link := range markdown.Links(content) {
+		match := projectUploadPattern.FindStringSubmatch(link.URL)
+		if match == nil || seen[link.URL] {
+			continue
 		}
+		projectID := match[1]
+		hash := match[2]
+		filename := match[3]
+
+		attachments = append(attachments, AttachmentInfo{
+			URL:          link.URL,
+			Filename:     filename,
+			IsImage:      link.Image || isImageURL(filename),
+			OriginalText: link.Source,
+		})
+		seen[link.URL] = true
+		fmt.Printf("[ATTACH] Found GitLab attachment: project=%s, hash=%s, file=%s, url=%s\n", projectID, hash, filename, link.URL)
 	}
 
 	return attachments
@@ -1005,42 +805,27 @@ func getGitHubRepoID(owner string, repo string, token string) string {
 	return fmt.Sprintf("%d", repoInfo.ID)
 }
 
-// fixGitLabAttachmentURLs converts relative GitLab URLs to absolute and ensures proper formatting
+// fixGitLabAttachmentURLs makes the upload URLs of links absolute, in the
+// https://gitlab.com/-/project/{projectID}/uploads/xxx/file form
 func fixGitLabAttachmentURLs(content string, baseURL string, projectID int) string {
 	if content == "" {
 		return content
 	}
 
-	// Convert old format URLs to new format FIRST
-	// Old: /uploads/xxx/file or https://gitlab.com/uploads/xxx/file
-	// New: https://gitlab.com/-/project/{projectID}/uploads/xxx/file
-
-	// Fix relative old format URLs
-	content = strings.ReplaceAll(content, `](/uploads/`, `](/-/project/`+fmt.Sprintf("%d", projectID)+`/uploads/`)
-	content = strings.ReplaceAll(content, `="/uploads/`, `="/-/project/`+fmt.Sprintf("%d", projectID)+`/uploads/`)
-	content = strings.ReplaceAll(content, `='/uploads/`, `='/-/project/`+fmt.Sprintf("%d", projectID)+`/uploads/`)
-
-	// Fix absolute old format URLs
-	oldFormatRegex := regexp.MustCompile(regexp.QuoteMeta(baseURL) + `/uploads/([a-f0-9]+)/([^"'\s\)]+)`)
-	content = oldFormatRegex.ReplaceAllString(content, baseURL+`/-/project/`+fmt.Sprintf("%d", projectID)+`/uploads/$1/$2`)
-
-	// Now handle new format: convert relative to absolute
-	content = strings.ReplaceAll(content, `](/-/project/`, `](`+baseURL+`/-/project/`)
-	content = strings.ReplaceAll(content, `="/-/project/`, `="`+baseURL+`/-/project/`)
-	content = strings.ReplaceAll(content, `='/-/project/`, `='`+baseURL+`/-/project/`)
-
-	// Add a note about external dependencies for GitHub
-	if strings.Contains(content, baseURL+"/uploads/") {
-		header := fmt.Sprintf("_Note: This issue contains attachments hosted on GitLab (%s). ", baseURL)
-		header += "These files will remain accessible as long as the GitLab project exists._\n\n"
-
-		// Only add the header if it's not already there
-		if !strings.Contains(content, header) {
-			content = header + content
-		}
-	}
-
-	return content
+	projectUploads := fmt.Sprintf("/-/project/%d/uploads/", projectID)
+	return markdown.ReplaceLinks(content, func(link markdown.LinkRef) string {
+		switch {
+		// Old format, relative or absolute: /uploads/xxx/file
+		case strings.HasPrefix(link.URL, "/uploads/"):
+			return baseURL + projectUploads + strings.TrimPrefix(link.URL, "/uploads/")
+		case strings.HasPrefix(link.URL, baseURL+"/uploads/"):
+			return baseURL + projectUploads + strings.TrimPrefix(link.URL, baseURL+"/uploads/")
+		// New format, relative
+		case strings.HasPrefix(link.URL, "/-/project/"):
+			return baseURL + link.URL
+		}
+		return link.URL
+	})
 }
 
 // detectFileExtension detects the file type from the binary content using magic bytes
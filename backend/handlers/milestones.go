@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+)
+
+// milestoneSync creates the milestones of migrated issues on the target,
+// copying their description, due date and state from the source
+type milestoneSync struct {
+	source Provider
+	target Provider
+	// defined holds the source milestones by title and known the titles of
+	// the target milestones; both are loaded on first use
+	defined map[string]TrackerMilestone
+	known   map[string]bool
+	// unavailable is set when the target has no milestones or they cannot be listed
+	unavailable bool
+}
+
+func newMilestoneSync(source Provider, target Provider) *milestoneSync {
+	return &milestoneSync{source: source, target: target}
+}
+
+// exists reports whether the target has a milestone with the given title
+func (s *milestoneSync) exists(title string) bool {
+	s.load()
+	return s.known[title]
+}
+
+// ensure creates the milestone with the given title on the target if it
+// does not exist yet and returns the title to set on the target issue. It
+// returns "" if the target has no milestones or the milestone cannot be created.
+func (s *milestoneSync) ensure(title string) string {
+	if title == "" {
+		return ""
+	}
+	s.load()
+	if s.unavailable {
+		return ""
+	}
+	if s.known[title] {
+		return title
+	}
+
+	milestone, ok := s.defined[title]
+	if !ok {
+		milestone = TrackerMilestone{Title: title, State: "open"}
+	}
+	fmt.Printf("[MIGRATE] Creating %s milestone %q\n", s.target.Name(), title)
+	if err := s.target.(milestoneTracker).CreateMilestone(milestone); err != nil {
+		fmt.Printf("[WARNING] Failed to create %s milestone %q: %v\n", s.target.Name(), title, err)
+		return ""
+	}
+	s.known[title] = true
+	return title
+}
+
+func (s *milestoneSync) load() {
+	if s.known != nil || s.unavailable {
+		return
+	}
+	target, ok := s.target.(milestoneTracker)
+	if !ok {
+		fmt.Printf("[INFO] %s has no milestones; milestones are only kept in the migration header\n", s.target.Name())
+		s.unavailable = true
+		return
+	}
+	milestones, err := target.ListMilestones()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list %s milestones, milestones are only kept in the migration header: %v\n", s.target.Name(), err)
+		s.unavailable = true
+		return
+	}
+	s.known = make(map[string]bool, len(milestones))
+	for _, milestone := range milestones {
+		s.known[milestone.Title] = true
+	}
+
+	s.defined = make(map[string]TrackerMilestone)
+	source, ok := s.source.(milestoneTracker)
+	if !ok {
+		return
+	}
+	milestones, err = source.ListMilestones()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list %s milestones, creating milestones with their title only: %v\n", s.source.Name(), err)
+		return
+	}
+	for _, milestone := range milestones {
+		s.defined[milestone.Title] = milestone
+	}
+}
@@ -0,0 +1,740 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/models"
+	"github.com/issue-migrator/backend/store"
+)
+
+// Conflict policies for issues edited on both sides between two mirror runs
+const (
+	ConflictNewestWins = "newest-wins"
+	ConflictOriginWins = "origin-wins"
+	ConflictGitHubWins = "github-wins"
+	ConflictGitLabWins = "gitlab-wins"
+	ConflictSkip       = "skip"
+)
+
+// mirrorClockSkew tolerates small differences between the update times the
+// mirror recorded and the ones the APIs report afterwards
+const mirrorClockSkew = 2 * time.Second
+
+// mirrorScheduler runs mirrors periodically and makes sure a mirror never runs twice at once
+type mirrorScheduler struct {
+	mu    sync.Mutex
+	stops map[string]chan struct{}
+	locks map[string]*sync.Mutex
+}
+
+var mirrors = &mirrorScheduler{
+	stops: make(map[string]chan struct{}),
+	locks: make(map[string]*sync.Mutex),
+}
+
+// CreateMirror registers a bidirectional mirror between a GitHub repository and a GitLab project
+func CreateMirror(c *gin.Context) {
+	if stateStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Mirroring requires the state store"})
+		return
+	}
+
+	var mirror models.MirrorConfig
+	if err := c.ShouldBindJSON(&mirror); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if mirror.GitHub.Owner == "" || mirror.GitHub.Repo == "" || mirror.GitLab.BaseURL == "" || mirror.GitLab.ProjectID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "github.owner, github.repo, gitlab.base_url and gitlab.project_id are required"})
+		return
+	}
+	if mirror.ConflictPolicy == "" {
+		mirror.ConflictPolicy = ConflictNewestWins
+	}
+	switch mirror.ConflictPolicy {
+	case ConflictNewestWins, ConflictOriginWins, ConflictGitHubWins, ConflictGitLabWins, ConflictSkip:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conflict policy"})
+		return
+	}
+
+	mirror.ID = generateJobID()
+	mirror.GitHub.Type = "github"
+	mirror.GitLab.Type = "gitlab"
+	mirror.Watermark = time.Time{}
+
+	if err := stateStore.PutMirror(mirror); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	fmt.Printf("[MIRROR] Registered mirror %s: %s/%s <-> %s project %d\n",
+		mirror.ID, mirror.GitHub.Owner, mirror.GitHub.Repo, mirror.GitLab.BaseURL, mirror.GitLab.ProjectID)
+	mirrors.schedule(mirror)
+
+	c.JSON(http.StatusCreated, redactMirror(mirror))
+}
+
+// ListMirrors returns the registered mirrors without their credentials
+func ListMirrors(c *gin.Context) {
+	if stateStore == nil {
+		c.JSON(http.StatusOK, gin.H{"mirrors": []models.MirrorConfig{}})
+		return
+	}
+
+	list, err := stateStore.ListMirrors()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	redacted := make([]models.MirrorConfig, len(list))
+	for i, mirror := range list {
+		redacted[i] = redactMirror(mirror)
+	}
+	c.JSON(http.StatusOK, gin.H{"mirrors": redacted})
+}
+
+// DeleteMirror stops and removes a mirror
+func DeleteMirror(c *gin.Context) {
+	if stateStore == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mirror not found"})
+		return
+	}
+
+	id := c.Param("id")
+	mirrors.unschedule(id)
+	if err := stateStore.DeleteMirror(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RunMirror starts a mirror run right away and returns its job ID
+func RunMirror(c *gin.Context) {
+	job, err := startMirrorRun(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id": job.ID(),
+		"status": JobQueued,
+	})
+}
+
+// StartMirrors schedules every stored mirror that has an interval
+func StartMirrors() {
+	if stateStore == nil {
+		return
+	}
+	list, err := stateStore.ListMirrors()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to load mirrors: %v\n", err)
+		return
+	}
+	for _, mirror := range list {
+		mirrors.schedule(mirror)
+	}
+}
+
+func (s *mirrorScheduler) schedule(mirror models.MirrorConfig) {
+	if mirror.IntervalSeconds <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.stops[mirror.ID]; ok {
+		return
+	}
+	stop := make(chan struct{})
+	s.stops[mirror.ID] = stop
+
+	interval := time.Duration(mirror.IntervalSeconds) * time.Second
+	fmt.Printf("[MIRROR] Scheduling mirror %s every %s\n", mirror.ID, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := startMirrorRun(mirror.ID); err != nil {
+					fmt.Printf("[MIRROR] Skipping scheduled run of %s: %v\n", mirror.ID, err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *mirrorScheduler) unschedule(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stop, ok := s.stops[id]; ok {
+		close(stop)
+		delete(s.stops, id)
+	}
+}
+
+// lock returns the mutex held while a mirror writes to either side. Full runs
+// give up when it is taken; webhook events wait for it so none are lost.
+func (s *mirrorScheduler) lock(id string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[id] = lock
+	}
+	return lock
+}
+
+// startMirrorRun loads the mirror and runs it in the background as a job
+func startMirrorRun(id string) (*Job, error) {
+	if stateStore == nil {
+		return nil, fmt.Errorf("mirroring requires the state store")
+	}
+	mirror, err := stateStore.GetMirror(id)
+	if err != nil {
+		return nil, err
+	}
+	if mirror == nil {
+		return nil, fmt.Errorf("mirror %s not found", id)
+	}
+	lock := mirrors.lock(id)
+	if !lock.TryLock() {
+		return nil, fmt.Errorf("mirror %s is already running", id)
+	}
+
+	job := newJob(models.MigrationRequest{Direction: "mirror"})
+	run, err := newMirrorRun(mirror, job)
+	if err != nil {
+		lock.Unlock()
+		job.finish(models.MigrationResult{}, err)
+		return nil, err
+	}
+	mirror.LastJobID = job.ID()
+	if _, err := stateStore.UpdateMirror(id, func(stored *models.MirrorConfig) {
+		stored.LastJobID = mirror.LastJobID
+	}); err != nil {
+		fmt.Printf("[WARNING] Failed to save mirror %s: %v\n", id, err)
+	}
+
+	go func() {
+		defer lock.Unlock()
+		runMigrationJob(job, models.MigrationRequest{}, func(models.MigrationRequest, *Job) models.MigrationResult {
+			return run.run()
+		})
+	}()
+	return job, nil
+}
+
+// redactMirror hides tokens and session cookies before a mirror is returned to clients
+func redactMirror(mirror models.MirrorConfig) models.MirrorConfig {
+	for _, endpoint := range []*models.Endpoint{&mirror.GitHub, &mirror.GitLab} {
+		if endpoint.Token != "" {
+			endpoint.Token = "***"
+		}
+		if endpoint.Session != "" {
+			endpoint.Session = "***"
+		}
+	}
+	if mirror.WebhookSecret != "" {
+		mirror.WebhookSecret = "***"
+	}
+	return mirror
+}
+
+// mirrorRun performs one synchronization pass of a mirror.
+// Job progress is reported per pair, keyed by the GitHub issue number.
+type mirrorRun struct {
+	config  *models.MirrorConfig
+	job     *Job
+	github  Provider
+	gitlab  Provider
+	result  models.MigrationResult
+	handled map[int]bool // GitHub numbers of pairs handled in this run
+	// users maps the mentions of text copied to a platform, keyed by that platform
+	users map[string]*userMap
+	// failures counts the failed issues, so handleIssue can tell whether the
+	// issue it handled failed
+	failures int
+	// watermark is the next watermark, held before the changes that failed
+	watermark time.Time
+}
+
+func newMirrorRun(config *models.MirrorConfig, job *Job) (*mirrorRun, error) {
+	ghProvider, err := newProvider(config.GitHub)
+	if err != nil {
+		return nil, err
+	}
+	glProvider, err := newProvider(config.GitLab)
+	if err != nil {
+		return nil, err
+	}
+	return &mirrorRun{
+		config: config,
+		job:    job,
+		github: ghProvider,
+		gitlab: glProvider,
+		result: models.MigrationResult{
+			Success: []models.MigrationStatus{},
+			Failed:  []models.MigrationStatus{},
+		},
+		handled: make(map[int]bool),
+		users:   mirrorUsers(config, ghProvider, glProvider),
+	}, nil
+}
+
+// mirrorUsers builds the user maps of both directions from the mirror's
+// GitHub-to-GitLab mapping
+func mirrorUsers(config *models.MirrorConfig, ghProvider, glProvider Provider) map[string]*userMap {
+	reverse := make(map[string]string, len(config.UserMapping))
+	for ghUser, glUser := range config.UserMapping {
+		reverse[glUser] = ghUser
+	}
+	return map[string]*userMap{
+		"gitlab": newUserMap(models.MigrationRequest{UserMapping: config.UserMapping}, ghProvider, glProvider),
+		"github": newUserMap(models.MigrationRequest{UserMapping: reverse}, glProvider, ghProvider),
+	}
+}
+
+// text converts an issue body or comment written on one side for the other side
+func (r *mirrorRun) text(body string, from, to Provider) string {
+	return r.users[to.Platform()].rewriteMentions(convertMarkdown(body, from, to))
+}
+
+func (r *mirrorRun) run() models.MigrationResult {
+	since := r.config.Watermark
+	runStarted := time.Now().UTC()
+	r.watermark = runStarted
+	fmt.Printf("[MIRROR] Running mirror %s for changes since %s\n", r.config.ID, since.Format(time.RFC3339))
+
+	ghIssues, err := r.github.ListIssues(since)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to list GitHub issues: %v\n", err)
+		r.job.setError(err)
+		return r.result
+	}
+	glIssues, err := r.gitlab.ListIssues(since)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to list GitLab issues: %v\n", err)
+		r.job.setError(err)
+		return r.result
+	}
+	fmt.Printf("[MIRROR] %d GitHub and %d GitLab issue(s) changed\n", len(ghIssues), len(glIssues))
+
+	for _, issue := range ghIssues {
+		r.handleIssue(r.github, issue)
+	}
+	for _, issue := range glIssues {
+		r.handleIssue(r.gitlab, issue)
+	}
+
+	r.config.Watermark = r.watermark
+	r.config.LastRunAt = &runStarted
+	// The mirror is read again so a run never restores a mirror deleted meanwhile
+	found, err := stateStore.UpdateMirror(r.config.ID, func(stored *models.MirrorConfig) {
+		stored.Watermark = r.config.Watermark
+		stored.LastRunAt = r.config.LastRunAt
+	})
+	switch {
+	case err != nil:
+		fmt.Printf("[WARNING] Failed to save mirror watermark: %v\n", err)
+	case !found:
+		fmt.Printf("[MIRROR] Mirror %s was deleted during the run, not saving it\n", r.config.ID)
+	}
+	return r.result
+}
+
+// side returns the provider of a platform
+func (r *mirrorRun) side(platform string) Provider {
+	if platform == "github" {
+		return r.github
+	}
+	return r.gitlab
+}
+
+// other returns the provider on the opposite side of the mirror
+func (r *mirrorRun) other(side Provider) Provider {
+	if side == r.github {
+		return r.gitlab
+	}
+	return r.github
+}
+
+// handleIssue pairs a changed issue with its counterpart on the other side,
+// creating the counterpart when the issue is new
+func (r *mirrorRun) handleIssue(side Provider, issue *TrackerIssue) {
+	other := r.other(side)
+	if side == r.github && r.handled[issue.Number] {
+		return
+	}
+
+	// The watermark stays before an issue that failed, so the next run retries it
+	failures := r.failures
+	defer func() {
+		if retry := issue.UpdatedAt.Add(-time.Second); r.failures > failures && retry.Before(r.watermark) {
+			r.watermark = retry
+		}
+	}()
+
+	pair := r.loadPair(side.Platform(), issue.Number)
+	if pair == nil {
+		var counterpart *TrackerIssue
+		var err error
+		if platform, project, number, ok := parseIssueMarker(issue.Body); ok && platform == other.Platform() && project == other.Project() {
+			// Copy of an issue made by an earlier migration or mirror run
+			if counterpart, err = other.GetIssue(number); err != nil {
+				r.failIssue(side, issue.Number, fmt.Errorf("failed to read %s #%d: %w", other.Name(), number, err))
+				return
+			}
+			pair = newMirrorPair(side.Platform(), issue.Number, number, other.Platform())
+		} else if counterpart, err = other.FindIssueByMarker(issueMarker(side.Platform(), side.Project(), issue.Number)); err != nil {
+			r.failIssue(side, issue.Number, fmt.Errorf("failed to search %s: %w", other.Name(), err))
+			return
+		} else if counterpart != nil {
+			pair = newMirrorPair(side.Platform(), issue.Number, counterpart.Number, side.Platform())
+		}
+		if pair != nil {
+			// Both issues are taken as in sync as they are now; otherwise both
+			// would count as changed and conflict
+			syncedAt(pair, side, issue.UpdatedAt)
+			syncedAt(pair, other, counterpart.UpdatedAt)
+		}
+	}
+
+	if pair == nil {
+		r.createCopy(side, issue)
+		return
+	}
+	if r.handled[pair.GitHubNumber] {
+		return
+	}
+	r.syncPair(pair)
+}
+
+// newMirrorPair builds a pair from the issue number on one side and its counterpart
+func newMirrorPair(platform string, number int, otherNumber int, origin string) *store.MirrorPair {
+	if platform == "github" {
+		return &store.MirrorPair{GitHubNumber: number, GitLabIID: otherNumber, Origin: origin}
+	}
+	return &store.MirrorPair{GitHubNumber: otherNumber, GitLabIID: number, Origin: origin}
+}
+
+// syncedAt records the update time of a pair's issue on one side
+func syncedAt(pair *store.MirrorPair, side Provider, updatedAt time.Time) {
+	if side.Platform() == "github" {
+		pair.GitHubSyncedAt = updatedAt
+	} else {
+		pair.GitLabSyncedAt = updatedAt
+	}
+}
+
+// pairNumber returns the issue number of a pair on one side
+func pairNumber(pair *store.MirrorPair, side Provider) int {
+	if side.Platform() == "github" {
+		return pair.GitHubNumber
+	}
+	return pair.GitLabIID
+}
+
+func (r *mirrorRun) loadPair(platform string, number int) *store.MirrorPair {
+	pair, err := stateStore.GetMirrorPair(r.config.ID, platform, number)
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to read mirror pair for %s issue #%d: %v\n", platform, number, err)
+		return nil
+	}
+	return pair
+}
+
+// syncPair copies issue fields from the side that changed and comments in both directions
+func (r *mirrorRun) syncPair(pair *store.MirrorPair) {
+	r.begin(pair.GitHubNumber)
+
+	ghIssue, err := r.github.GetIssue(pair.GitHubNumber)
+	if err != nil {
+		r.fail(pair.GitHubNumber, err)
+		return
+	}
+	glIssue, err := r.gitlab.GetIssue(pair.GitLabIID)
+	if err != nil {
+		r.fail(pair.GitHubNumber, err)
+		return
+	}
+
+	ghChanged := ghIssue.UpdatedAt.After(pair.GitHubSyncedAt.Add(mirrorClockSkew))
+	glChanged := glIssue.UpdatedAt.After(pair.GitLabSyncedAt.Add(mirrorClockSkew))
+
+	winner := ""
+	switch {
+	case ghChanged && glChanged:
+		winner = r.resolveConflict(pair, ghIssue, glIssue)
+		fmt.Printf("[MIRROR] GitHub #%d and GitLab #%d were both edited; %s applies (winner: %q)\n",
+			pair.GitHubNumber, pair.GitLabIID, r.config.ConflictPolicy, winner)
+	case ghChanged:
+		winner = "github"
+	case glChanged:
+		winner = "gitlab"
+	}
+
+	switch winner {
+	case "github":
+		err = r.push(pair, r.github, ghIssue)
+	case "gitlab":
+		err = r.push(pair, r.gitlab, glIssue)
+	}
+	if err != nil {
+		r.fail(pair.GitHubNumber, err)
+		return
+	}
+
+	conflict := ghChanged && glChanged && winner == ""
+	commentErr := r.copyComments(pair)
+	if conflict {
+		// The synced times stay where they were, so the conflict is reported
+		// again on every run until one of the issues is made to win
+		r.putPair(pair)
+	} else {
+		r.savePair(pair)
+	}
+
+	if commentErr != nil {
+		r.fail(pair.GitHubNumber, commentErr)
+		return
+	}
+	if conflict {
+		r.fail(pair.GitHubNumber, fmt.Errorf("conflict: GitHub #%d and GitLab #%d were both edited; issue fields left unchanged", pair.GitHubNumber, pair.GitLabIID))
+		return
+	}
+	r.succeed(pair, glIssue.URL)
+}
+
+// resolveConflict applies the conflict policy and returns the winning platform, or "" to skip
+func (r *mirrorRun) resolveConflict(pair *store.MirrorPair, ghIssue *TrackerIssue, glIssue *TrackerIssue) string {
+	switch r.config.ConflictPolicy {
+	case ConflictOriginWins:
+		return pair.Origin
+	case ConflictGitHubWins:
+		return "github"
+	case ConflictGitLabWins:
+		return "gitlab"
+	case ConflictSkip:
+		return ""
+	default:
+		if glIssue.UpdatedAt.After(ghIssue.UpdatedAt) {
+			return "gitlab"
+		}
+		return "github"
+	}
+}
+
+// push copies title, body, labels and state of an issue to its counterpart.
+// The copy carries the migration header; an original gets its body back without it.
+func (r *mirrorRun) push(pair *store.MirrorPair, from Provider, issue *TrackerIssue) error {
+	to := r.other(from)
+	toNumber := pairNumber(pair, to)
+
+	var body string
+	if pair.Origin == from.Platform() {
+		processed := transferAttachments(r.text(issue.Body, from, to), from, to, toNumber, pairAttachments(pair, to))
+		body = issueHeader(issueMarker(from.Platform(), from.Project(), issue.Number), from, issue, r.users[to.Platform()]) + processed
+	} else {
+		body = transferAttachments(r.text(stripMigrationHeader(issue.Body), from, to), from, to, toNumber, pairAttachments(pair, to))
+	}
+
+	_, err := to.UpdateIssue(toNumber, IssueInput{
+		Title:  issue.Title,
+		Body:   body,
+		Labels: issue.Labels,
+		State:  issue.State,
+	})
+	if err == nil {
+		fmt.Printf("[MIRROR] Updated %s #%d from %s #%d\n", to.Name(), toNumber, from.Name(), issue.Number)
+	}
+	return err
+}
+
+// copyComments copies comments that only exist on one side. Comments that
+// carry a marker were written by the migrator and are never copied back.
+// It returns an error when any comment could not be copied.
+func (r *mirrorRun) copyComments(pair *store.MirrorPair) error {
+	failed := r.copyCommentsFrom(pair, r.github) + r.copyCommentsFrom(pair, r.gitlab)
+	if failed > 0 {
+		return fmt.Errorf("%d comment(s) could not be mirrored", failed)
+	}
+	return nil
+}
+
+// copyCommentsFrom copies the comments of one side and returns how many
+// could not be copied; a failed listing counts as one
+func (r *mirrorRun) copyCommentsFrom(pair *store.MirrorPair, from Provider) int {
+	to := r.other(from)
+	fromNumber, toNumber := pairNumber(pair, from), pairNumber(pair, to)
+
+	comments, err := from.ListComments(fromNumber, time.Time{})
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list comments of %s #%d: %v\n", from.Name(), fromNumber, err)
+		return 1
+	}
+	existing, err := commentMarkers(to, toNumber)
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list comments of %s #%d: %v\n", to.Name(), toNumber, err)
+		return 1
+	}
+
+	failed := 0
+
+	for _, comment := range comments {
+		marker := commentMarker(from.Platform(), from.Project(), fromNumber, comment.ID)
+		if comment.System || hasMarker(comment.Body) || existing[markerToken(marker)] {
+			continue
+		}
+		processed := transferAttachments(r.text(comment.Body, from, to), from, to, toNumber, pairAttachments(pair, to))
+		newComment, err := to.AddComment(toNumber, commentHeader(marker, from, comment, r.users[to.Platform()])+processed)
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to mirror comment to %s #%d: %v\n", to.Name(), toNumber, err)
+			failed++
+			continue
+		}
+		r.job.commentCreated(pair.GitHubNumber, int(newComment.ID), newComment.URL)
+	}
+	return failed
+}
+
+// createCopy creates the counterpart of an issue that only exists on one side
+func (r *mirrorRun) createCopy(from Provider, issue *TrackerIssue) {
+	to := r.other(from)
+	if from == r.github {
+		r.begin(issue.Number)
+	}
+
+	header := issueHeader(issueMarker(from.Platform(), from.Project(), issue.Number), from, issue, r.users[to.Platform()])
+	body := r.text(issue.Body, from, to)
+	newIssue, err := to.CreateIssue(IssueInput{
+		Title:  issue.Title,
+		Body:   header + body,
+		Labels: issue.Labels,
+	})
+	if err != nil {
+		r.failIssue(from, issue.Number, fmt.Errorf("failed to create %s issue: %w", to.Name(), err))
+		return
+	}
+	if from == r.gitlab {
+		r.begin(newIssue.Number)
+	}
+	if issue.State == "closed" {
+		if err := to.SetState(newIssue.Number, "closed"); err != nil {
+			fmt.Printf("[WARNING] Failed to close %s #%d: %v\n", to.Name(), newIssue.Number, err)
+		}
+	}
+	fmt.Printf("[MIRROR] Created %s #%d for new %s #%d\n", to.Name(), newIssue.Number, from.Name(), issue.Number)
+
+	// Attachments are uploaded once the issue number is known
+	pair := newMirrorPair(from.Platform(), issue.Number, newIssue.Number, from.Platform())
+	processed := transferAttachments(body, from, to, newIssue.Number, pairAttachments(pair, to))
+	if processed != body {
+		if _, err := to.UpdateIssue(newIssue.Number, IssueInput{Body: header + processed}); err != nil {
+			fmt.Printf("[WARNING] Failed to update %s #%d with processed attachments: %v\n", to.Name(), newIssue.Number, err)
+		}
+	}
+
+	commentErr := r.copyComments(pair)
+	r.savePair(pair)
+	if commentErr != nil {
+		r.fail(pair.GitHubNumber, commentErr)
+		return
+	}
+
+	url := newIssue.URL
+	if to == r.github {
+		url = issue.URL
+	}
+	r.succeed(pair, url)
+}
+
+// pairAttachments returns the tracker of the attachments a pair copies to one
+// side. An upload is recorded both ways: the copy is reused when the original
+// is mirrored again, and a copy mirrored back turns into the original again.
+func pairAttachments(pair *store.MirrorPair, to Provider) *attachmentTracker {
+	if pair.Attachments == nil {
+		pair.Attachments = make(map[string]string)
+	}
+	return &attachmentTracker{
+		uploaded: pair.Attachments,
+		onUpload: func(originalURL, newURL string) {
+			pair.Attachments[originalURL] = newURL
+			pair.Attachments[newURL] = originalURL
+			// The side the copy is on may find it under another URL, as GitLab
+			// does with relative /uploads/ links
+			_, found := to.FindAttachments("[](" + newURL + ")")
+			for _, attachment := range found {
+				pair.Attachments[attachment.URL] = originalURL
+			}
+		},
+	}
+}
+
+// savePair records the current update times of both issues so the mirror's
+// own writes are not picked up as changes by the next run
+func (r *mirrorRun) savePair(pair *store.MirrorPair) {
+	if ghIssue, err := r.github.GetIssue(pair.GitHubNumber); err == nil {
+		pair.GitHubSyncedAt = ghIssue.UpdatedAt
+	}
+	if glIssue, err := r.gitlab.GetIssue(pair.GitLabIID); err == nil {
+		pair.GitLabSyncedAt = glIssue.UpdatedAt
+	}
+	r.putPair(pair)
+}
+
+// putPair stores a pair as it is
+func (r *mirrorRun) putPair(pair *store.MirrorPair) {
+	if err := stateStore.PutMirrorPair(r.config.ID, pair); err != nil {
+		fmt.Printf("[WARNING] Failed to save mirror pair GitHub #%d / GitLab #%d: %v\n", pair.GitHubNumber, pair.GitLabIID, err)
+	}
+}
+
+func (r *mirrorRun) begin(number int) {
+	r.handled[number] = true
+	r.job.setTotal(len(r.handled))
+	r.job.issueStarted(number)
+}
+
+func (r *mirrorRun) succeed(pair *store.MirrorPair, url string) {
+	success := models.MigrationStatus{
+		OriginalID: pair.GitHubNumber,
+		NewID:      pair.GitLabIID,
+		NewURL:     url,
+	}
+	r.result.Success = append(r.result.Success, success)
+	r.job.issueSucceeded(success)
+}
+
+func (r *mirrorRun) fail(number int, err error) {
+	r.failures++
+	fmt.Printf("[ERROR] Mirroring GitHub #%d failed: %v\n", number, err)
+	failure := models.MigrationStatus{
+		OriginalID: number,
+		Error:      err.Error(),
+	}
+	r.result.Failed = append(r.result.Failed, failure)
+	r.job.issueFailed(failure)
+}
+
+// failIssue reports a failure before the pair is known. GitLab issues
+// without a GitHub counterpart cannot be keyed in the job progress, so
+// their failures are recorded as job errors.
+func (r *mirrorRun) failIssue(side Provider, number int, err error) {
+	if side == r.github {
+		r.fail(number, err)
+		return
+	}
+	r.failures++
+	fmt.Printf("[ERROR] Mirroring GitLab #%d failed: %v\n", number, err)
+	r.job.setError(err)
+}
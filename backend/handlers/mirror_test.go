@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+	"github.com/issue-migrator/backend/store"
+)
+
+// testMirrorRun returns a run of a mirror between two fake trackers
+func testMirrorRun(config *models.MirrorConfig, ghProvider, glProvider Provider) *mirrorRun {
+	return &mirrorRun{
+		config: config,
+		job:    newJob(models.MigrationRequest{Direction: "mirror"}),
+		github: ghProvider,
+		gitlab: glProvider,
+		result: models.MigrationResult{
+			Success: []models.MigrationStatus{},
+			Failed:  []models.MigrationStatus{},
+		},
+		handled: make(map[int]bool),
+		users:   mirrorUsers(config, ghProvider, glProvider),
+	}
+}
+
+func TestMirrorConflictPolicies(t *testing.T) {
+	synced := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		policy    string
+		wantTitle string // title of both issues after the run; "" if they keep their own
+	}{
+		{ConflictNewestWins, "Edited on GitLab"},
+		{ConflictOriginWins, "Edited on GitHub"},
+		{ConflictGitHubWins, "Edited on GitHub"},
+		{ConflictGitLabWins, "Edited on GitLab"},
+		{ConflictSkip, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			s := useTestStore(t)
+			ghProvider := newFakeProvider("github", "owner/repo")
+			glProvider := newFakeProvider("gitlab", "group/project")
+			// Both sides were edited after the last run, GitLab most recently
+			ghProvider.putIssue(&TrackerIssue{
+				Number:    1,
+				Title:     "Edited on GitHub",
+				Body:      "Original body",
+				UpdatedAt: synced.Add(time.Hour),
+			})
+			glProvider.putIssue(&TrackerIssue{
+				Number:    5,
+				Title:     "Edited on GitLab",
+				Body:      issueMarker("github", "owner/repo", 1) + "\n\nOriginal body",
+				UpdatedAt: synced.Add(2 * time.Hour),
+			})
+			config := &models.MirrorConfig{ID: "mirror", ConflictPolicy: tt.policy}
+			pair := &store.MirrorPair{
+				GitHubNumber:   1,
+				GitLabIID:      5,
+				Origin:         "github",
+				GitHubSyncedAt: synced,
+				GitLabSyncedAt: synced,
+			}
+			if err := s.PutMirror(*config); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.PutMirrorPair(config.ID, pair); err != nil {
+				t.Fatal(err)
+			}
+
+			// A skipped conflict is reported on every run until it is resolved
+			for run := 1; run <= 2; run++ {
+				result := testMirrorRun(config, ghProvider, glProvider).run()
+
+				if tt.wantTitle == "" {
+					if len(result.Failed) != 1 || len(result.Success) != 0 {
+						t.Errorf("run %d: result %+v, want the conflict as a failure", run, result)
+					}
+					continue
+				}
+				if run == 1 && (len(result.Failed) != 0 || len(result.Success) != 1) {
+					t.Errorf("run %d: result %+v, want the pair synced", run, result)
+				}
+				if run == 2 && len(result.Failed) != 0 {
+					t.Errorf("run %d: result %+v, want no conflict after the first run", run, result)
+				}
+			}
+
+			ghTitle, glTitle := ghProvider.issues[1].Title, glProvider.issues[5].Title
+			if tt.wantTitle == "" {
+				if ghTitle != "Edited on GitHub" || glTitle != "Edited on GitLab" {
+					t.Errorf("titles %q and %q, want both left unchanged", ghTitle, glTitle)
+				}
+				stored, err := s.GetMirrorPair(config.ID, "github", 1)
+				if err != nil || stored == nil {
+					t.Fatalf("pair not stored: %v", err)
+				}
+				if !stored.GitHubSyncedAt.Equal(synced) || !stored.GitLabSyncedAt.Equal(synced) {
+					t.Errorf("synced times moved to %s and %s", stored.GitHubSyncedAt, stored.GitLabSyncedAt)
+				}
+				return
+			}
+			if ghTitle != tt.wantTitle || glTitle != tt.wantTitle {
+				t.Errorf("titles %q and %q, want %q on both sides", ghTitle, glTitle, tt.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+// Planned actions of a dry run
+const (
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanResume = "resume" // the target issue exists from an interrupted run; only the missing comments are added
+	PlanSkip   = "skip"
+)
+
+// planMigration reads the selected issues like migrateIssues but only
+// records what would be written to the target. The plan is stored on the job.
+func planMigration(req models.MigrationRequest, job *Job, source Provider, target Provider) models.MigrationResult {
+	result := models.MigrationResult{
+		Success: []models.MigrationStatus{},
+		Failed:  []models.MigrationStatus{},
+	}
+	plan := &models.MigrationPlan{
+		Issues:             []models.PlannedIssue{},
+		LabelsToCreate:     []string{},
+		LabelsToUpdate:     []string{},
+		MilestonesToCreate: []string{},
+		UnmappedUsers:      []string{},
+		UnmappedMentions:   []string{},
+	}
+	labels := make(map[string]bool)
+	milestones := make(map[string]bool)
+	users := newUserMap(req, source, target)
+	translation := newTranslator(req, source, target)
+
+	for _, issueID := range req.IssueIDs {
+		fmt.Printf("[PLAN] Analyzing %s issue #%d\n", source.Name(), issueID)
+		job.issueStarted(issueID)
+
+		planned, err := planIssue(req, source, target, issueID, users, translation)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to plan issue #%d: %v\n", issueID, err)
+			plan.Issues = append(plan.Issues, models.PlannedIssue{
+				OriginalID: issueID,
+				Error:      err.Error(),
+			})
+			failure := models.MigrationStatus{
+				OriginalID: issueID,
+				Error:      err.Error(),
+			}
+			result.Failed = append(result.Failed, failure)
+			job.issueFailed(failure)
+			continue
+		}
+
+		plan.Issues = append(plan.Issues, *planned)
+		if planned.Action == PlanCreate || planned.Action == PlanUpdate {
+			for _, label := range planned.Labels {
+				labels[label] = true
+			}
+			if planned.Milestone != "" {
+				milestones[planned.Milestone] = true
+			}
+		}
+		for _, attachment := range planned.Attachments {
+			if attachment.Error == "" {
+				plan.AttachmentCount++
+				plan.AttachmentBytes += attachment.Size
+			}
+		}
+		success := models.MigrationStatus{
+			OriginalID: issueID,
+			NewID:      planned.TargetID,
+			Skipped:    planned.Action == PlanSkip,
+		}
+		result.Success = append(result.Success, success)
+		job.issueSucceeded(success)
+	}
+
+	plan.LabelsToCreate = missingLabels(target, labels)
+	if req.SyncLabels {
+		created, updated := syncLabels(source, target, translation, true)
+		plan.LabelsToCreate = mergeLabels(plan.LabelsToCreate, created)
+		if updated != nil {
+			plan.LabelsToUpdate = updated
+		}
+	}
+	plan.MilestonesToCreate = missingMilestones(source, target, milestones)
+	plan.UnmappedUsers = users.unmapped()
+	plan.UnmappedMentions = users.unmappedMentions()
+	users.report(&result)
+
+	fmt.Printf("[PLAN] %d issue(s), %d new label(s), %d new milestone(s), %d attachment(s) totalling %d bytes\n",
+		len(plan.Issues), len(plan.LabelsToCreate), len(plan.MilestonesToCreate), plan.AttachmentCount, plan.AttachmentBytes)
+	job.setPlan(plan)
+	return result
+}
+
+// planIssue previews the migration of one source issue
+func planIssue(req models.MigrationRequest, source Provider, target Provider, issueID int, users *userMap, translation *translator) (*models.PlannedIssue, error) {
+	state := loadIssueState(req, issueID)
+	if state.completed() {
+		return &models.PlannedIssue{
+			OriginalID: issueID,
+			Action:     PlanSkip,
+			TargetID:   state.record.TargetID,
+		}, nil
+	}
+
+	issue, err := source.GetIssue(issueID)
+	if err != nil {
+		return nil, err
+	}
+	marker := issueMarker(source.Platform(), source.Project(), issueID)
+	issue, frontMatter := translation.translate(issue)
+	planned := &models.PlannedIssue{
+		OriginalID:  issueID,
+		Action:      PlanCreate,
+		Title:       issue.Title,
+		Body:        frontMatter + issueHeader(marker, source, issue, users) + users.rewriteMentions(convertMarkdown(issue.Body, source, target)),
+		Labels:      issue.Labels,
+		Assignees:   users.assignees(issue.Assignees),
+		Milestone:   issue.Milestone,
+		State:       issue.State,
+		Comments:    []models.PlannedComment{},
+		Attachments: []models.PlannedAttachment{},
+	}
+	if planned.Labels == nil {
+		planned.Labels = []string{}
+	}
+	if planned.Assignees == nil {
+		planned.Assignees = []string{}
+	}
+
+	if state.created() {
+		planned.Action = PlanResume
+		planned.TargetID = state.record.TargetID
+	} else {
+		existing, err := target.FindIssueByMarker(marker)
+		if err != nil {
+			// The migration fails such issues instead of risking a duplicate
+			return nil, fmt.Errorf("failed to search %s: %w", target.Name(), err)
+		}
+		if existing != nil {
+			planned.Action = PlanUpdate
+			planned.TargetID = existing.Number
+		}
+	}
+	if planned.Action != PlanResume {
+		planAttachments(planned, source, issue.Body, state)
+	}
+
+	targetMarkers := map[string]bool{}
+	if planned.TargetID != 0 {
+		targetMarkers, err = commentMarkers(target, planned.TargetID)
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to list existing comments of %s issue #%d: %v\n", target.Name(), planned.TargetID, err)
+		}
+	}
+
+	comments, err := source.ListComments(issueID, time.Time{})
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list comments of issue #%d: %v\n", issueID, err)
+	}
+	comments = prepareComments(comments, req.SystemNotes, source, users)
+	for _, comment := range comments {
+		noteMarker := commentMarker(source.Platform(), source.Project(), issueID, comment.ID)
+		if state.commentDone(comment.ID) || targetMarkers[markerToken(noteMarker)] {
+			planned.Comments = append(planned.Comments, models.PlannedComment{
+				OriginalID: comment.ID,
+				Action:     PlanSkip,
+			})
+			continue
+		}
+		planAttachments(planned, source, comment.Body, state)
+		planned.Comments = append(planned.Comments, models.PlannedComment{
+			OriginalID: comment.ID,
+			Action:     PlanCreate,
+			Body:       commentHeader(noteMarker, source, comment, users) + users.rewriteMentions(convertMarkdown(comment.Body, source, target)),
+		})
+	}
+
+	if req.History {
+		events := issueHistory(source, issueID, time.Time{})
+		if len(events) > 0 {
+			eventsMarker := historyMarker(source.Platform(), source.Project(), issueID, events[0].CreatedAt)
+			if !targetMarkers[markerToken(eventsMarker)] {
+				planned.History = eventsMarker + "\n" + historyBody(events, source, users)
+			}
+		}
+	}
+	return planned, nil
+}
+
+// planAttachments downloads the attachments of a body to measure them. Files
+// uploaded by an earlier run and files already planned are left out.
+func planAttachments(planned *models.PlannedIssue, source Provider, body string, state *issueState) {
+	_, attachments := source.FindAttachments(body)
+	for _, attachment := range attachments {
+		if _, ok := state.record.Attachments[attachment.URL]; ok || plannedAttachment(planned, attachment.URL) {
+			continue
+		}
+		entry := models.PlannedAttachment{
+			URL:      attachment.URL,
+			Filename: attachment.Filename,
+		}
+		data, err := source.DownloadAttachment(attachment)
+		if err != nil {
+			fmt.Printf("[WARNING] Cannot download attachment %s: %v\n", attachment.URL, err)
+			entry.Error = err.Error()
+		} else {
+			entry.Filename = withDetectedExtension(attachment.Filename, data)
+			entry.Size = int64(len(data))
+		}
+		planned.Attachments = append(planned.Attachments, entry)
+	}
+}
+
+func plannedAttachment(planned *models.PlannedIssue, url string) bool {
+	for _, attachment := range planned.Attachments {
+		if attachment.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// missingLabels returns the labels that do not exist on the target yet.
+// Label names are compared case-insensitively, as the trackers do.
+func missingLabels(target Provider, labels map[string]bool) []string {
+	missing := []string{}
+	if len(labels) == 0 {
+		return missing
+	}
+	existing, err := target.ListLabels()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to list %s labels: %v\n", target.Name(), err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, label := range existing {
+		known[strings.ToLower(label.Name)] = true
+	}
+	for label := range labels {
+		if !known[strings.ToLower(label)] {
+			missing = append(missing, label)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// mergeLabels adds the labels that are not in a list yet, ignoring case, and
+// sorts the result
+func mergeLabels(labels []string, more []string) []string {
+	known := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		known[strings.ToLower(label)] = true
+	}
+	for _, label := range more {
+		if !known[strings.ToLower(label)] {
+			known[strings.ToLower(label)] = true
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// missingMilestones returns the milestones that would be created on the
+// target. Nothing is returned if the target has no milestones.
+func missingMilestones(source Provider, target Provider, milestones map[string]bool) []string {
+	missing := []string{}
+	if len(milestones) == 0 {
+		return missing
+	}
+	targetMilestones := newMilestoneSync(source, target)
+	for title := range milestones {
+		if !targetMilestones.exists(title) && !targetMilestones.unavailable {
+			missing = append(missing, title)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
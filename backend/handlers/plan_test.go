@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/issue-migrator/backend/store"
+)
+
+func TestPlanMigrationWritesNothing(t *testing.T) {
+	tests := []struct {
+		name         string
+		record       *store.IssueRecord // state of an earlier run
+		migrated     bool               // whether the target has a copy of the issue
+		wantAction   string
+		wantComments []string // planned action of each comment
+		wantFiles    int
+	}{
+		{
+			name:         "new issue",
+			wantAction:   PlanCreate,
+			wantComments: []string{PlanCreate, PlanCreate},
+			wantFiles:    1,
+		},
+		{
+			name:         "issue migrated before",
+			migrated:     true,
+			wantAction:   PlanUpdate,
+			wantComments: []string{PlanCreate, PlanCreate},
+			wantFiles:    1,
+		},
+		{
+			name:         "interrupted issue",
+			record:       &store.IssueRecord{SourceID: 1, TargetID: 1, BodyDone: true, Comments: map[int64]int64{10: 1001}},
+			migrated:     true,
+			wantAction:   PlanResume,
+			wantComments: []string{PlanSkip, PlanCreate},
+		},
+		{
+			name:         "completed issue",
+			record:       &store.IssueRecord{SourceID: 1, TargetID: 1, BodyDone: true, Completed: true},
+			migrated:     true,
+			wantAction:   PlanSkip,
+			wantComments: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := useTestStore(t)
+			source, target, req := testMigration()
+			req.DryRun = true
+			migration := migrationKey(req)
+			if tt.record != nil {
+				record := *tt.record
+				if err := s.PutIssue(migration, &record); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.migrated {
+				target.putIssue(&TrackerIssue{Number: 1, Body: issueMarker("github", "owner/repo", 1)})
+			}
+			before, err := s.ListIssues(migration)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			job := newJob(req)
+			result := planMigration(req, job, source, target)
+
+			if len(target.writes) > 0 || len(source.writes) > 0 {
+				t.Errorf("dry run wrote %v to the target and %v to the source", target.writes, source.writes)
+			}
+			after, err := s.ListIssues(migration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(after) != fmt.Sprint(before) {
+				t.Errorf("dry run changed the state from %+v to %+v", before, after)
+			}
+			if len(result.Failed) > 0 {
+				t.Fatalf("plan failed: %+v", result.Failed)
+			}
+
+			plan := job.Snapshot().Plan
+			if plan == nil || len(plan.Issues) != 1 {
+				t.Fatalf("plan %+v, want one issue", plan)
+			}
+			planned := plan.Issues[0]
+			if planned.Action != tt.wantAction {
+				t.Errorf("action %q, want %q", planned.Action, tt.wantAction)
+			}
+			var comments []string
+			for _, comment := range planned.Comments {
+				comments = append(comments, comment.Action)
+			}
+			if fmt.Sprint(comments) != fmt.Sprint(tt.wantComments) {
+				t.Errorf("comment actions %v, want %v", comments, tt.wantComments)
+			}
+			if plan.AttachmentCount != tt.wantFiles {
+				t.Errorf("%d attachment(s) planned, want %d", plan.AttachmentCount, tt.wantFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+// TrackerIssue is an issue as seen by any provider
+type TrackerIssue struct {
+	Number       int
+	Title        string
+	Body         string
+	State        string // "open" or "closed"
+	Labels       []string
+	Milestone    string // title of the milestone, if any
+	Author       string
+	Assignees    []string
+	URL          string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ClosedAt     *time.Time
+	DueDate      *time.Time
+	Weight       *int   // GitLab only
+	Confidential bool   // GitLab only
+	HealthStatus string // GitLab only: "on_track", "needs_attention" or "at_risk"
+	Epic         string // GitLab only: title of the epic
+}
+
+// TrackerComment is a comment on an issue as seen by any provider
+type TrackerComment struct {
+	ID        int64
+	Body      string
+	Author    string
+	URL       string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	System    bool // generated by the tracker, e.g. GitLab system notes
+	// InReplyTo is the ID of the previous comment in the thread of a reply,
+	// 0 for comments that start a thread or are not part of one
+	InReplyTo int64
+	// Resolved is set on the first comment of a resolved thread
+	Resolved   bool
+	ResolvedBy string
+}
+
+// TrackerEvent is a change in the history of an issue, such as a label
+// being added; Action is one of the History constants
+type TrackerEvent struct {
+	Action    string
+	Actor     string
+	Subject   string // label name, assignee, milestone title, new title or reference
+	Previous  string // title before a rename
+	CreatedAt time.Time
+}
+
+// TrackerLabel is a label defined in a repository or project
+type TrackerLabel struct {
+	Name        string
+	Color       string // hex RGB without "#", e.g. "d73a4a"; empty if the tracker has no colors
+	Description string
+}
+
+// TrackerMilestone is a milestone defined in a repository or project
+type TrackerMilestone struct {
+	Title       string
+	Description string
+	DueDate     *time.Time
+	State       string // "open" or "closed"
+}
+
+// IssueInput holds the fields written when an issue is created or updated
+type IssueInput struct {
+	Title  string
+	Body   string
+	Labels []string
+	State  string // "open" or "closed"; empty leaves the state unchanged
+	// Assignees are target usernames; nil leaves the assignees unchanged
+	Assignees []string
+	// Milestone is the title of an existing target milestone; empty leaves
+	// the milestone unchanged
+	Milestone string
+	// DueDate and Weight are left unchanged when nil, HealthStatus when
+	// empty. Trackers without due dates, weights, confidential issues or
+	// health statuses ignore them.
+	DueDate      *time.Time
+	Weight       *int
+	Confidential bool
+	HealthStatus string
+	// Epic is the title of an epic of the project's group or its parent
+	// groups; empty leaves the epic unchanged. Only GitLab has epics.
+	Epic string
+}
+
+// Provider is an issue tracker that issues can be migrated from or to.
+// Any provider can be paired with any other as source and target.
+type Provider interface {
+	// Platform identifies the tracker type in markers, e.g. "github"
+	Platform() string
+	// Name is the display name used in migration headers, e.g. "GitHub"
+	Name() string
+	// Project identifies the repository or project in markers
+	Project() string
+
+	// ListIssues returns issues updated since the given time, or all issues for a zero time
+	ListIssues(since time.Time) ([]*TrackerIssue, error)
+	GetIssue(number int) (*TrackerIssue, error)
+	// FindIssueByMarker returns the issue carrying the hidden marker, or nil
+	FindIssueByMarker(marker string) (*TrackerIssue, error)
+	// CreateIssue creates an open issue; the state in the input is ignored
+	CreateIssue(input IssueInput) (*TrackerIssue, error)
+	UpdateIssue(number int, input IssueInput) (*TrackerIssue, error)
+	SetState(number int, state string) error
+	// ListLabels returns the labels defined in the repository or project, or
+	// nil for trackers without label definitions
+	ListLabels() ([]TrackerLabel, error)
+
+	// ListComments returns comments updated since the given time, oldest
+	// first; replies may follow the thread they belong to instead
+	ListComments(number int, since time.Time) ([]*TrackerComment, error)
+	AddComment(number int, body string) (*TrackerComment, error)
+
+	// FindAttachments returns the body with attachment URLs normalized and
+	// the attachments hosted by this tracker
+	FindAttachments(body string) (string, []AttachmentInfo)
+	DownloadAttachment(attachment AttachmentInfo) ([]byte, error)
+	// UploadAttachment stores a file for the given issue and returns the URL to link to
+	UploadAttachment(number int, data []byte, filename string) (string, error)
+}
+
+// userDirectory is implemented by providers that can look up users by
+// email address, so users can be matched between trackers
+type userDirectory interface {
+	// UserEmail returns the email address of a user, or "" if it is not visible
+	UserEmail(username string) (string, error)
+	// FindUserByEmail returns the username with the given email address, or ""
+	FindUserByEmail(email string) (string, error)
+}
+
+// labelEditor is implemented by providers that can define labels with a
+// color and description
+type labelEditor interface {
+	CreateLabel(label TrackerLabel) error
+	// UpdateLabel sets the color and description of the label with the given name
+	UpdateLabel(label TrackerLabel) error
+}
+
+// milestoneTracker is implemented by providers with milestones
+type milestoneTracker interface {
+	ListMilestones() ([]TrackerMilestone, error)
+	// CreateMilestone creates a milestone that issues can then be added to by title
+	CreateMilestone(milestone TrackerMilestone) error
+}
+
+// historyTracker is implemented by providers that can list the events of an issue
+type historyTracker interface {
+	// ListEvents returns the label, state, assignee, milestone, title and
+	// reference events of an issue, oldest first
+	ListEvents(number int) ([]*TrackerEvent, error)
+}
+
+// commentEditor is implemented by providers that can change the body of a
+// comment after it was added
+type commentEditor interface {
+	UpdateComment(number int, commentID int64, body string) error
+}
+
+// projectLinker is implemented by providers whose issues are referenced in
+// Markdown as #123, owner/repo#123 or by their URL
+type projectLinker interface {
+	// WebURL returns the web address of the project, e.g.
+	// "https://github.com/owner/repo"; issue URLs and cross-project
+	// references are derived from it
+	WebURL() (string, error)
+}
+
+// impersonator is implemented by providers that can write issues and
+// comments as other users, keeping their original timestamps
+type impersonator interface {
+	// CanImpersonate reports whether the token may act as other users
+	CanImpersonate() bool
+	CreateIssueAs(author string, createdAt time.Time, input IssueInput) (*TrackerIssue, error)
+	AddCommentAs(number int, author string, createdAt time.Time, body string) (*TrackerComment, error)
+}
+
+// closer is implemented by providers that hold resources, such as an open
+// archive file, until the job using them is done
+type closer interface {
+	Close() error
+}
+
+// closeProviders releases the resources of the providers that hold any
+func closeProviders(providers ...Provider) {
+	for _, provider := range providers {
+		if c, ok := provider.(closer); ok {
+			if err := c.Close(); err != nil {
+				fmt.Printf("[WARNING] Failed to close %s: %v\n", provider.Name(), err)
+			}
+		}
+	}
+}
+
+// sourceOnlyTypes are the trackers that issues can be migrated from but not to
+var sourceOnlyTypes = map[string]bool{
+	"jira":      true,
+	"bitbucket": true,
+	"archive":   true,
+}
+
+// newProvider returns the provider for a migration endpoint
+func newProvider(endpoint models.Endpoint) (Provider, error) {
+	switch endpoint.Type {
+	case "github":
+		if endpoint.Owner == "" || endpoint.Repo == "" {
+			return nil, fmt.Errorf("github endpoint requires owner and repo")
+		}
+		return newGitHubProvider(endpoint), nil
+	case "gitlab":
+		if endpoint.BaseURL == "" || endpoint.ProjectID == 0 {
+			return nil, fmt.Errorf("gitlab endpoint requires base_url and project_id")
+		}
+		return newGitLabProvider(endpoint)
+	case "gitea":
+		if endpoint.BaseURL == "" || endpoint.Owner == "" || endpoint.Repo == "" {
+			return nil, fmt.Errorf("gitea endpoint requires base_url, owner and repo")
+		}
+		return newGiteaProvider(endpoint), nil
+	case "jira":
+		if endpoint.BaseURL == "" || endpoint.ProjectKey == "" || endpoint.Token == "" {
+			return nil, fmt.Errorf("jira endpoint requires base_url, project_key and token")
+		}
+		return newJiraProvider(endpoint), nil
+	case "bitbucket":
+		if endpoint.Owner == "" || endpoint.Repo == "" {
+			return nil, fmt.Errorf("bitbucket endpoint requires owner and repo")
+		}
+		return newBitbucketProvider(endpoint), nil
+	case "azure":
+		if endpoint.Owner == "" || endpoint.Repo == "" || endpoint.Token == "" {
+			return nil, fmt.Errorf("azure endpoint requires owner, repo and token")
+		}
+		return newAzureProvider(endpoint), nil
+	case "archive":
+		if endpoint.ArchivePath == "" {
+			return nil, fmt.Errorf("archive endpoint requires archive_path")
+		}
+		return newArchiveProvider(endpoint)
+	default:
+		return nil, fmt.Errorf("unsupported tracker type %q", endpoint.Type)
+	}
+}
+
+// endpointTypes returns the source and target types of a request. Requests
+// that only set the legacy direction get their types from it.
+func endpointTypes(req models.MigrationRequest) (string, string) {
+	source, target := req.Source.Type, req.Target.Type
+	switch req.Direction {
+	case "github-to-gitlab":
+		if source == "" {
+			source = "github"
+		}
+		if target == "" {
+			target = "gitlab"
+		}
+	case "gitlab-to-github":
+		if source == "" {
+			source = "gitlab"
+		}
+		if target == "" {
+			target = "github"
+		}
+	}
+	return source, target
+}
+
+// appendAttachmentList lists attachments that are not embedded in the body
+// below it, so the attachment pipeline transfers them as well
+func appendAttachmentList(body string, links []string) string {
+	if len(links) == 0 {
+		return body
+	}
+	return strings.TrimSpace(body + "\n\n**Attachments:**\n\n- " + strings.Join(links, "\n- "))
+}
+
+// commentMarkers returns the markers of every comment on an issue
+func commentMarkers(provider Provider, number int) (map[string]bool, error) {
+	comments, err := provider.ListComments(number, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	bodies := make([]string, len(comments))
+	for i, comment := range comments {
+		bodies[i] = comment.Body
+	}
+	return collectMarkers(bodies...), nil
+}
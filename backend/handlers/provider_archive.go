@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/issue-migrator/backend/archive"
+	"github.com/issue-migrator/backend/markdown"
+	"github.com/issue-migrator/backend/models"
+)
+
+// errArchiveReadOnly is returned by the methods that would write to an archive
+var errArchiveReadOnly = errors.New("archives are only supported as a migration source")
+
+// archiveProvider reads issues from an offline archive written by an export,
+// so a migration can run where only the target tracker is reachable. It
+// identifies itself with the platform and project of the exported tracker,
+// so markers match those of a direct migration from it.
+type archiveProvider struct {
+	reader *archive.Reader
+	// attachments maps attachment URLs of the issues read so far to their files
+	attachments map[string]archive.Attachment
+}
+
+func newArchiveProvider(endpoint models.Endpoint) (*archiveProvider, error) {
+	reader, err := archive.Open(endpoint.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	return &archiveProvider{
+		reader:      reader,
+		attachments: make(map[string]archive.Attachment),
+	}, nil
+}
+
+func (p *archiveProvider) Platform() string { return p.reader.Manifest.Source.Platform }
+
+func (p *archiveProvider) Name() string { return p.reader.Manifest.Source.Name }
+
+func (p *archiveProvider) Project() string { return p.reader.Manifest.Source.Project }
+
+func (p *archiveProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	var all []*TrackerIssue
+	for _, number := range p.reader.Manifest.Issues {
+		issue, err := p.GetIssue(number)
+		if err != nil {
+			return nil, err
+		}
+		if issue.UpdatedAt.Before(since) {
+			continue
+		}
+		all = append(all, issue)
+	}
+	return all, nil
+}
+
+func (p *archiveProvider) GetIssue(number int) (*TrackerIssue, error) {
+	issue, err := p.issue(number)
+	if err != nil {
+		return nil, err
+	}
+	return &TrackerIssue{
+		Number:       issue.Number,
+		Title:        issue.Title,
+		Body:         issue.Body,
+		State:        issue.State,
+		Labels:       issue.Labels,
+		Milestone:    issue.Milestone,
+		Author:       issue.Author,
+		Assignees:    issue.Assignees,
+		URL:          issue.URL,
+		CreatedAt:    issue.CreatedAt,
+		UpdatedAt:    issue.UpdatedAt,
+		ClosedAt:     issue.ClosedAt,
+		DueDate:      issue.DueDate,
+		Weight:       issue.Weight,
+		Confidential: issue.Confidential,
+		HealthStatus: issue.HealthStatus,
+		Epic:         issue.Epic,
+	}, nil
+}
+
+// FindIssueByMarker finds nothing, since no issue is ever migrated to an archive
+func (p *archiveProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	return nil, nil
+}
+
+func (p *archiveProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	return nil, errArchiveReadOnly
+}
+
+func (p *archiveProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	return nil, errArchiveReadOnly
+}
+
+func (p *archiveProvider) SetState(number int, state string) error {
+	return errArchiveReadOnly
+}
+
+// ListLabels returns the labels of the exported project; archives of
+// version 1 only name the labels of the archived issues
+func (p *archiveProvider) ListLabels() ([]TrackerLabel, error) {
+	labels := make([]TrackerLabel, len(p.reader.Manifest.Labels))
+	for i, label := range p.reader.Manifest.Labels {
+		labels[i] = TrackerLabel{Name: label.Name, Color: label.Color, Description: label.Description}
+	}
+	return labels, nil
+}
+
+// ListMilestones returns the milestones of the exported project; archives of
+// version 1 only hold the titles of the milestones of the archived issues
+func (p *archiveProvider) ListMilestones() ([]TrackerMilestone, error) {
+	milestones := make([]TrackerMilestone, len(p.reader.Manifest.Milestones))
+	for i, milestone := range p.reader.Manifest.Milestones {
+		state := milestone.State
+		if state == "" {
+			state = "open"
+		}
+		milestones[i] = TrackerMilestone{
+			Title:       milestone.Title,
+			Description: milestone.Description,
+			DueDate:     milestone.DueDate,
+			State:       state,
+		}
+	}
+	return milestones, nil
+}
+
+func (p *archiveProvider) CreateMilestone(milestone TrackerMilestone) error {
+	return errArchiveReadOnly
+}
+
+// WebURL returns the address of the exported project, taken from the URL of
+// its first issue
+func (p *archiveProvider) WebURL() (string, error) {
+	if len(p.reader.Manifest.Issues) == 0 {
+		return "", fmt.Errorf("the archive has no issues")
+	}
+	issue, err := p.issue(p.reader.Manifest.Issues[0])
+	if err != nil {
+		return "", err
+	}
+	webURL := projectURL(issue.URL)
+	if webURL == "" {
+		return "", fmt.Errorf("unknown issue URL %q", issue.URL)
+	}
+	return webURL, nil
+}
+
+func (p *archiveProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	issue, err := p.issue(number)
+	if err != nil {
+		return nil, err
+	}
+	var all []*TrackerComment
+	for _, comment := range issue.Comments {
+		if comment.UpdatedAt.Before(since) {
+			continue
+		}
+		all = append(all, &TrackerComment{
+			ID:         comment.ID,
+			Body:       comment.Body,
+			Author:     comment.Author,
+			URL:        comment.URL,
+			CreatedAt:  comment.CreatedAt,
+			UpdatedAt:  comment.UpdatedAt,
+			System:     comment.System,
+			InReplyTo:  comment.InReplyTo,
+			Resolved:   comment.Resolved,
+			ResolvedBy: comment.ResolvedBy,
+		})
+	}
+	return all, nil
+}
+
+// ListEvents returns the archived history of an issue
+func (p *archiveProvider) ListEvents(number int) ([]*TrackerEvent, error) {
+	issue, err := p.issue(number)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*TrackerEvent, len(issue.Events))
+	for i, event := range issue.Events {
+		events[i] = &TrackerEvent{
+			Action:    event.Action,
+			Actor:     event.Actor,
+			Subject:   event.Subject,
+			Previous:  event.Previous,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+	return events, nil
+}
+
+func (p *archiveProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	return nil, errArchiveReadOnly
+}
+
+// Close closes the archive file
+func (p *archiveProvider) Close() error {
+	return p.reader.Close()
+}
+
+// FindAttachments returns the archived files whose original URLs the body links to
+func (p *archiveProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	var attachments []AttachmentInfo
+	seen := make(map[string]bool)
+	for _, link := range markdown.Links(body) {
+		attachment, ok := p.attachments[link.URL]
+		if !ok || seen[link.URL] {
+			continue
+		}
+		seen[link.URL] = true
+		attachments = append(attachments, AttachmentInfo{
+			URL:          link.URL,
+			Filename:     attachment.Filename,
+			IsImage:      attachment.IsImage,
+			OriginalText: link.Source,
+		})
+		fmt.Printf("[ATTACH] Found archived attachment: %s\n", attachment.Path)
+	}
+	return body, attachments
+}
+
+// DownloadAttachment reads the archived file instead of contacting the source
+func (p *archiveProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	archived, ok := p.attachments[attachment.URL]
+	if !ok {
+		return nil, fmt.Errorf("attachment %s is not in the archive", attachment.URL)
+	}
+	return p.reader.ReadFile(archived.Path)
+}
+
+func (p *archiveProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	return "", errArchiveReadOnly
+}
+
+// issue reads an archived issue and remembers its attachments for the
+// bodies of the issue and its comments
+func (p *archiveProvider) issue(number int) (*archive.Issue, error) {
+	issue, err := p.reader.Issue(number)
+	if err != nil {
+		return nil, fmt.Errorf("issue #%d: %w", number, err)
+	}
+	for _, attachment := range issue.Attachments {
+		p.attachments[attachment.URL] = attachment
+	}
+	return issue, nil
+}
@@ -0,0 +1,761 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+// azureDevOpsURL is the Azure DevOps Services host; base_url overrides it for
+// Azure DevOps Server
+const azureDevOpsURL = "https://dev.azure.com"
+
+// API versions used for work items, work item comments and tags
+const (
+	azureAPIVersion         = "7.1"
+	azureCommentsAPIVersion = "7.1-preview.4"
+	azureTagsAPIVersion     = "7.1-preview.1"
+)
+
+// azureBatchSize is the maximum number of work items fetched per batch request
+const azureBatchSize = 200
+
+// azureDefaultWorkItemType is created for issues without a known "type:" label
+const azureDefaultWorkItemType = "Issue"
+
+// Rich-text fields that hold the body of a work item; bugs use repro steps
+// instead of a description
+const (
+	azureDescriptionField = "System.Description"
+	azureReproStepsField  = "Microsoft.VSTS.TCM.ReproSteps"
+)
+
+// azureProvider reads and writes work items of an Azure DevOps Boards
+// project. Work items are numbered by their ID; their type, area path and
+// tags become labels and their state category decides whether they are open.
+type azureProvider struct {
+	client   *http.Client
+	endpoint models.Endpoint
+	orgURL   string // organization or collection URL
+	// types maps the work item types of the project to their fields and
+	// states, loaded on first use
+	types map[string]*azureWorkItemType
+	// markers maps marker tokens to work item IDs; WIQL cannot search HTML
+	// comments, so the project is scanned once on first use
+	markers         map[string]int
+	attachmentRegex *regexp.Regexp
+}
+
+type azureIdentity struct {
+	DisplayName string `json:"displayName"`
+	UniqueName  string `json:"uniqueName"`
+}
+
+type azureRelation struct {
+	Rel        string                 `json:"rel"`
+	URL        string                 `json:"url"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+type azureWorkItem struct {
+	ID     int `json:"id"`
+	Fields struct {
+		Title        string         `json:"System.Title"`
+		Description  string         `json:"System.Description"`
+		ReproSteps   string         `json:"Microsoft.VSTS.TCM.ReproSteps"`
+		State        string         `json:"System.State"`
+		WorkItemType string         `json:"System.WorkItemType"`
+		TeamProject  string         `json:"System.TeamProject"`
+		AreaPath     string         `json:"System.AreaPath"`
+		Tags         string         `json:"System.Tags"`
+		CreatedBy    *azureIdentity `json:"System.CreatedBy"`
+		AssignedTo   *azureIdentity `json:"System.AssignedTo"`
+		CreatedDate  time.Time      `json:"System.CreatedDate"`
+		ChangedDate  time.Time      `json:"System.ChangedDate"`
+		ClosedDate   *time.Time     `json:"Microsoft.VSTS.Common.ClosedDate"`
+		DueDate      *time.Time     `json:"Microsoft.VSTS.Scheduling.DueDate"`
+	} `json:"fields"`
+	// MultilineFieldsFormat names the rich-text fields stored as Markdown
+	// rather than HTML
+	MultilineFieldsFormat map[string]string `json:"multilineFieldsFormat"`
+	Relations             []azureRelation   `json:"relations"`
+	Links                 struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"_links"`
+}
+
+type azureComment struct {
+	ID           int64          `json:"id"`
+	Text         string         `json:"text"`
+	Format       string         `json:"format"` // "html" or "markdown"
+	CreatedBy    *azureIdentity `json:"createdBy"`
+	CreatedDate  time.Time      `json:"createdDate"`
+	ModifiedDate time.Time      `json:"modifiedDate"`
+	IsDeleted    bool           `json:"isDeleted"`
+}
+
+type azureWorkItemType struct {
+	Name   string `json:"name"`
+	Fields []struct {
+		ReferenceName string `json:"referenceName"`
+	} `json:"fields"`
+	states []azureState
+}
+
+// azureState is a workflow state; its category is one of Proposed,
+// InProgress, Resolved, Completed or Removed
+type azureState struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// azurePatch is one JSON Patch operation on a work item
+type azurePatch struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+func newAzureProvider(endpoint models.Endpoint) *azureProvider {
+	baseURL := strings.TrimSuffix(endpoint.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = azureDevOpsURL
+	}
+	orgURL := baseURL + "/" + url.PathEscape(endpoint.Owner)
+	return &azureProvider{
+		client:   &http.Client{Timeout: 60 * time.Second},
+		endpoint: endpoint,
+		orgURL:   orgURL,
+		attachmentRegex: regexp.MustCompile(`^` + regexp.QuoteMeta(orgURL) +
+			`/(?:[^/\s]+/)?_apis/wit/attachments/[0-9a-fA-F-]{36}(?:\?\S*)?$`),
+	}
+}
+
+func (p *azureProvider) Platform() string { return "azure" }
+
+func (p *azureProvider) Name() string { return "Azure DevOps" }
+
+func (p *azureProvider) Project() string {
+	return azureProject(p.orgURL, p.endpoint.Repo)
+}
+
+// ListIssues queries the work items of the project with WIQL and reads them
+// in batches
+func (p *azureProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	query := "SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project"
+	if !since.IsZero() {
+		query += fmt.Sprintf(" AND [System.ChangedDate] >= '%s'", since.UTC().Format(time.RFC3339))
+	}
+	query += " ORDER BY [System.Id]"
+
+	var result struct {
+		WorkItems []struct {
+			ID int `json:"id"`
+		} `json:"workItems"`
+	}
+	wiql := p.projectURL("/_apis/wit/wiql", url.Values{"timePrecision": {"true"}})
+	if err := p.do("POST", wiql, map[string]string{"query": query}, &result); err != nil {
+		return nil, err
+	}
+
+	var all []*TrackerIssue
+	for start := 0; start < len(result.WorkItems); start += azureBatchSize {
+		end := min(start+azureBatchSize, len(result.WorkItems))
+		ids := make([]int, 0, end-start)
+		for _, item := range result.WorkItems[start:end] {
+			ids = append(ids, item.ID)
+		}
+
+		var batch struct {
+			Value []azureWorkItem `json:"value"`
+		}
+		payload := map[string]interface{}{"ids": ids, "$expand": "relations"}
+		if err := p.do("POST", p.projectURL("/_apis/wit/workitemsbatch", nil), payload, &batch); err != nil {
+			return nil, err
+		}
+		for i := range batch.Value {
+			issue, err := p.trackerIssue(&batch.Value[i])
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, issue)
+		}
+	}
+	return all, nil
+}
+
+func (p *azureProvider) GetIssue(number int) (*TrackerIssue, error) {
+	item, err := p.workItem(number)
+	if err != nil {
+		return nil, err
+	}
+	return p.trackerIssue(item)
+}
+
+func (p *azureProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	if p.markers == nil {
+		issues, err := p.ListIssues(time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		p.markers = make(map[string]int)
+		for _, issue := range issues {
+			p.rememberMarkers(issue)
+		}
+	}
+	number, ok := p.markers[markerToken(marker)]
+	if !ok {
+		return nil, nil
+	}
+	return p.GetIssue(number)
+}
+
+// CreateIssue creates a work item of the type named by a "type:" label, or of
+// the endpoint's default type, with the body stored as Markdown
+func (p *azureProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	workItemType, tags, err := p.splitTypeLabel(input.Labels)
+	if err != nil {
+		return nil, err
+	}
+	bodyField := p.bodyField(workItemType)
+
+	ops := []azurePatch{
+		{Op: "add", Path: "/fields/System.Title", Value: input.Title},
+		{Op: "add", Path: "/fields/" + bodyField, Value: input.Body},
+		{Op: "add", Path: "/multilineFieldsFormat/" + bodyField, Value: "Markdown"},
+	}
+	if len(tags) > 0 {
+		ops = append(ops, azurePatch{Op: "add", Path: "/fields/System.Tags", Value: strings.Join(tags, "; ")})
+	}
+	if len(input.Assignees) > 0 {
+		ops = append(ops, azureAssignment(input.Assignees))
+	}
+
+	var item azureWorkItem
+	createURL := p.projectURL("/_apis/wit/workitems/$"+url.PathEscape(workItemType), nil)
+	if err := p.do("POST", createURL, ops, &item); err != nil {
+		return nil, err
+	}
+	created, err := p.trackerIssue(&item)
+	if err != nil {
+		return nil, err
+	}
+	p.rememberMarkers(created)
+	return created, nil
+}
+
+func (p *azureProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	if err := p.loadTypes(); err != nil {
+		return nil, err
+	}
+	current, err := p.workItem(number)
+	if err != nil {
+		return nil, err
+	}
+	workItemType := current.Fields.WorkItemType
+	bodyField := p.bodyField(workItemType)
+
+	ops := []azurePatch{
+		{Op: "add", Path: "/fields/" + bodyField, Value: input.Body},
+		{Op: "add", Path: "/multilineFieldsFormat/" + bodyField, Value: "Markdown"},
+	}
+	if input.Title != "" {
+		ops = append(ops, azurePatch{Op: "add", Path: "/fields/System.Title", Value: input.Title})
+	}
+	if input.Labels != nil {
+		// The type of an existing work item is kept, so its label is not a tag
+		var tags []string
+		for _, label := range input.Labels {
+			if label != "type: "+workItemType {
+				tags = append(tags, label)
+			}
+		}
+		ops = append(ops, azurePatch{Op: "add", Path: "/fields/System.Tags", Value: strings.Join(tags, "; ")})
+	}
+	if input.State != "" {
+		state, err := p.stateName(workItemType, input.State)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, azurePatch{Op: "add", Path: "/fields/System.State", Value: state})
+	}
+	if input.Assignees != nil {
+		ops = append(ops, azureAssignment(input.Assignees))
+	}
+
+	var item azureWorkItem
+	if err := p.do("PATCH", p.projectURL(fmt.Sprintf("/_apis/wit/workitems/%d", number), nil), ops, &item); err != nil {
+		return nil, err
+	}
+	return p.trackerIssue(&item)
+}
+
+// SetState moves the work item to the first state of its type in the
+// Completed category for "closed", or in the Proposed category for "open"
+func (p *azureProvider) SetState(number int, state string) error {
+	item, err := p.workItem(number)
+	if err != nil {
+		return err
+	}
+	name, err := p.stateName(item.Fields.WorkItemType, state)
+	if err != nil {
+		return err
+	}
+	ops := []azurePatch{{Op: "add", Path: "/fields/System.State", Value: name}}
+	return p.do("PATCH", p.projectURL(fmt.Sprintf("/_apis/wit/workitems/%d", number), nil), ops, nil)
+}
+
+// ListLabels returns the work item tags of the project. Tags have no colors
+// and are created when a work item first uses them.
+func (p *azureProvider) ListLabels() ([]TrackerLabel, error) {
+	var result struct {
+		Value []struct {
+			Name string `json:"name"`
+		} `json:"value"`
+	}
+	tagsURL := p.projectURL("/_apis/wit/tags", url.Values{"api-version": {azureTagsAPIVersion}})
+	if err := p.do("GET", tagsURL, nil, &result); err != nil {
+		return nil, err
+	}
+	labels := make([]TrackerLabel, len(result.Value))
+	for i, tag := range result.Value {
+		labels[i] = TrackerLabel{Name: tag.Name}
+	}
+	return labels, nil
+}
+
+// ListComments returns the discussion entries of a work item
+func (p *azureProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	query := url.Values{}
+	query.Set("order", "asc")
+	query.Set("api-version", azureCommentsAPIVersion)
+	itemURL := p.itemURL(number)
+
+	var all []*TrackerComment
+	for {
+		var page struct {
+			Comments          []azureComment `json:"comments"`
+			ContinuationToken string         `json:"continuationToken"`
+		}
+		if err := p.do("GET", p.projectURL(fmt.Sprintf("/_apis/wit/workItems/%d/comments", number), query), nil, &page); err != nil {
+			return nil, err
+		}
+		for i := range page.Comments {
+			comment := &page.Comments[i]
+			if comment.IsDeleted || comment.ModifiedDate.Before(since) {
+				continue
+			}
+			all = append(all, azureTrackerComment(comment, itemURL))
+		}
+		if page.ContinuationToken == "" {
+			return all, nil
+		}
+		query.Set("continuationToken", page.ContinuationToken)
+	}
+}
+
+func (p *azureProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	query := url.Values{}
+	query.Set("format", "markdown")
+	query.Set("api-version", azureCommentsAPIVersion)
+
+	var comment azureComment
+	commentsURL := p.projectURL(fmt.Sprintf("/_apis/wit/workItems/%d/comments", number), query)
+	if err := p.do("POST", commentsURL, map[string]string{"text": body}, &comment); err != nil {
+		return nil, err
+	}
+	return azureTrackerComment(&comment, p.itemURL(number)), nil
+}
+
+// FindAttachments returns the work item attachments linked from the converted Markdown
+func (p *azureProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	var attachments []AttachmentInfo
+	for _, link := range attachmentLinks(body, p.attachmentRegex) {
+		name := link.Text
+		if parsed, err := url.Parse(link.URL); err == nil && parsed.Query().Get("fileName") != "" {
+			name = parsed.Query().Get("fileName")
+		}
+		filename := sanitizeFilename(name)
+		if filename == "" {
+			filename = "attachment"
+		}
+		attachments = append(attachments, AttachmentInfo{
+			URL:          link.URL,
+			Filename:     filename,
+			IsImage:      link.Image || isImageURL(name),
+			OriginalText: link.Source,
+		})
+		fmt.Printf("[ATTACH] Found Azure DevOps attachment: %s\n", link.URL)
+	}
+	return body, attachments
+}
+
+func (p *azureProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	req, err := http.NewRequest("GET", attachment.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth("", p.endpoint.Token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// UploadAttachment stores the file and attaches it to the work item
+func (p *azureProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	uploadURL := p.projectURL("/_apis/wit/attachments", url.Values{"fileName": {filename}})
+	req, err := http.NewRequest("POST", uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth("", p.endpoint.Token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	fmt.Printf("[ATTACH] Uploading as '%s' to Azure DevOps work item #%d\n", filename, number)
+	var reference struct {
+		URL string `json:"url"`
+	}
+	if err := p.send(req, &reference); err != nil {
+		return "", err
+	}
+
+	ops := []azurePatch{{
+		Op:   "add",
+		Path: "/relations/-",
+		Value: map[string]interface{}{
+			"rel":        "AttachedFile",
+			"url":        reference.URL,
+			"attributes": map[string]string{"name": filename},
+		},
+	}}
+	if err := p.do("PATCH", p.projectURL(fmt.Sprintf("/_apis/wit/workitems/%d", number), nil), ops, nil); err != nil {
+		return "", fmt.Errorf("failed to attach %s to work item #%d: %w", filename, number, err)
+	}
+	return reference.URL + "?fileName=" + url.QueryEscape(filename), nil
+}
+
+func (p *azureProvider) workItem(number int) (*azureWorkItem, error) {
+	var item azureWorkItem
+	itemURL := p.projectURL(fmt.Sprintf("/_apis/wit/workitems/%d", number), url.Values{"$expand": {"relations"}})
+	if err := p.do("GET", itemURL, nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// loadTypes reads the work item types of the project with their fields
+func (p *azureProvider) loadTypes() error {
+	if p.types != nil {
+		return nil
+	}
+	var result struct {
+		Value []*azureWorkItemType `json:"value"`
+	}
+	if err := p.do("GET", p.projectURL("/_apis/wit/workitemtypes", nil), nil, &result); err != nil {
+		return err
+	}
+	p.types = make(map[string]*azureWorkItemType, len(result.Value))
+	for _, workItemType := range result.Value {
+		p.types[workItemType.Name] = workItemType
+	}
+	return nil
+}
+
+// states returns the workflow states of a work item type
+func (p *azureProvider) states(workItemType string) ([]azureState, error) {
+	if err := p.loadTypes(); err != nil {
+		return nil, err
+	}
+	known, ok := p.types[workItemType]
+	if !ok {
+		return nil, fmt.Errorf("unknown work item type %q", workItemType)
+	}
+	if known.states == nil {
+		var result struct {
+			Value []azureState `json:"value"`
+		}
+		statesURL := p.projectURL("/_apis/wit/workitemtypes/"+url.PathEscape(workItemType)+"/states", nil)
+		if err := p.do("GET", statesURL, nil, &result); err != nil {
+			return nil, err
+		}
+		known.states = result.Value
+	}
+	return known.states, nil
+}
+
+// stateCategory returns the category of a work item's state
+func (p *azureProvider) stateCategory(workItemType string, state string) (string, error) {
+	states, err := p.states(workItemType)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range states {
+		if candidate.Name == state {
+			return candidate.Category, nil
+		}
+	}
+	return "", nil
+}
+
+// stateName returns the state a work item of the type moves to when it is
+// opened or closed
+func (p *azureProvider) stateName(workItemType string, state string) (string, error) {
+	states, err := p.states(workItemType)
+	if err != nil {
+		return "", err
+	}
+	category := "Proposed"
+	if state == "closed" {
+		category = "Completed"
+	}
+	for _, candidate := range states {
+		if candidate.Category == category {
+			return candidate.Name, nil
+		}
+	}
+	return "", fmt.Errorf("work item type %q has no state in the %s category", workItemType, category)
+}
+
+// splitTypeLabel picks the work item type from a "type:" label naming a type
+// of the project and returns the remaining labels as tags
+func (p *azureProvider) splitTypeLabel(labels []string) (string, []string, error) {
+	if err := p.loadTypes(); err != nil {
+		return "", nil, err
+	}
+	workItemType := p.endpoint.WorkItemType
+	if workItemType == "" {
+		workItemType = azureDefaultWorkItemType
+	}
+
+	var tags []string
+	typeFound := false
+	for _, label := range labels {
+		name, isType := strings.CutPrefix(label, "type: ")
+		if isType && !typeFound && p.types[name] != nil {
+			workItemType = name
+			typeFound = true
+			continue
+		}
+		tags = append(tags, label)
+	}
+	return workItemType, tags, nil
+}
+
+// bodyField returns the rich-text field that holds the body of a work item
+// type; bugs of the Agile, Scrum and CMMI processes show repro steps instead
+// of a description
+func (p *azureProvider) bodyField(workItemType string) string {
+	if known, ok := p.types[workItemType]; ok {
+		for _, field := range known.Fields {
+			if field.ReferenceName == azureReproStepsField {
+				return azureReproStepsField
+			}
+		}
+	}
+	return azureDescriptionField
+}
+
+func (p *azureProvider) rememberMarkers(issue *TrackerIssue) {
+	if p.markers == nil {
+		return
+	}
+	for token := range collectMarkers(issue.Body) {
+		p.markers[token] = issue.Number
+	}
+}
+
+func (p *azureProvider) trackerIssue(item *azureWorkItem) (*TrackerIssue, error) {
+	body, bodyField := item.Fields.Description, azureDescriptionField
+	if body == "" && item.Fields.ReproSteps != "" {
+		body, bodyField = item.Fields.ReproSteps, azureReproStepsField
+	}
+	if !strings.EqualFold(item.MultilineFieldsFormat[bodyField], "markdown") {
+		body = htmlToMarkdown(body)
+	}
+	body = appendAttachmentList(body, azureAttachmentLinks(item, body))
+
+	category, err := p.stateCategory(item.Fields.WorkItemType, item.Fields.State)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := &TrackerIssue{
+		Number:    item.ID,
+		Title:     item.Fields.Title,
+		Body:      body,
+		State:     "open",
+		Labels:    azureLabels(item, category),
+		Author:    azureUserName(item.Fields.CreatedBy),
+		URL:       item.Links.HTML.Href,
+		CreatedAt: item.Fields.CreatedDate,
+		UpdatedAt: item.Fields.ChangedDate,
+		DueDate:   item.Fields.DueDate,
+	}
+	if item.Fields.AssignedTo != nil {
+		tracked.Assignees = []string{azureUserName(item.Fields.AssignedTo)}
+	}
+	if category == "Completed" || category == "Removed" {
+		tracked.State = "closed"
+		tracked.ClosedAt = item.Fields.ClosedDate
+	}
+	return tracked, nil
+}
+
+func (p *azureProvider) itemURL(number int) string {
+	return fmt.Sprintf("%s/%s/_workitems/edit/%d", p.orgURL, url.PathEscape(p.endpoint.Repo), number)
+}
+
+// projectURL returns the URL of a project API path with the API version set
+func (p *azureProvider) projectURL(apiPath string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	if query.Get("api-version") == "" {
+		query.Set("api-version", azureAPIVersion)
+	}
+	return p.orgURL + "/" + url.PathEscape(p.endpoint.Repo) + apiPath + "?" + query.Encode()
+}
+
+// do sends a JSON request to the API and decodes the response into out.
+// Work item writes take a list of patch operations.
+func (p *azureProvider) do(method string, apiURL string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, apiURL, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("", p.endpoint.Token)
+	if _, isPatch := payload.([]azurePatch); isPatch {
+		req.Header.Set("Content-Type", "application/json-patch+json")
+	} else if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return p.send(req, out)
+}
+
+func (p *azureProvider) send(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("azure devops API %s %s returned status %d: %s", req.Method, req.URL.Path, resp.StatusCode, string(respBody))
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// azureLabels maps the tags, the work item type and the area path below the
+// project root to labels, and keeps the state of removed work items
+func azureLabels(item *azureWorkItem, category string) []string {
+	var labels []string
+	for _, tag := range strings.Split(item.Fields.Tags, ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			labels = append(labels, tag)
+		}
+	}
+	if item.Fields.WorkItemType != "" && !slices.Contains(labels, "type: "+item.Fields.WorkItemType) {
+		labels = append(labels, "type: "+item.Fields.WorkItemType)
+	}
+	if area, ok := strings.CutPrefix(item.Fields.AreaPath, item.Fields.TeamProject+`\`); ok && area != "" {
+		labels = append(labels, "area: "+strings.ReplaceAll(area, `\`, "/"))
+	}
+	if category == "Removed" {
+		labels = append(labels, "status: "+item.Fields.State)
+	}
+	return labels
+}
+
+// azureAttachmentLinks links the attached files that the body does not embed
+func azureAttachmentLinks(item *azureWorkItem, body string) []string {
+	var links []string
+	for _, relation := range item.Relations {
+		if relation.Rel != "AttachedFile" || strings.Contains(body, relation.URL) {
+			continue
+		}
+		name, _ := relation.Attributes["name"].(string)
+		if name == "" {
+			name = path.Base(relation.URL)
+		}
+		link := fmt.Sprintf("[%s](%s?fileName=%s)", name, relation.URL, url.QueryEscape(name))
+		if isImageURL(name) {
+			link = "!" + link
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+func azureTrackerComment(comment *azureComment, itemURL string) *TrackerComment {
+	body := comment.Text
+	if !strings.EqualFold(comment.Format, "markdown") {
+		body = htmlToMarkdown(body)
+	}
+	return &TrackerComment{
+		ID:        comment.ID,
+		Body:      body,
+		Author:    azureUserName(comment.CreatedBy),
+		URL:       itemURL,
+		CreatedAt: comment.CreatedDate,
+		UpdatedAt: comment.ModifiedDate,
+	}
+}
+
+// azureAssignment assigns a work item to the first of the assignees, since
+// work items have a single assignee; an empty list unassigns it
+func azureAssignment(assignees []string) azurePatch {
+	if len(assignees) == 0 {
+		return azurePatch{Op: "add", Path: "/fields/System.AssignedTo", Value: ""}
+	}
+	if len(assignees) > 1 {
+		fmt.Printf("[INFO] Work items have a single assignee, assigning %s only\n", assignees[0])
+	}
+	return azurePatch{Op: "add", Path: "/fields/System.AssignedTo", Value: assignees[0]}
+}
+
+func azureUserName(user *azureIdentity) string {
+	if user == nil {
+		return "unknown"
+	}
+	if user.UniqueName != "" {
+		return user.UniqueName
+	}
+	return user.DisplayName
+}
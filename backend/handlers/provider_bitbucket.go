@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/markdown"
+	"github.com/issue-migrator/backend/models"
+)
+
+// bitbucketAPIURL is the Bitbucket Cloud REST API; base_url overrides it
+const bitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// bitbucketPageSize is the page size used for Bitbucket list endpoints
+const bitbucketPageSize = 50
+
+// errBitbucketReadOnly is returned by the methods that would write to Bitbucket
+var errBitbucketReadOnly = errors.New("bitbucket is only supported as a migration source")
+
+// bitbucketOpenStates are the issue states that stay open on the target
+var bitbucketOpenStates = map[string]bool{
+	"new":     true,
+	"open":    true,
+	"on hold": true,
+}
+
+// bitbucketProvider reads issues of a Bitbucket Cloud repository's issue
+// tracker. Bitbucket is a source only.
+type bitbucketProvider struct {
+	client   *http.Client
+	endpoint models.Endpoint
+	apiURL   string
+	// attachmentRegex matches the URLs of files listed by the attachment API and
+	// images uploaded into issue content
+	attachmentRegex *regexp.Regexp
+}
+
+type bitbucketUser struct {
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+}
+
+type bitbucketNamed struct {
+	Name string `json:"name"`
+}
+
+type bitbucketContent struct {
+	Raw string `json:"raw"`
+}
+
+type bitbucketLinks struct {
+	Self struct {
+		Href string `json:"href"`
+	} `json:"self"`
+	HTML struct {
+		Href string `json:"href"`
+	} `json:"html"`
+}
+
+type bitbucketIssue struct {
+	ID        int              `json:"id"`
+	Title     string           `json:"title"`
+	Content   bitbucketContent `json:"content"`
+	State     string           `json:"state"`
+	Kind      string           `json:"kind"`
+	Priority  string           `json:"priority"`
+	Component *bitbucketNamed  `json:"component"`
+	Reporter  *bitbucketUser   `json:"reporter"`
+	Assignee  *bitbucketUser   `json:"assignee"`
+	Milestone *bitbucketNamed  `json:"milestone"`
+	CreatedOn time.Time        `json:"created_on"`
+	UpdatedOn time.Time        `json:"updated_on"`
+	Links     bitbucketLinks   `json:"links"`
+}
+
+type bitbucketComment struct {
+	ID        int64            `json:"id"`
+	Content   bitbucketContent `json:"content"`
+	User      *bitbucketUser   `json:"user"`
+	CreatedOn time.Time        `json:"created_on"`
+	UpdatedOn *time.Time       `json:"updated_on"`
+	Links     bitbucketLinks   `json:"links"`
+}
+
+type bitbucketAttachment struct {
+	Name  string         `json:"name"`
+	Links bitbucketLinks `json:"links"`
+}
+
+func newBitbucketProvider(endpoint models.Endpoint) *bitbucketProvider {
+	apiURL := strings.TrimSuffix(endpoint.BaseURL, "/")
+	if apiURL == "" {
+		apiURL = bitbucketAPIURL
+	}
+	repoAPI := fmt.Sprintf("%s/repositories/%s/%s", apiURL, endpoint.Owner, endpoint.Repo)
+	return &bitbucketProvider{
+		client:   &http.Client{Timeout: 60 * time.Second},
+		endpoint: endpoint,
+		apiURL:   apiURL,
+		attachmentRegex: regexp.MustCompile(`^(?:` + regexp.QuoteMeta(repoAPI) +
+			`/issues/\d+/attachments/|https://bitbucket\.org/repo/[^/\s]+/images/)\S+$`),
+	}
+}
+
+func (p *bitbucketProvider) Platform() string { return "bitbucket" }
+
+func (p *bitbucketProvider) Name() string { return "Bitbucket" }
+
+func (p *bitbucketProvider) Project() string {
+	return bitbucketProject(p.endpoint.Owner, p.endpoint.Repo)
+}
+
+// ListIssues lists issues for the issue picker and for syncs. The attachment
+// listing needs one request per issue, so it is only added for syncs; the
+// migration itself reads each issue with GetIssue.
+func (p *bitbucketProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	query := url.Values{}
+	query.Set("pagelen", fmt.Sprintf("%d", bitbucketPageSize))
+	query.Set("sort", "id")
+	if !since.IsZero() {
+		query.Set("q", "updated_on >= "+since.UTC().Format(time.RFC3339))
+	}
+
+	var all []*TrackerIssue
+	next := p.repoURL("/issues") + "?" + query.Encode()
+	for next != "" {
+		var page struct {
+			Values []bitbucketIssue `json:"values"`
+			Next   string           `json:"next"`
+		}
+		if err := p.do(next, &page); err != nil {
+			return nil, err
+		}
+		for i := range page.Values {
+			issue := bitbucketTrackerIssue(&page.Values[i])
+			if !since.IsZero() {
+				if err := p.addAttachmentList(issue); err != nil {
+					return nil, err
+				}
+			}
+			all = append(all, issue)
+		}
+		next = page.Next
+	}
+	return all, nil
+}
+
+func (p *bitbucketProvider) GetIssue(number int) (*TrackerIssue, error) {
+	var issue bitbucketIssue
+	if err := p.do(p.repoURL(fmt.Sprintf("/issues/%d", number)), &issue); err != nil {
+		return nil, err
+	}
+	tracked := bitbucketTrackerIssue(&issue)
+	if err := p.addAttachmentList(tracked); err != nil {
+		return nil, err
+	}
+	return tracked, nil
+}
+
+// FindIssueByMarker finds nothing, since no issue is ever migrated to Bitbucket
+func (p *bitbucketProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	return nil, nil
+}
+
+func (p *bitbucketProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	return nil, errBitbucketReadOnly
+}
+
+func (p *bitbucketProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	return nil, errBitbucketReadOnly
+}
+
+func (p *bitbucketProvider) SetState(number int, state string) error {
+	return errBitbucketReadOnly
+}
+
+// ListLabels returns nil, since Bitbucket issues have no labels
+func (p *bitbucketProvider) ListLabels() ([]TrackerLabel, error) {
+	return nil, nil
+}
+
+func (p *bitbucketProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	query := url.Values{}
+	query.Set("pagelen", fmt.Sprintf("%d", bitbucketPageSize))
+	query.Set("sort", "created_on")
+
+	var all []*TrackerComment
+	next := p.repoURL(fmt.Sprintf("/issues/%d/comments", number)) + "?" + query.Encode()
+	for next != "" {
+		var page struct {
+			Values []bitbucketComment `json:"values"`
+			Next   string             `json:"next"`
+		}
+		if err := p.do(next, &page); err != nil {
+			return nil, err
+		}
+		for i := range page.Values {
+			comment := bitbucketTrackerComment(&page.Values[i])
+			if comment.Body == "" || comment.UpdatedAt.Before(since) {
+				// Comments without content only record state changes
+				continue
+			}
+			all = append(all, comment)
+		}
+		next = page.Next
+	}
+	return all, nil
+}
+
+func (p *bitbucketProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	return nil, errBitbucketReadOnly
+}
+
+// FindAttachments returns the attachments and uploaded images linked from the body
+func (p *bitbucketProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	var attachments []AttachmentInfo
+	for _, link := range attachmentLinks(body, p.attachmentRegex) {
+		name := link.Text
+		if name == "" || link.Kind == markdown.PlainURL {
+			name, _ = url.PathUnescape(path.Base(link.URL))
+		}
+		attachments = append(attachments, AttachmentInfo{
+			URL:          link.URL,
+			Filename:     sanitizeFilename(name),
+			IsImage:      link.Image || isImageURL(name),
+			OriginalText: link.Source,
+		})
+		fmt.Printf("[ATTACH] Found Bitbucket attachment: %s\n", link.URL)
+	}
+	return body, attachments
+}
+
+// DownloadAttachment fetches a file; the attachment API redirects to storage
+// that must not receive the credentials, which Go drops on cross-host redirects
+func (p *bitbucketProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	req, err := http.NewRequest("GET", attachment.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (p *bitbucketProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	return "", errBitbucketReadOnly
+}
+
+// addAttachmentList lists the files from the issue's attachment API below the
+// body; Bitbucket keeps them apart from the issue content
+func (p *bitbucketProvider) addAttachmentList(issue *TrackerIssue) error {
+	var links []string
+	next := p.repoURL(fmt.Sprintf("/issues/%d/attachments", issue.Number))
+	for next != "" {
+		var page struct {
+			Values []bitbucketAttachment `json:"values"`
+			Next   string                `json:"next"`
+		}
+		if err := p.do(next, &page); err != nil {
+			return fmt.Errorf("failed to list attachments of issue #%d: %w", issue.Number, err)
+		}
+		for _, attachment := range page.Values {
+			href := attachment.Links.Self.Href
+			if strings.Contains(issue.Body, href) {
+				continue
+			}
+			if isImageURL(attachment.Name) {
+				links = append(links, fmt.Sprintf("![%s](%s)", attachment.Name, href))
+			} else {
+				links = append(links, fmt.Sprintf("[%s](%s)", attachment.Name, href))
+			}
+		}
+		next = page.Next
+	}
+	issue.Body = appendAttachmentList(issue.Body, links)
+	return nil
+}
+
+func (p *bitbucketProvider) repoURL(path string) string {
+	return fmt.Sprintf("%s/repositories/%s/%s%s", p.apiURL, url.PathEscape(p.endpoint.Owner), url.PathEscape(p.endpoint.Repo), path)
+}
+
+func (p *bitbucketProvider) authorize(req *http.Request) {
+	if p.endpoint.Username != "" {
+		req.SetBasicAuth(p.endpoint.Username, p.endpoint.Token)
+	} else if p.endpoint.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.endpoint.Token)
+	}
+}
+
+// do fetches an API URL, including the next-page URLs returned by Bitbucket,
+// and decodes the response into out
+func (p *bitbucketProvider) do(apiURL string, out interface{}) error {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	p.authorize(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bitbucket API GET %s returned status %d: %s", req.URL.Path, resp.StatusCode, string(respBody))
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// bitbucketLabels maps the kind, priority and component to labels, and keeps
+// states such as "wontfix" or "duplicate" that only become "closed" on the target
+func bitbucketLabels(issue *bitbucketIssue) []string {
+	var labels []string
+	if issue.Kind != "" {
+		labels = append(labels, "type: "+issue.Kind)
+	}
+	if issue.Priority != "" {
+		labels = append(labels, "priority: "+issue.Priority)
+	}
+	if issue.Component != nil && issue.Component.Name != "" {
+		labels = append(labels, "component: "+issue.Component.Name)
+	}
+	switch issue.State {
+	case "new", "open", "resolved", "closed":
+	default:
+		labels = append(labels, "status: "+issue.State)
+	}
+	return labels
+}
+
+func bitbucketTrackerIssue(issue *bitbucketIssue) *TrackerIssue {
+	tracked := &TrackerIssue{
+		Number:    issue.ID,
+		Title:     issue.Title,
+		Body:      issue.Content.Raw,
+		State:     "closed",
+		Labels:    bitbucketLabels(issue),
+		Author:    bitbucketUserName(issue.Reporter),
+		URL:       issue.Links.HTML.Href,
+		CreatedAt: issue.CreatedOn,
+		UpdatedAt: issue.UpdatedOn,
+	}
+	if issue.Assignee != nil {
+		tracked.Assignees = []string{bitbucketUserName(issue.Assignee)}
+	}
+	if issue.Milestone != nil {
+		tracked.Milestone = issue.Milestone.Name
+	}
+	if bitbucketOpenStates[issue.State] {
+		tracked.State = "open"
+	}
+	return tracked
+}
+
+func bitbucketTrackerComment(comment *bitbucketComment) *TrackerComment {
+	tracked := &TrackerComment{
+		ID:        comment.ID,
+		Body:      comment.Content.Raw,
+		Author:    bitbucketUserName(comment.User),
+		URL:       comment.Links.HTML.Href,
+		CreatedAt: comment.CreatedOn,
+		UpdatedAt: comment.CreatedOn,
+	}
+	if comment.UpdatedOn != nil {
+		tracked.UpdatedAt = *comment.UpdatedOn
+	}
+	return tracked
+}
+
+func bitbucketUserName(user *bitbucketUser) string {
+	if user == nil {
+		return "unknown"
+	}
+	if user.Nickname != "" {
+		return user.Nickname
+	}
+	return user.DisplayName
+}
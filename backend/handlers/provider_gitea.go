@@ -0,0 +1,606 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/markdown"
+	"github.com/issue-migrator/backend/models"
+)
+
+// giteaPageSize is the page size used for Gitea list endpoints
+const giteaPageSize = 50
+
+// giteaProvider reads and writes issues of a Gitea or Forgejo repository
+// through the REST API (/api/v1), which both share
+type giteaProvider struct {
+	client   *http.Client
+	endpoint models.Endpoint
+	labelIDs map[string]int64 // label name -> ID, loaded on first use
+	// milestoneIDs maps milestone titles to IDs, loaded on first use
+	milestoneIDs map[string]int64
+	// markers maps marker tokens to issue numbers; Gitea's issue search does
+	// not index HTML comments, so the repository is scanned once on first use
+	markers map[string]int
+}
+
+type giteaUser struct {
+	Login string `json:"login"`
+	Email string `json:"email"`
+}
+
+type giteaLabel struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+type giteaMilestone struct {
+	ID          int64      `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	DueOn       *time.Time `json:"due_on"`
+}
+
+type giteaIssue struct {
+	Number    int             `json:"number"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	State     string          `json:"state"`
+	Labels    []giteaLabel    `json:"labels"`
+	Milestone *giteaMilestone `json:"milestone"`
+	DueDate   *time.Time      `json:"due_date"`
+	User      giteaUser       `json:"user"`
+	Assignees []giteaUser     `json:"assignees"`
+	HTMLURL   string          `json:"html_url"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	ClosedAt  *time.Time      `json:"closed_at"`
+}
+
+type giteaComment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	User      giteaUser `json:"user"`
+	HTMLURL   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newGiteaProvider(endpoint models.Endpoint) *giteaProvider {
+	endpoint.BaseURL = strings.TrimSuffix(endpoint.BaseURL, "/")
+	return &giteaProvider{
+		client:   &http.Client{Timeout: 60 * time.Second},
+		endpoint: endpoint,
+	}
+}
+
+func (p *giteaProvider) Platform() string { return "gitea" }
+
+func (p *giteaProvider) Name() string { return "Gitea" }
+
+func (p *giteaProvider) Project() string {
+	return giteaProject(p.endpoint.BaseURL, p.endpoint.Owner, p.endpoint.Repo)
+}
+
+func (p *giteaProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	query := url.Values{}
+	query.Set("state", "all")
+	query.Set("type", "issues")
+	query.Set("limit", fmt.Sprintf("%d", giteaPageSize))
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+
+	var all []*TrackerIssue
+	for page := 1; ; page++ {
+		query.Set("page", fmt.Sprintf("%d", page))
+		var issues []giteaIssue
+		if err := p.do("GET", p.repoPath("/issues")+"?"+query.Encode(), nil, &issues); err != nil {
+			return nil, err
+		}
+		for i := range issues {
+			all = append(all, giteaTrackerIssue(&issues[i]))
+		}
+		if len(issues) < giteaPageSize {
+			return all, nil
+		}
+	}
+}
+
+func (p *giteaProvider) GetIssue(number int) (*TrackerIssue, error) {
+	var issue giteaIssue
+	if err := p.do("GET", p.repoPath(fmt.Sprintf("/issues/%d", number)), nil, &issue); err != nil {
+		return nil, err
+	}
+	return giteaTrackerIssue(&issue), nil
+}
+
+func (p *giteaProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	if p.markers == nil {
+		issues, err := p.ListIssues(time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		p.markers = make(map[string]int)
+		for _, issue := range issues {
+			p.rememberMarkers(issue)
+		}
+	}
+	number, ok := p.markers[markerToken(marker)]
+	if !ok {
+		return nil, nil
+	}
+	return p.GetIssue(number)
+}
+
+func (p *giteaProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	labelIDs, err := p.resolveLabels(input.Labels)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{
+		"title":     input.Title,
+		"body":      input.Body,
+		"labels":    labelIDs,
+		"assignees": input.Assignees,
+	}
+	if err := p.setSchedule(payload, input); err != nil {
+		return nil, err
+	}
+	var issue giteaIssue
+	if err := p.do("POST", p.repoPath("/issues"), payload, &issue); err != nil {
+		return nil, err
+	}
+	created := giteaTrackerIssue(&issue)
+	p.rememberMarkers(created)
+	return created, nil
+}
+
+func (p *giteaProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	payload := map[string]interface{}{"body": input.Body}
+	if input.Title != "" {
+		payload["title"] = input.Title
+	}
+	if input.State != "" {
+		payload["state"] = input.State
+	}
+	if input.Assignees != nil {
+		payload["assignees"] = input.Assignees
+	}
+	if err := p.setSchedule(payload, input); err != nil {
+		return nil, err
+	}
+	if input.Labels != nil {
+		labelIDs, err := p.resolveLabels(input.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.do("PUT", p.repoPath(fmt.Sprintf("/issues/%d/labels", number)), map[string]interface{}{"labels": labelIDs}, nil); err != nil {
+			return nil, err
+		}
+	}
+	var issue giteaIssue
+	if err := p.do("PATCH", p.repoPath(fmt.Sprintf("/issues/%d", number)), payload, &issue); err != nil {
+		return nil, err
+	}
+	return giteaTrackerIssue(&issue), nil
+}
+
+func (p *giteaProvider) SetState(number int, state string) error {
+	return p.do("PATCH", p.repoPath(fmt.Sprintf("/issues/%d", number)), map[string]interface{}{"state": state}, nil)
+}
+
+func (p *giteaProvider) ListLabels() ([]TrackerLabel, error) {
+	var all []TrackerLabel
+	for page := 1; ; page++ {
+		var labels []giteaLabel
+		path := fmt.Sprintf("%s?page=%d&limit=%d", p.repoPath("/labels"), page, giteaPageSize)
+		if err := p.do("GET", path, nil, &labels); err != nil {
+			return nil, err
+		}
+		for _, label := range labels {
+			all = append(all, TrackerLabel{
+				Name:        label.Name,
+				Color:       strings.TrimPrefix(label.Color, "#"),
+				Description: label.Description,
+			})
+		}
+		if len(labels) < giteaPageSize {
+			return all, nil
+		}
+	}
+}
+
+func (p *giteaProvider) CreateLabel(label TrackerLabel) error {
+	payload := map[string]string{
+		"name":        label.Name,
+		"color":       "#" + label.Color,
+		"description": label.Description,
+	}
+	var created giteaLabel
+	if err := p.do("POST", p.repoPath("/labels"), payload, &created); err != nil {
+		return err
+	}
+	if p.labelIDs != nil {
+		p.labelIDs[created.Name] = created.ID
+	}
+	return nil
+}
+
+func (p *giteaProvider) UpdateLabel(label TrackerLabel) error {
+	ids, err := p.resolveLabels([]string{label.Name})
+	if err != nil {
+		return err
+	}
+	payload := map[string]string{
+		"color":       "#" + label.Color,
+		"description": label.Description,
+	}
+	return p.do("PATCH", p.repoPath(fmt.Sprintf("/labels/%d", ids[0])), payload, nil)
+}
+
+func (p *giteaProvider) ListMilestones() ([]TrackerMilestone, error) {
+	p.milestoneIDs = make(map[string]int64)
+	var all []TrackerMilestone
+	for page := 1; ; page++ {
+		var milestones []giteaMilestone
+		path := fmt.Sprintf("%s?state=all&page=%d&limit=%d", p.repoPath("/milestones"), page, giteaPageSize)
+		if err := p.do("GET", path, nil, &milestones); err != nil {
+			return nil, err
+		}
+		for _, milestone := range milestones {
+			p.milestoneIDs[milestone.Title] = milestone.ID
+			all = append(all, TrackerMilestone{
+				Title:       milestone.Title,
+				Description: milestone.Description,
+				DueDate:     milestone.DueOn,
+				State:       milestone.State,
+			})
+		}
+		if len(milestones) < giteaPageSize {
+			return all, nil
+		}
+	}
+}
+
+func (p *giteaProvider) CreateMilestone(milestone TrackerMilestone) error {
+	payload := map[string]interface{}{
+		"title":       milestone.Title,
+		"description": milestone.Description,
+	}
+	if milestone.State != "" {
+		payload["state"] = milestone.State
+	}
+	if milestone.DueDate != nil {
+		payload["due_on"] = milestone.DueDate.UTC().Format(time.RFC3339)
+	}
+	var created giteaMilestone
+	if err := p.do("POST", p.repoPath("/milestones"), payload, &created); err != nil {
+		return err
+	}
+	if p.milestoneIDs == nil {
+		p.milestoneIDs = make(map[string]int64)
+	}
+	p.milestoneIDs[created.Title] = created.ID
+	return nil
+}
+
+// setSchedule adds the milestone ID and the due date of an issue input to
+// an issue payload; an unknown milestone title is left out
+func (p *giteaProvider) setSchedule(payload map[string]interface{}, input IssueInput) error {
+	if input.DueDate != nil {
+		payload["due_date"] = input.DueDate.UTC().Format(time.RFC3339)
+	}
+	if input.Milestone == "" {
+		return nil
+	}
+	if p.milestoneIDs == nil {
+		if _, err := p.ListMilestones(); err != nil {
+			return err
+		}
+	}
+	if id, ok := p.milestoneIDs[input.Milestone]; ok {
+		payload["milestone"] = id
+	} else {
+		fmt.Printf("[WARNING] Gitea milestone %q does not exist\n", input.Milestone)
+	}
+	return nil
+}
+
+func (p *giteaProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	path := p.repoPath(fmt.Sprintf("/issues/%d/comments", number))
+	if !since.IsZero() {
+		path += "?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+	var comments []giteaComment
+	if err := p.do("GET", path, nil, &comments); err != nil {
+		return nil, err
+	}
+	all := make([]*TrackerComment, len(comments))
+	for i := range comments {
+		all[i] = giteaTrackerComment(&comments[i])
+	}
+	return all, nil
+}
+
+func (p *giteaProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	var comment giteaComment
+	if err := p.do("POST", p.repoPath(fmt.Sprintf("/issues/%d/comments", number)), map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return giteaTrackerComment(&comment), nil
+}
+
+func (p *giteaProvider) UpdateComment(number int, commentID int64, body string) error {
+	return p.do("PATCH", p.repoPath(fmt.Sprintf("/issues/comments/%d", commentID)), map[string]string{"body": body}, nil)
+}
+
+// WebURL returns the repository address on the instance
+func (p *giteaProvider) WebURL() (string, error) {
+	return p.endpoint.BaseURL + "/" + p.endpoint.Owner + "/" + p.endpoint.Repo, nil
+}
+
+// giteaAttachmentRegex matches the URLs of Gitea attachments, which are served
+// from /attachments/<uuid> or /<owner>/<repo>/attachments/<uuid>
+var giteaAttachmentRegex = regexp.MustCompile(`^(?:https?://\S+)?/(?:[^\s/]+/[^\s/]+/)?attachments/[0-9a-f-]{36}$`)
+
+// FindAttachments makes attachment URLs absolute and returns the files attached in this instance
+func (p *giteaProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	var attachments []AttachmentInfo
+	seen := make(map[string]bool)
+
+	body = markdown.ReplaceLinks(body, func(link markdown.LinkRef) string {
+		if !giteaAttachmentRegex.MatchString(link.URL) {
+			return link.URL
+		}
+		attachmentURL := link.URL
+		if strings.HasPrefix(attachmentURL, "/") {
+			attachmentURL = p.endpoint.BaseURL + attachmentURL
+		} else if !strings.HasPrefix(attachmentURL, p.endpoint.BaseURL+"/") {
+			// Attachment of another instance
+			return link.URL
+		}
+
+		if !seen[attachmentURL] {
+			seen[attachmentURL] = true
+			filename := sanitizeFilename(link.Text)
+			if link.Text == "" || link.Kind == markdown.PlainURL {
+				filename = "attachment"
+			}
+			attachments = append(attachments, AttachmentInfo{
+				URL:          attachmentURL,
+				Filename:     filename,
+				IsImage:      link.Image || isImageURL(link.Text),
+				OriginalText: link.Source,
+			})
+			fmt.Printf("[ATTACH] Found Gitea attachment: %s\n", attachmentURL)
+		}
+		return attachmentURL
+	})
+
+	return body, attachments
+}
+
+func (p *giteaProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	req, err := http.NewRequest("GET", attachment.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.endpoint.Token != "" {
+		req.Header.Set("Authorization", "token "+p.endpoint.Token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// UploadAttachment attaches the file to the issue through the issue-asset API
+func (p *giteaProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("attachment", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	path := p.repoPath(fmt.Sprintf("/issues/%d/assets", number)) + "?name=" + url.QueryEscape(filename)
+	req, err := http.NewRequest("POST", p.endpoint.BaseURL+"/api/v1"+path, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "token "+p.endpoint.Token)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	fmt.Printf("[ATTACH] Uploading as '%s' to Gitea issue #%d\n", filename, number)
+	var asset struct {
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}
+	if err := p.send(req, &asset); err != nil {
+		return "", err
+	}
+	return asset.BrowserDownloadURL, nil
+}
+
+// resolveLabels returns the IDs of the named labels, creating missing ones
+func (p *giteaProvider) resolveLabels(names []string) ([]int64, error) {
+	if p.labelIDs == nil {
+		p.labelIDs = make(map[string]int64)
+		for page := 1; ; page++ {
+			var labels []giteaLabel
+			path := fmt.Sprintf("%s?page=%d&limit=%d", p.repoPath("/labels"), page, giteaPageSize)
+			if err := p.do("GET", path, nil, &labels); err != nil {
+				return nil, err
+			}
+			for _, label := range labels {
+				p.labelIDs[label.Name] = label.ID
+			}
+			if len(labels) < giteaPageSize {
+				break
+			}
+		}
+	}
+
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		id, ok := p.labelIDs[name]
+		if !ok {
+			var label giteaLabel
+			payload := map[string]string{"name": name, "color": "#ededed"}
+			if err := p.do("POST", p.repoPath("/labels"), payload, &label); err != nil {
+				return nil, fmt.Errorf("failed to create label %q: %w", name, err)
+			}
+			fmt.Printf("[GITEA] Created label %q\n", name)
+			id = label.ID
+			p.labelIDs[name] = id
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// UserEmail returns the email address of a Gitea user, which Gitea only
+// shows to administrators and for users with a public address
+func (p *giteaProvider) UserEmail(username string) (string, error) {
+	var user giteaUser
+	if err := p.do("GET", "/users/"+url.PathEscape(username), nil, &user); err != nil {
+		return "", err
+	}
+	return user.Email, nil
+}
+
+// FindUserByEmail searches Gitea users by email address
+func (p *giteaProvider) FindUserByEmail(email string) (string, error) {
+	var result struct {
+		Data []giteaUser `json:"data"`
+	}
+	if err := p.do("GET", "/users/search?q="+url.QueryEscape(email), nil, &result); err != nil {
+		return "", err
+	}
+	for _, user := range result.Data {
+		if strings.EqualFold(user.Email, email) {
+			return user.Login, nil
+		}
+	}
+	return "", nil
+}
+
+func (p *giteaProvider) rememberMarkers(issue *TrackerIssue) {
+	if p.markers == nil {
+		return
+	}
+	for token := range collectMarkers(issue.Body) {
+		p.markers[token] = issue.Number
+	}
+}
+
+func (p *giteaProvider) repoPath(path string) string {
+	return fmt.Sprintf("/repos/%s/%s%s", url.PathEscape(p.endpoint.Owner), url.PathEscape(p.endpoint.Repo), path)
+}
+
+// do sends a JSON request to the API and decodes the response into out
+func (p *giteaProvider) do(method string, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, p.endpoint.BaseURL+"/api/v1"+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.endpoint.Token != "" {
+		req.Header.Set("Authorization", "token "+p.endpoint.Token)
+	}
+	return p.send(req, out)
+}
+
+func (p *giteaProvider) send(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gitea API %s %s returned status %d: %s", req.Method, req.URL.Path, resp.StatusCode, string(respBody))
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func giteaTrackerIssue(issue *giteaIssue) *TrackerIssue {
+	labels := make([]string, len(issue.Labels))
+	for i, label := range issue.Labels {
+		labels[i] = label.Name
+	}
+	tracked := &TrackerIssue{
+		Number:    issue.Number,
+		Title:     issue.Title,
+		Body:      issue.Body,
+		State:     issue.State,
+		Labels:    labels,
+		Author:    issue.User.Login,
+		URL:       issue.HTMLURL,
+		CreatedAt: issue.CreatedAt,
+		UpdatedAt: issue.UpdatedAt,
+		ClosedAt:  issue.ClosedAt,
+		DueDate:   issue.DueDate,
+	}
+	for _, assignee := range issue.Assignees {
+		tracked.Assignees = append(tracked.Assignees, assignee.Login)
+	}
+	if issue.Milestone != nil {
+		tracked.Milestone = issue.Milestone.Title
+	}
+	return tracked
+}
+
+func giteaTrackerComment(comment *giteaComment) *TrackerComment {
+	return &TrackerComment{
+		ID:        comment.ID,
+		Body:      comment.Body,
+		Author:    comment.User.Login,
+		URL:       comment.HTMLURL,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
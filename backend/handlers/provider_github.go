@@ -0,0 +1,515 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/issue-migrator/backend/models"
+)
+
+// githubProvider reads and writes issues of a GitHub repository
+type githubProvider struct {
+	ctx      context.Context
+	client   *github.Client
+	endpoint models.Endpoint
+	repoID   string // resolved lazily for attachment uploads
+	// milestones maps milestone titles to numbers, loaded on first use
+	milestones map[string]int
+	// uploadNoticeShown limits the explanation of failed uploads to once per run
+	uploadNoticeShown bool
+	// markers maps marker tokens to issue numbers; the search API is rate
+	// limited and indexes new issues late, so the repository is scanned once
+	// on first use
+	markers map[string]int
+}
+
+func newGitHubProvider(endpoint models.Endpoint) *githubProvider {
+	return &githubProvider{
+		ctx:      context.Background(),
+		client:   github.NewClient(nil).WithAuthToken(endpoint.Token),
+		endpoint: endpoint,
+	}
+}
+
+func (p *githubProvider) Platform() string { return "github" }
+
+func (p *githubProvider) Name() string { return "GitHub" }
+
+func (p *githubProvider) Project() string {
+	return githubProject(p.endpoint.Owner, p.endpoint.Repo)
+}
+
+func (p *githubProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "all",
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var all []*TrackerIssue
+	for {
+		issues, resp, err := p.client.Issues.ListByRepo(p.ctx, p.endpoint.Owner, p.endpoint.Repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if issue.PullRequestLinks != nil {
+				continue
+			}
+			all = append(all, githubTrackerIssue(issue))
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *githubProvider) GetIssue(number int) (*TrackerIssue, error) {
+	issue, _, err := p.client.Issues.Get(p.ctx, p.endpoint.Owner, p.endpoint.Repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return githubTrackerIssue(issue), nil
+}
+
+func (p *githubProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	if p.markers == nil {
+		issues, err := p.ListIssues(time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		p.markers = make(map[string]int)
+		for _, issue := range issues {
+			p.rememberMarkers(issue)
+		}
+	}
+	number, ok := p.markers[markerToken(marker)]
+	if !ok {
+		return nil, nil
+	}
+	return p.GetIssue(number)
+}
+
+func (p *githubProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	labels := append([]string{}, input.Labels...)
+	assignees := append([]string{}, input.Assignees...)
+	createReq := &github.IssueRequest{
+		Title:     &input.Title,
+		Body:      &input.Body,
+		Labels:    &labels,
+		Assignees: &assignees,
+	}
+	if err := p.setMilestone(createReq, input.Milestone); err != nil {
+		return nil, err
+	}
+	issue, _, err := p.client.Issues.Create(p.ctx, p.endpoint.Owner, p.endpoint.Repo, createReq)
+	if err != nil {
+		return nil, err
+	}
+	created := githubTrackerIssue(issue)
+	p.rememberMarkers(created)
+	return created, nil
+}
+
+func (p *githubProvider) rememberMarkers(issue *TrackerIssue) {
+	if p.markers == nil {
+		return
+	}
+	for token := range collectMarkers(issue.Body) {
+		p.markers[token] = issue.Number
+	}
+}
+
+func (p *githubProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	editReq := &github.IssueRequest{Body: &input.Body}
+	if input.Title != "" {
+		editReq.Title = &input.Title
+	}
+	if input.Labels != nil {
+		labels := append([]string{}, input.Labels...)
+		editReq.Labels = &labels
+	}
+	if input.State != "" {
+		editReq.State = &input.State
+	}
+	if input.Assignees != nil {
+		editReq.Assignees = &input.Assignees
+	}
+	if err := p.setMilestone(editReq, input.Milestone); err != nil {
+		return nil, err
+	}
+	issue, _, err := p.client.Issues.Edit(p.ctx, p.endpoint.Owner, p.endpoint.Repo, number, editReq)
+	if err != nil {
+		return nil, err
+	}
+	return githubTrackerIssue(issue), nil
+}
+
+func (p *githubProvider) SetState(number int, state string) error {
+	_, _, err := p.client.Issues.Edit(p.ctx, p.endpoint.Owner, p.endpoint.Repo, number, &github.IssueRequest{State: &state})
+	return err
+}
+
+func (p *githubProvider) ListLabels() ([]TrackerLabel, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var all []TrackerLabel
+	for {
+		labels, resp, err := p.client.Issues.ListLabels(p.ctx, p.endpoint.Owner, p.endpoint.Repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range labels {
+			all = append(all, TrackerLabel{
+				Name:        label.GetName(),
+				Color:       label.GetColor(),
+				Description: label.GetDescription(),
+			})
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *githubProvider) CreateLabel(label TrackerLabel) error {
+	_, _, err := p.client.Issues.CreateLabel(p.ctx, p.endpoint.Owner, p.endpoint.Repo, &github.Label{
+		Name:        &label.Name,
+		Color:       &label.Color,
+		Description: &label.Description,
+	})
+	return err
+}
+
+func (p *githubProvider) UpdateLabel(label TrackerLabel) error {
+	_, _, err := p.client.Issues.EditLabel(p.ctx, p.endpoint.Owner, p.endpoint.Repo, label.Name, &github.Label{
+		Color:       &label.Color,
+		Description: &label.Description,
+	})
+	return err
+}
+
+func (p *githubProvider) ListMilestones() ([]TrackerMilestone, error) {
+	opts := &github.MilestoneListOptions{
+		State:       "all",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	p.milestones = make(map[string]int)
+	var all []TrackerMilestone
+	for {
+		milestones, resp, err := p.client.Issues.ListMilestones(p.ctx, p.endpoint.Owner, p.endpoint.Repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, milestone := range milestones {
+			p.milestones[milestone.GetTitle()] = milestone.GetNumber()
+			tracked := TrackerMilestone{
+				Title:       milestone.GetTitle(),
+				Description: milestone.GetDescription(),
+				State:       milestone.GetState(),
+			}
+			if milestone.DueOn != nil {
+				dueOn := milestone.GetDueOn().Time
+				tracked.DueDate = &dueOn
+			}
+			all = append(all, tracked)
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *githubProvider) CreateMilestone(milestone TrackerMilestone) error {
+	request := &github.Milestone{
+		Title:       &milestone.Title,
+		Description: &milestone.Description,
+	}
+	if milestone.State != "" {
+		request.State = &milestone.State
+	}
+	if milestone.DueDate != nil {
+		request.DueOn = &github.Timestamp{Time: *milestone.DueDate}
+	}
+	created, _, err := p.client.Issues.CreateMilestone(p.ctx, p.endpoint.Owner, p.endpoint.Repo, request)
+	if err != nil {
+		return err
+	}
+	if p.milestones == nil {
+		p.milestones = make(map[string]int)
+	}
+	p.milestones[created.GetTitle()] = created.GetNumber()
+	return nil
+}
+
+// setMilestone adds the number of the milestone with the given title to an
+// issue request; an unknown title leaves the milestone unset
+func (p *githubProvider) setMilestone(request *github.IssueRequest, title string) error {
+	if title == "" {
+		return nil
+	}
+	if p.milestones == nil {
+		if _, err := p.ListMilestones(); err != nil {
+			return err
+		}
+	}
+	if number, ok := p.milestones[title]; ok {
+		request.Milestone = &number
+	} else {
+		fmt.Printf("[WARNING] GitHub milestone %q does not exist\n", title)
+	}
+	return nil
+}
+
+func (p *githubProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	if !since.IsZero() {
+		opts.Since = &since
+	}
+	var all []*TrackerComment
+	for {
+		comments, resp, err := p.client.Issues.ListComments(p.ctx, p.endpoint.Owner, p.endpoint.Repo, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			all = append(all, githubTrackerComment(comment))
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListEvents reads the history of an issue from its timeline, which unlike
+// the events API includes references from other issues
+func (p *githubProvider) ListEvents(number int) ([]*TrackerEvent, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var all []*TrackerEvent
+	for {
+		timeline, resp, err := p.client.Issues.ListIssueTimeline(p.ctx, p.endpoint.Owner, p.endpoint.Repo, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range timeline {
+			if event := githubTrackerEvent(item); event != nil {
+				all = append(all, event)
+			}
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *githubProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	comment, _, err := p.client.Issues.CreateComment(p.ctx, p.endpoint.Owner, p.endpoint.Repo, number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return nil, err
+	}
+	return githubTrackerComment(comment), nil
+}
+
+func (p *githubProvider) UpdateComment(number int, commentID int64, body string) error {
+	_, _, err := p.client.Issues.EditComment(p.ctx, p.endpoint.Owner, p.endpoint.Repo, commentID, &github.IssueComment{Body: &body})
+	return err
+}
+
+// WebURL returns the repository address on github.com
+func (p *githubProvider) WebURL() (string, error) {
+	return "https://github.com/" + githubProject(p.endpoint.Owner, p.endpoint.Repo), nil
+}
+
+// FindAttachments returns images and file links in Markdown or HTML form
+func (p *githubProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	attachments := findAllAttachments(body)
+	for i := range attachments {
+		attachments[i].Filename = getFilename(attachments[i].URL, attachments[i].OriginalText)
+	}
+	return body, attachments
+}
+
+func (p *githubProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	req, err := http.NewRequest("GET", attachment.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add GitHub authentication if needed
+	if strings.Contains(attachment.URL, "github.com") && p.endpoint.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.endpoint.Token)
+		fmt.Printf("[ATTACH] Using GitHub authentication for download\n")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// UploadAttachment uses GitHub's unofficial upload API, which needs a browser session cookie
+func (p *githubProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	// Skip if no session provided since it won't work anyway
+	if p.endpoint.Session == "" {
+		fmt.Printf("[INFO] Skipping GitHub upload (no session cookie provided)\n")
+		return "", fmt.Errorf("no GitHub session cookie provided")
+	}
+
+	if p.repoID == "" && p.endpoint.Token != "" {
+		p.repoID = getGitHubRepoID(p.endpoint.Owner, p.endpoint.Repo, p.endpoint.Token)
+		if p.repoID != "" {
+			fmt.Printf("[INFO] Using repository ID: %s for uploads\n", p.repoID)
+		}
+	}
+
+	// Build the referer URL using the actual issue URL
+	refererURL := fmt.Sprintf("https://github.com/%s/%s/issues", p.endpoint.Owner, p.endpoint.Repo)
+	if number > 0 {
+		refererURL = fmt.Sprintf("%s/%d", refererURL, number)
+	}
+
+	githubURL, err := UploadToGitHubWithRepoAndReferer(data, filename, p.endpoint.Token, p.endpoint.Session, p.repoID, refererURL)
+	if err != nil {
+		if !p.uploadNoticeShown {
+			p.uploadNoticeShown = true
+			fmt.Printf("[INFO] ========================================\n")
+			fmt.Printf("[INFO] GitHub Upload Limitation:\n")
+			fmt.Printf("[INFO] GitHub's file upload API requires a complete browser session\n")
+			fmt.Printf("[INFO] including CSRF tokens and other security measures that cannot\n")
+			fmt.Printf("[INFO] be easily obtained programmatically.\n")
+			fmt.Printf("[INFO] \n")
+			fmt.Printf("[INFO] Current behavior:\n")
+			fmt.Printf("[INFO] - Files remain hosted on the source tracker\n")
+			fmt.Printf("[INFO] - Links are preserved in migrated issues\n")
+			fmt.Printf("[INFO] - Images will display if the source repo is public\n")
+			fmt.Printf("[INFO] \n")
+			fmt.Printf("[INFO] Alternatives:\n")
+			fmt.Printf("[INFO] 1. Make the source repo public during migration\n")
+			fmt.Printf("[INFO] 2. Manually re-upload important files after migration\n")
+			fmt.Printf("[INFO] 3. Use GitHub Actions for automated uploads\n")
+			fmt.Printf("[INFO] ========================================\n")
+		}
+		return "", err
+	}
+	return githubURL, nil
+}
+
+// UserEmail returns the public email address of a GitHub user
+func (p *githubProvider) UserEmail(username string) (string, error) {
+	user, _, err := p.client.Users.Get(p.ctx, username)
+	if err != nil {
+		return "", err
+	}
+	return user.GetEmail(), nil
+}
+
+// FindUserByEmail searches the public email addresses of GitHub users
+func (p *githubProvider) FindUserByEmail(email string) (string, error) {
+	result, _, err := p.client.Search.Users(p.ctx, email+" in:email", nil)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Users) != 1 {
+		return "", nil
+	}
+	return result.Users[0].GetLogin(), nil
+}
+
+func githubTrackerIssue(issue *github.Issue) *TrackerIssue {
+	labels := make([]string, len(issue.Labels))
+	for i, label := range issue.Labels {
+		labels[i] = label.GetName()
+	}
+	assignees := make([]string, len(issue.Assignees))
+	for i, assignee := range issue.Assignees {
+		assignees[i] = assignee.GetLogin()
+	}
+	tracked := &TrackerIssue{
+		Number:    issue.GetNumber(),
+		Title:     issue.GetTitle(),
+		Body:      issue.GetBody(),
+		State:     issue.GetState(),
+		Labels:    labels,
+		Milestone: issue.GetMilestone().GetTitle(),
+		Author:    issue.GetUser().GetLogin(),
+		Assignees: assignees,
+		URL:       issue.GetHTMLURL(),
+		CreatedAt: issue.GetCreatedAt().Time,
+		UpdatedAt: issue.GetUpdatedAt().Time,
+	}
+	if issue.ClosedAt != nil {
+		closedAt := issue.GetClosedAt().Time
+		tracked.ClosedAt = &closedAt
+	}
+	return tracked
+}
+
+// githubTrackerEvent converts a timeline item, or returns nil for items that
+// are not part of the history, such as comments
+func githubTrackerEvent(item *github.Timeline) *TrackerEvent {
+	event := &TrackerEvent{
+		Actor:     item.GetActor().GetLogin(),
+		CreatedAt: item.GetCreatedAt().Time,
+	}
+	switch item.GetEvent() {
+	case "labeled":
+		event.Action, event.Subject = HistoryLabeled, item.GetLabel().GetName()
+	case "unlabeled":
+		event.Action, event.Subject = HistoryUnlabeled, item.GetLabel().GetName()
+	case "closed":
+		event.Action = HistoryClosed
+	case "reopened":
+		event.Action = HistoryReopened
+	case "assigned":
+		event.Action, event.Subject = HistoryAssigned, item.GetAssignee().GetLogin()
+	case "unassigned":
+		event.Action, event.Subject = HistoryUnassigned, item.GetAssignee().GetLogin()
+	case "milestoned":
+		event.Action, event.Subject = HistoryMilestoned, item.GetMilestone().GetTitle()
+	case "demilestoned":
+		event.Action, event.Subject = HistoryDemilestoned, item.GetMilestone().GetTitle()
+	case "renamed":
+		event.Action = HistoryRenamed
+		event.Subject, event.Previous = item.GetRename().GetTo(), item.GetRename().GetFrom()
+	case "cross-referenced":
+		event.Action, event.Subject = HistoryReferenced, item.GetSource().GetIssue().GetHTMLURL()
+		if event.Actor == "" {
+			event.Actor = item.GetSource().GetActor().GetLogin()
+		}
+	case "referenced":
+		if item.GetCommitID() == "" {
+			return nil
+		}
+		event.Action, event.Subject = HistoryReferenced, "commit "+item.GetCommitID()
+	default:
+		return nil
+	}
+	return event
+}
+
+func githubTrackerComment(comment *github.IssueComment) *TrackerComment {
+	return &TrackerComment{
+		ID:        comment.GetID(),
+		Body:      comment.GetBody(),
+		Author:    comment.GetUser().GetLogin(),
+		URL:       comment.GetHTMLURL(),
+		CreatedAt: comment.GetCreatedAt().Time,
+		UpdatedAt: comment.GetUpdatedAt().Time,
+	}
+}
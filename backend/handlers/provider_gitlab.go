@@ -0,0 +1,800 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+	"github.com/xanzy/go-gitlab"
+)
+
+// gitlabProvider reads and writes issues of a GitLab project
+type gitlabProvider struct {
+	client   *gitlab.Client
+	endpoint models.Endpoint
+	// issueURLs remembers issue URLs so new notes can link to their anchor
+	issueURLs map[int]string
+	userIDs   map[string]int // username -> user ID, for assignees
+	// milestones maps milestone titles to IDs, loaded on first use
+	milestones map[string]int
+	webURL     string // loaded on first use
+	// epics maps the titles of the epics of the project's groups to them,
+	// loaded on first use
+	epics map[string]*gitlab.Epic
+}
+
+func newGitLabProvider(endpoint models.Endpoint) (*gitlabProvider, error) {
+	client, err := gitlab.NewClient(endpoint.Token, gitlab.WithBaseURL(endpoint.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+	return &gitlabProvider{
+		client:    client,
+		endpoint:  endpoint,
+		issueURLs: make(map[int]string),
+		userIDs:   make(map[string]int),
+	}, nil
+}
+
+func (p *gitlabProvider) Platform() string { return "gitlab" }
+
+func (p *gitlabProvider) Name() string { return "GitLab" }
+
+func (p *gitlabProvider) Project() string {
+	return gitlabProject(p.endpoint.BaseURL, p.endpoint.ProjectID)
+}
+
+func (p *gitlabProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	opts := &gitlab.ListProjectIssuesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1},
+	}
+	if !since.IsZero() {
+		opts.UpdatedAfter = &since
+	}
+	var all []*TrackerIssue
+	for {
+		issues, resp, err := p.client.Issues.ListProjectIssues(p.endpoint.ProjectID, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			all = append(all, p.trackerIssue(issue))
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *gitlabProvider) GetIssue(number int) (*TrackerIssue, error) {
+	issue, _, err := p.client.Issues.GetIssue(p.endpoint.ProjectID, number)
+	if err != nil {
+		return nil, err
+	}
+	return p.trackerIssue(issue), nil
+}
+
+func (p *gitlabProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	issue, err := findGitLabIssueByMarker(p.client, p.endpoint.ProjectID, marker)
+	if err != nil || issue == nil {
+		return nil, err
+	}
+	return p.trackerIssue(issue), nil
+}
+
+func (p *gitlabProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	return p.createIssue(input, nil)
+}
+
+// createIssue creates an issue; options such as gitlab.WithSudo apply to the request
+func (p *gitlabProvider) createIssue(input IssueInput, createdAt *time.Time, options ...gitlab.RequestOptionFunc) (*TrackerIssue, error) {
+	labels := gitlab.Labels(input.Labels)
+	assigneeIDs, err := p.resolveUsers(input.Assignees)
+	if err != nil {
+		return nil, err
+	}
+	milestoneID, err := p.milestoneID(input.Milestone)
+	if err != nil {
+		return nil, err
+	}
+	opts := &gitlab.CreateIssueOptions{
+		Title:       &input.Title,
+		Description: &input.Body,
+		Labels:      &labels,
+		AssigneeIDs: &assigneeIDs,
+		MilestoneID: milestoneID,
+		Weight:      input.Weight,
+		CreatedAt:   createdAt,
+	}
+	if input.DueDate != nil {
+		opts.DueDate = gitlab.Ptr(gitlab.ISOTime(*input.DueDate))
+	}
+	if input.Confidential {
+		opts.Confidential = gitlab.Ptr(true)
+	}
+	issue, _, err := p.client.Issues.CreateIssue(p.endpoint.ProjectID, opts, options...)
+	if err != nil {
+		return nil, err
+	}
+	p.setHealthStatus(issue, input.HealthStatus)
+	p.setEpic(issue, input.Epic)
+	return p.trackerIssue(issue), nil
+}
+
+func (p *gitlabProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	opts := &gitlab.UpdateIssueOptions{Description: &input.Body}
+	if input.Title != "" {
+		opts.Title = &input.Title
+	}
+	if input.Labels != nil {
+		labels := gitlab.Labels(input.Labels)
+		opts.Labels = &labels
+	}
+	if input.State != "" {
+		opts.StateEvent = gitlab.Ptr(gitlabStateEvent(input.State))
+	}
+	if input.Assignees != nil {
+		assigneeIDs, err := p.resolveUsers(input.Assignees)
+		if err != nil {
+			return nil, err
+		}
+		opts.AssigneeIDs = &assigneeIDs
+	}
+	milestoneID, err := p.milestoneID(input.Milestone)
+	if err != nil {
+		return nil, err
+	}
+	opts.MilestoneID = milestoneID
+	opts.Weight = input.Weight
+	if input.DueDate != nil {
+		opts.DueDate = gitlab.Ptr(gitlab.ISOTime(*input.DueDate))
+	}
+	if input.Confidential {
+		opts.Confidential = gitlab.Ptr(true)
+	}
+	issue, _, err := p.client.Issues.UpdateIssue(p.endpoint.ProjectID, number, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.setHealthStatus(issue, input.HealthStatus)
+	p.setEpic(issue, input.Epic)
+	return p.trackerIssue(issue), nil
+}
+
+// setHealthStatus sets the health status of an issue, which the client
+// library has no option for. Health statuses need GitLab Premium, so a
+// failure only warns.
+func (p *gitlabProvider) setHealthStatus(issue *gitlab.Issue, status string) {
+	if status == "" || status == issue.HealthStatus {
+		return
+	}
+	path := fmt.Sprintf("projects/%d/issues/%d", p.endpoint.ProjectID, issue.IID)
+	req, err := p.client.NewRequest(http.MethodPut, path, map[string]string{"health_status": status}, nil)
+	if err == nil {
+		_, err = p.client.Do(req, nil)
+	}
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to set the health status of GitLab issue #%d: %v\n", issue.IID, err)
+		return
+	}
+	issue.HealthStatus = status
+}
+
+// setEpic adds an issue to the epic with the given title. Epics need GitLab
+// Premium and a project in a group, so a failure only warns.
+func (p *gitlabProvider) setEpic(issue *gitlab.Issue, title string) {
+	if title == "" || (issue.Epic != nil && issue.Epic.Title == title) {
+		return
+	}
+	epic, err := p.epic(title)
+	if err == nil && epic == nil {
+		err = fmt.Errorf("no epic is titled %q", title)
+	}
+	if err == nil {
+		_, _, err = p.client.EpicIssues.AssignEpicIssue(epic.GroupID, epic.IID, issue.ID)
+	}
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to set the epic of GitLab issue #%d: %v\n", issue.IID, err)
+		return
+	}
+	issue.Epic = &gitlab.Epic{ID: epic.ID, IID: epic.IID, GroupID: epic.GroupID, Title: epic.Title}
+}
+
+// epic returns the epic with the given title from the group of the project
+// and its parent groups, or nil if there is none. Epics that cannot be
+// listed are only reported once.
+func (p *gitlabProvider) epic(title string) (*gitlab.Epic, error) {
+	if p.epics == nil {
+		p.epics = make(map[string]*gitlab.Epic)
+		project, _, err := p.client.Projects.GetProject(p.endpoint.ProjectID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if project.Namespace == nil || project.Namespace.Kind != "group" {
+			return nil, fmt.Errorf("the project is not in a group")
+		}
+		opts := &gitlab.ListGroupEpicsOptions{
+			IncludeAncestorGroups: gitlab.Ptr(true),
+			ListOptions:           gitlab.ListOptions{PerPage: 100, Page: 1},
+		}
+		for {
+			page, resp, err := p.client.Epics.ListGroupEpics(project.Namespace.ID, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, epic := range page {
+				if _, ok := p.epics[epic.Title]; !ok {
+					p.epics[epic.Title] = epic
+				}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	return p.epics[title], nil
+}
+
+func (p *gitlabProvider) SetState(number int, state string) error {
+	_, _, err := p.client.Issues.UpdateIssue(p.endpoint.ProjectID, number, &gitlab.UpdateIssueOptions{
+		StateEvent: gitlab.Ptr(gitlabStateEvent(state)),
+	})
+	return err
+}
+
+func (p *gitlabProvider) ListLabels() ([]TrackerLabel, error) {
+	opts := &gitlab.ListLabelsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1},
+	}
+	var all []TrackerLabel
+	for {
+		labels, resp, err := p.client.Labels.ListLabels(p.endpoint.ProjectID, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range labels {
+			all = append(all, TrackerLabel{
+				Name:        label.Name,
+				Color:       strings.TrimPrefix(label.Color, "#"),
+				Description: label.Description,
+			})
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *gitlabProvider) CreateLabel(label TrackerLabel) error {
+	_, _, err := p.client.Labels.CreateLabel(p.endpoint.ProjectID, &gitlab.CreateLabelOptions{
+		Name:        &label.Name,
+		Color:       gitlab.Ptr("#" + label.Color),
+		Description: &label.Description,
+	})
+	return err
+}
+
+func (p *gitlabProvider) UpdateLabel(label TrackerLabel) error {
+	_, _, err := p.client.Labels.UpdateLabel(p.endpoint.ProjectID, &gitlab.UpdateLabelOptions{
+		Name:        &label.Name,
+		Color:       gitlab.Ptr("#" + label.Color),
+		Description: &label.Description,
+	})
+	return err
+}
+
+func (p *gitlabProvider) ListMilestones() ([]TrackerMilestone, error) {
+	opts := &gitlab.ListMilestonesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1},
+	}
+	p.milestones = make(map[string]int)
+	var all []TrackerMilestone
+	for {
+		milestones, resp, err := p.client.Milestones.ListMilestones(p.endpoint.ProjectID, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, milestone := range milestones {
+			p.milestones[milestone.Title] = milestone.ID
+			tracked := TrackerMilestone{
+				Title:       milestone.Title,
+				Description: milestone.Description,
+				State:       "open",
+			}
+			if milestone.State == "closed" {
+				tracked.State = "closed"
+			}
+			if milestone.DueDate != nil {
+				dueDate := time.Time(*milestone.DueDate)
+				tracked.DueDate = &dueDate
+			}
+			all = append(all, tracked)
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (p *gitlabProvider) CreateMilestone(milestone TrackerMilestone) error {
+	opts := &gitlab.CreateMilestoneOptions{
+		Title:       &milestone.Title,
+		Description: &milestone.Description,
+	}
+	if milestone.DueDate != nil {
+		opts.DueDate = gitlab.Ptr(gitlab.ISOTime(*milestone.DueDate))
+	}
+	created, _, err := p.client.Milestones.CreateMilestone(p.endpoint.ProjectID, opts)
+	if err != nil {
+		return err
+	}
+	if milestone.State == "closed" {
+		_, _, err = p.client.Milestones.UpdateMilestone(p.endpoint.ProjectID, created.ID, &gitlab.UpdateMilestoneOptions{
+			StateEvent: gitlab.Ptr("close"),
+		})
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to close GitLab milestone %q: %v\n", milestone.Title, err)
+		}
+	}
+	if p.milestones == nil {
+		p.milestones = make(map[string]int)
+	}
+	p.milestones[created.Title] = created.ID
+	return nil
+}
+
+// milestoneID returns the ID of the milestone with the given title, or nil
+// for an empty or unknown title
+func (p *gitlabProvider) milestoneID(title string) (*int, error) {
+	if title == "" {
+		return nil, nil
+	}
+	if p.milestones == nil {
+		if _, err := p.ListMilestones(); err != nil {
+			return nil, err
+		}
+	}
+	id, ok := p.milestones[title]
+	if !ok {
+		fmt.Printf("[WARNING] GitLab milestone %q does not exist\n", title)
+		return nil, nil
+	}
+	return &id, nil
+}
+
+// ListComments reads the notes of an issue through its discussions, so the
+// replies of a thread follow the note that started it and know which note
+// they follow
+func (p *gitlabProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	opts := &gitlab.ListIssueDiscussionsOptions{PerPage: 100, Page: 1}
+	var all []*TrackerComment
+	for {
+		discussions, resp, err := p.client.Discussions.ListIssueDiscussions(p.endpoint.ProjectID, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, discussion := range discussions {
+			var previous int64
+			for i, note := range discussion.Notes {
+				comment := p.trackerComment(number, note)
+				if i == 0 && !discussion.IndividualNote {
+					comment.Resolved, comment.ResolvedBy = gitlabThreadResolved(discussion)
+				}
+				comment.InReplyTo = previous
+				previous = comment.ID
+				if note.UpdatedAt != nil && note.UpdatedAt.Before(since) {
+					continue
+				}
+				all = append(all, comment)
+			}
+		}
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// gitlabThreadResolved reports whether every resolvable note of a thread is
+// resolved, and by whom
+func gitlabThreadResolved(discussion *gitlab.Discussion) (bool, string) {
+	resolved, resolvedBy := false, ""
+	for _, note := range discussion.Notes {
+		if !note.Resolvable {
+			continue
+		}
+		if !note.Resolved {
+			return false, ""
+		}
+		resolved, resolvedBy = true, note.ResolvedBy.Username
+	}
+	return resolved, resolvedBy
+}
+
+// ListEvents merges the label, state and milestone events of an issue.
+// Assignments and references are only recorded as system notes, which are
+// copied with the comments.
+func (p *gitlabProvider) ListEvents(number int) ([]*TrackerEvent, error) {
+	var all []*TrackerEvent
+
+	labelOpts := &gitlab.ListLabelEventsOptions{ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1}}
+	for {
+		events, resp, err := p.client.ResourceLabelEvents.ListIssueLabelEvents(p.endpoint.ProjectID, number, labelOpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			action := HistoryLabeled
+			if event.Action == "remove" {
+				action = HistoryUnlabeled
+			}
+			all = append(all, gitlabTrackerEvent(action, event.User.Username, event.Label.Name, event.CreatedAt))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		labelOpts.Page = resp.NextPage
+	}
+
+	stateOpts := &gitlab.ListStateEventsOptions{ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1}}
+	for {
+		events, resp, err := p.client.ResourceStateEvents.ListIssueStateEvents(p.endpoint.ProjectID, number, stateOpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			var action string
+			switch event.State {
+			case gitlab.ClosedEventType:
+				action = HistoryClosed
+			case gitlab.ReopenedEventType:
+				action = HistoryReopened
+			default:
+				continue
+			}
+			all = append(all, gitlabTrackerEvent(action, gitlabUsername(event.User), "", event.CreatedAt))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		stateOpts.Page = resp.NextPage
+	}
+
+	milestoneOpts := &gitlab.ListMilestoneEventsOptions{ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1}}
+	for {
+		events, resp, err := p.client.ResourceMilestoneEvents.ListIssueMilestoneEvents(p.endpoint.ProjectID, number, milestoneOpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			action, title := HistoryMilestoned, ""
+			if event.Action == "remove" {
+				action = HistoryDemilestoned
+			}
+			if event.Milestone != nil {
+				title = event.Milestone.Title
+			}
+			all = append(all, gitlabTrackerEvent(action, gitlabUsername(event.User), title, event.CreatedAt))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		milestoneOpts.Page = resp.NextPage
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].CreatedAt.Before(all[j].CreatedAt) })
+	return all, nil
+}
+
+func (p *gitlabProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	return p.addComment(number, body, nil)
+}
+
+func (p *gitlabProvider) UpdateComment(number int, commentID int64, body string) error {
+	_, _, err := p.client.Notes.UpdateIssueNote(p.endpoint.ProjectID, number, int(commentID), &gitlab.UpdateIssueNoteOptions{Body: &body})
+	return err
+}
+
+// WebURL returns the project address with its namespace path, which the
+// project ID of the endpoint does not tell
+func (p *gitlabProvider) WebURL() (string, error) {
+	if p.webURL == "" {
+		project, _, err := p.client.Projects.GetProject(p.endpoint.ProjectID, nil)
+		if err != nil {
+			return "", err
+		}
+		p.webURL = project.WebURL
+	}
+	return p.webURL, nil
+}
+
+// addComment creates a note; options such as gitlab.WithSudo apply to the request
+func (p *gitlabProvider) addComment(number int, body string, createdAt *time.Time, options ...gitlab.RequestOptionFunc) (*TrackerComment, error) {
+	note, _, err := p.client.Notes.CreateIssueNote(p.endpoint.ProjectID, number, &gitlab.CreateIssueNoteOptions{
+		Body:      &body,
+		CreatedAt: createdAt,
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+	return p.trackerComment(number, note), nil
+}
+
+// CanImpersonate reports whether the token belongs to an administrator, who
+// can act as any user through the Sudo header
+func (p *gitlabProvider) CanImpersonate() bool {
+	user, _, err := p.client.Users.CurrentUser()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to read the GitLab token owner: %v\n", err)
+		return false
+	}
+	return user.IsAdmin
+}
+
+// CreateIssueAs creates an issue as another user with its original creation
+// time. If GitLab refuses to act as the user, e.g. because they are blocked or
+// not a project member, the issue is created as the token owner instead.
+func (p *gitlabProvider) CreateIssueAs(author string, createdAt time.Time, input IssueInput) (*TrackerIssue, error) {
+	issue, err := p.createIssue(input, &createdAt, gitlab.WithSudo(author))
+	if gitlabSudoRefused(err) {
+		fmt.Printf("[WARNING] GitLab refused to create the issue as %s, creating it as the token owner: %v\n", author, err)
+		return p.createIssue(input, &createdAt)
+	}
+	return issue, err
+}
+
+// AddCommentAs creates a note as another user with its original creation
+// time, falling back to the token owner like CreateIssueAs
+func (p *gitlabProvider) AddCommentAs(number int, author string, createdAt time.Time, body string) (*TrackerComment, error) {
+	comment, err := p.addComment(number, body, &createdAt, gitlab.WithSudo(author))
+	if gitlabSudoRefused(err) {
+		fmt.Printf("[WARNING] GitLab refused to comment as %s, commenting as the token owner: %v\n", author, err)
+		return p.addComment(number, body, &createdAt)
+	}
+	return comment, err
+}
+
+// FindAttachments makes upload URLs absolute and returns the files uploaded to this project
+func (p *gitlabProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	body = fixGitLabAttachmentURLs(body, p.endpoint.BaseURL, p.endpoint.ProjectID)
+	return body, findGitLabAttachments(body, p.endpoint.BaseURL)
+}
+
+// DownloadAttachment fetches an upload; /uploads/ URLs of private projects need a session cookie
+func (p *gitlabProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	req, err := http.NewRequest("GET", attachment.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try using GitLab session cookie first (for /uploads/ endpoints)
+	if p.endpoint.Session != "" {
+		req.Header.Set("Cookie", fmt.Sprintf("_gitlab_session=%s", p.endpoint.Session))
+		fmt.Printf("[AUTH] Using GitLab session cookie for download\n")
+	} else if p.endpoint.Token != "" {
+		// Fallback to API token (might work for some endpoints)
+		req.Header.Set("PRIVATE-TOKEN", p.endpoint.Token)
+		fmt.Printf("[AUTH] Using GitLab API token for download\n")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized {
+			if p.endpoint.Session == "" {
+				fmt.Printf("[INFO] GitLab /uploads/ URLs require browser session authentication.\n")
+				fmt.Printf("[INFO] To download attachments from private repos, provide a GitLab session cookie.\n")
+				fmt.Printf("[INFO] How to get GitLab session cookie:\n")
+				fmt.Printf("[INFO]   1. Log in to GitLab in your browser\n")
+				fmt.Printf("[INFO]   2. Open Developer Tools (F12)\n")
+				fmt.Printf("[INFO]   3. Go to Application/Storage -> Cookies\n")
+				fmt.Printf("[INFO]   4. Find and copy the '_gitlab_session' cookie value\n")
+			} else {
+				fmt.Printf("[INFO] Session cookie provided but still cannot access. The session may be expired or invalid.\n")
+			}
+			fmt.Printf("[INFO] Alternative workarounds:\n")
+			fmt.Printf("[INFO]   1. Make the GitLab project public temporarily during migration\n")
+			fmt.Printf("[INFO]   2. Manually download and re-upload attachments after migration\n")
+		} else {
+			fmt.Printf("[DEBUG] Response: %s\n", string(bodyBytes))
+		}
+		return nil, fmt.Errorf("GitLab download returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// UploadAttachment uploads to the project and returns the project-relative /uploads/ URL
+func (p *gitlabProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	fmt.Printf("[ATTACH] Uploading as '%s' to GitLab project %d\n", filename, p.endpoint.ProjectID)
+	newURL, err := uploadFileToGitLab(p.endpoint.ProjectID, data, filename, p.endpoint.Token, p.endpoint.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	if parts := strings.SplitN(newURL, "/uploads/", 2); len(parts) == 2 {
+		return "/uploads/" + parts[1], nil
+	}
+	return newURL, nil
+}
+
+// UserEmail returns the email address of a GitLab user; only administrators
+// see addresses that are not public
+func (p *gitlabProvider) UserEmail(username string) (string, error) {
+	user, err := p.user(username)
+	if err != nil || user == nil {
+		return "", err
+	}
+	if user.Email != "" {
+		return user.Email, nil
+	}
+	return user.PublicEmail, nil
+}
+
+// FindUserByEmail searches GitLab users by email address
+func (p *gitlabProvider) FindUserByEmail(email string) (string, error) {
+	users, _, err := p.client.Users.ListUsers(&gitlab.ListUsersOptions{Search: &email})
+	if err != nil {
+		return "", err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) || strings.EqualFold(user.PublicEmail, email) {
+			return user.Username, nil
+		}
+	}
+	return "", nil
+}
+
+// user looks up a GitLab user by username, or returns nil if there is none
+func (p *gitlabProvider) user(username string) (*gitlab.User, error) {
+	users, _, err := p.client.Users.ListUsers(&gitlab.ListUsersOptions{Username: &username})
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+	p.userIDs[username] = users[0].ID
+	return users[0], nil
+}
+
+// resolveUsers returns the IDs of the given usernames; unknown users are skipped
+func (p *gitlabProvider) resolveUsers(usernames []string) ([]int, error) {
+	ids := []int{}
+	for _, username := range usernames {
+		if _, ok := p.userIDs[username]; !ok {
+			user, err := p.user(username)
+			if err != nil {
+				return nil, err
+			}
+			if user == nil {
+				fmt.Printf("[WARNING] GitLab user %s does not exist, not assigning them\n", username)
+				p.userIDs[username] = 0
+			}
+		}
+		if id := p.userIDs[username]; id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (p *gitlabProvider) trackerIssue(issue *gitlab.Issue) *TrackerIssue {
+	p.issueURLs[issue.IID] = issue.WebURL
+	tracked := &TrackerIssue{
+		Number:   issue.IID,
+		Title:    issue.Title,
+		Body:     issue.Description,
+		State:    "open",
+		Labels:   append([]string{}, issue.Labels...),
+		URL:      issue.WebURL,
+		ClosedAt: issue.ClosedAt,
+	}
+	if strings.ToLower(issue.State) == "closed" {
+		tracked.State = "closed"
+	}
+	if issue.Author != nil {
+		tracked.Author = issue.Author.Username
+	}
+	for _, assignee := range issue.Assignees {
+		tracked.Assignees = append(tracked.Assignees, assignee.Username)
+	}
+	if issue.Milestone != nil {
+		tracked.Milestone = issue.Milestone.Title
+	}
+	if issue.DueDate != nil {
+		dueDate := time.Time(*issue.DueDate)
+		tracked.DueDate = &dueDate
+	}
+	if issue.Weight != 0 {
+		weight := issue.Weight
+		tracked.Weight = &weight
+	}
+	tracked.Confidential = issue.Confidential
+	tracked.HealthStatus = issue.HealthStatus
+	if issue.Epic != nil {
+		tracked.Epic = issue.Epic.Title
+	}
+	if issue.CreatedAt != nil {
+		tracked.CreatedAt = *issue.CreatedAt
+	}
+	if issue.UpdatedAt != nil {
+		tracked.UpdatedAt = *issue.UpdatedAt
+	}
+	return tracked
+}
+
+func (p *gitlabProvider) trackerComment(issueIID int, note *gitlab.Note) *TrackerComment {
+	comment := &TrackerComment{
+		ID:     int64(note.ID),
+		Body:   note.Body,
+		Author: note.Author.Username,
+		System: note.System,
+	}
+	if issueURL, ok := p.issueURLs[issueIID]; ok {
+		comment.URL = fmt.Sprintf("%s#note_%d", issueURL, note.ID)
+	}
+	if note.CreatedAt != nil {
+		comment.CreatedAt = *note.CreatedAt
+	}
+	if note.UpdatedAt != nil {
+		comment.UpdatedAt = *note.UpdatedAt
+	}
+	return comment
+}
+
+func gitlabTrackerEvent(action string, actor string, subject string, createdAt *time.Time) *TrackerEvent {
+	event := &TrackerEvent{Action: action, Actor: actor, Subject: subject}
+	if createdAt != nil {
+		event.CreatedAt = *createdAt
+	}
+	return event
+}
+
+// gitlabUsername returns the username of a user that may be missing, e.g.
+// for events of deleted users
+func gitlabUsername(user *gitlab.BasicUser) string {
+	if user == nil {
+		return ""
+	}
+	return user.Username
+}
+
+// gitlabSudoUserNotFound is part of the message of the 404 GitLab answers
+// when the user of a Sudo header does not exist
+const gitlabSudoUserNotFound = "User with ID or username"
+
+// gitlabSudoRefused reports whether a request failed because GitLab would not
+// act as the requested user: the user is blocked or may not write to the
+// project (401 or 403), or the user does not exist (a 404 naming the sudo
+// user). Other 404s, such as a missing issue, are not refusals.
+func gitlabSudoRefused(err error) bool {
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	switch errResp.Response.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	case http.StatusNotFound:
+		return strings.Contains(errResp.Message, gitlabSudoUserNotFound)
+	}
+	return false
+}
+
+// gitlabStateEvent translates an issue state into the event that sets it
+func gitlabStateEvent(state string) string {
+	if state == "closed" {
+		return "close"
+	}
+	return "reopen"
+}
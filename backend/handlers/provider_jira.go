@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+// jiraPageSize is the page size used for Jira search and comment requests
+const jiraPageSize = 50
+
+// jiraIssueFields are the issue fields requested from Jira
+const jiraIssueFields = "summary,description,status,issuetype,priority,components,labels,reporter,assignee,duedate,created,updated,resolutiondate,attachment"
+
+// errJiraReadOnly is returned by the methods that would write to Jira
+var errJiraReadOnly = errors.New("jira is only supported as a migration source")
+
+var jiraOrderByRegex = regexp.MustCompile(`(?i)\s+order\s+by\s+.*$`)
+
+// jiraProvider reads issues of a Jira Cloud or Jira Server/Data Center
+// project. Issues are numbered by the numeric part of their key, so PROJ-42
+// is issue 42 of project PROJ. Jira is a source only.
+type jiraProvider struct {
+	client   *http.Client
+	endpoint models.Endpoint
+	// cloud selects REST API v3, whose bodies are ADF documents, and basic
+	// authentication with email and API token; otherwise REST API v2 with
+	// wiki markup bodies and a personal access token is used
+	cloud bool
+	// attachments remembers the files of each issue so comments can link to them
+	attachments     map[int][]jiraAttachment
+	attachmentRegex *regexp.Regexp
+}
+
+type jiraUser struct {
+	Name        string `json:"name"` // not returned by Jira Cloud
+	DisplayName string `json:"displayName"`
+}
+
+type jiraNamed struct {
+	Name string `json:"name"`
+}
+
+type jiraAttachment struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+	MimeType string `json:"mimeType"`
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string          `json:"summary"`
+		Description json.RawMessage `json:"description"`
+		Status      struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		IssueType      *jiraNamed       `json:"issuetype"`
+		Priority       *jiraNamed       `json:"priority"`
+		Components     []jiraNamed      `json:"components"`
+		Labels         []string         `json:"labels"`
+		Reporter       *jiraUser        `json:"reporter"`
+		Assignee       *jiraUser        `json:"assignee"`
+		DueDate        string           `json:"duedate"` // "2006-01-02"
+		Created        jiraTime         `json:"created"`
+		Updated        jiraTime         `json:"updated"`
+		ResolutionDate *jiraTime        `json:"resolutiondate"`
+		Attachment     []jiraAttachment `json:"attachment"`
+	} `json:"fields"`
+}
+
+type jiraComment struct {
+	ID      string          `json:"id"`
+	Author  *jiraUser       `json:"author"`
+	Body    json.RawMessage `json:"body"`
+	Created jiraTime        `json:"created"`
+	Updated jiraTime        `json:"updated"`
+}
+
+// jiraTime parses Jira timestamps such as 2024-01-02T15:04:05.000+0000
+type jiraTime struct {
+	time.Time
+}
+
+func (t *jiraTime) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil || value == "" {
+		return err
+	}
+	parsed, err := time.Parse("2006-01-02T15:04:05.000-0700", value)
+	if err != nil {
+		parsed, err = time.Parse(time.RFC3339, value)
+	}
+	t.Time = parsed
+	return err
+}
+
+func newJiraProvider(endpoint models.Endpoint) *jiraProvider {
+	endpoint.BaseURL = strings.TrimSuffix(endpoint.BaseURL, "/")
+	return &jiraProvider{
+		client:      &http.Client{Timeout: 60 * time.Second},
+		endpoint:    endpoint,
+		cloud:       endpoint.Email != "",
+		attachments: make(map[int][]jiraAttachment),
+		attachmentRegex: regexp.MustCompile(`^` + regexp.QuoteMeta(endpoint.BaseURL) +
+			`/(?:secure/attachment/\d+/\S+|rest/api/\d+/attachment/content/\d+)$`),
+	}
+}
+
+func (p *jiraProvider) Platform() string { return "jira" }
+
+func (p *jiraProvider) Name() string { return "Jira" }
+
+func (p *jiraProvider) Project() string {
+	return jiraProject(p.endpoint.BaseURL, p.endpoint.ProjectKey)
+}
+
+func (p *jiraProvider) ListIssues(since time.Time) ([]*TrackerIssue, error) {
+	query := url.Values{}
+	query.Set("jql", p.jql(since))
+	query.Set("fields", jiraIssueFields)
+	query.Set("maxResults", strconv.Itoa(jiraPageSize))
+
+	// Jira Cloud pages with tokens; Server and Data Center with offsets
+	path := p.apiPath("/search")
+	if p.cloud {
+		path = p.apiPath("/search/jql")
+	}
+
+	var all []*TrackerIssue
+	for startAt := 0; ; {
+		var result struct {
+			Issues        []jiraIssue `json:"issues"`
+			Total         int         `json:"total"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := p.do("GET", path+"?"+query.Encode(), &result); err != nil {
+			return nil, err
+		}
+		for i := range result.Issues {
+			issue := p.trackerIssue(&result.Issues[i])
+			if issue.UpdatedAt.Before(since) {
+				continue
+			}
+			all = append(all, issue)
+		}
+
+		startAt += len(result.Issues)
+		if p.cloud {
+			if result.NextPageToken == "" {
+				return all, nil
+			}
+			query.Set("nextPageToken", result.NextPageToken)
+		} else {
+			if len(result.Issues) == 0 || startAt >= result.Total {
+				return all, nil
+			}
+			query.Set("startAt", strconv.Itoa(startAt))
+		}
+	}
+}
+
+// jql returns the query for the project's issues, narrowed by the endpoint's
+// JQL. JQL compares update times in the user's time zone, so the query for a
+// sync starts a day early and ListIssues filters the exact time.
+func (p *jiraProvider) jql(since time.Time) string {
+	clauses := []string{fmt.Sprintf("project = %q", p.endpoint.ProjectKey)}
+	if filter := strings.TrimSpace(jiraOrderByRegex.ReplaceAllString(p.endpoint.JQL, "")); filter != "" {
+		clauses = append(clauses, "("+filter+")")
+	}
+	if !since.IsZero() {
+		clauses = append(clauses, fmt.Sprintf(`updated >= "%s"`, since.Add(-24*time.Hour).UTC().Format("2006-01-02 15:04")))
+	}
+	return strings.Join(clauses, " AND ") + " ORDER BY key ASC"
+}
+
+func (p *jiraProvider) GetIssue(number int) (*TrackerIssue, error) {
+	var issue jiraIssue
+	if err := p.do("GET", p.apiPath("/issue/"+p.key(number))+"?fields="+jiraIssueFields, &issue); err != nil {
+		return nil, err
+	}
+	return p.trackerIssue(&issue), nil
+}
+
+// FindIssueByMarker finds nothing, since no issue is ever migrated to Jira
+func (p *jiraProvider) FindIssueByMarker(marker string) (*TrackerIssue, error) {
+	return nil, nil
+}
+
+func (p *jiraProvider) CreateIssue(input IssueInput) (*TrackerIssue, error) {
+	return nil, errJiraReadOnly
+}
+
+func (p *jiraProvider) UpdateIssue(number int, input IssueInput) (*TrackerIssue, error) {
+	return nil, errJiraReadOnly
+}
+
+func (p *jiraProvider) SetState(number int, state string) error {
+	return errJiraReadOnly
+}
+
+// ListLabels returns nil; Jira labels are global and have no definitions
+func (p *jiraProvider) ListLabels() ([]TrackerLabel, error) {
+	return nil, nil
+}
+
+func (p *jiraProvider) ListComments(number int, since time.Time) ([]*TrackerComment, error) {
+	attachments, ok := p.attachments[number]
+	if !ok {
+		// Comments embed the issue's attachments by filename
+		if _, err := p.GetIssue(number); err != nil {
+			return nil, err
+		}
+		attachments = p.attachments[number]
+	}
+
+	query := url.Values{}
+	query.Set("orderBy", "created")
+	query.Set("maxResults", strconv.Itoa(jiraPageSize))
+	path := p.apiPath("/issue/" + p.key(number) + "/comment")
+
+	var all []*TrackerComment
+	for startAt := 0; ; {
+		query.Set("startAt", strconv.Itoa(startAt))
+		var result struct {
+			Comments []jiraComment `json:"comments"`
+			Total    int           `json:"total"`
+		}
+		if err := p.do("GET", path+"?"+query.Encode(), &result); err != nil {
+			return nil, err
+		}
+		for i := range result.Comments {
+			comment := &result.Comments[i]
+			if comment.Updated.Before(since) {
+				continue
+			}
+			all = append(all, p.trackerComment(number, comment, attachments))
+		}
+
+		startAt += len(result.Comments)
+		if len(result.Comments) == 0 || startAt >= result.Total {
+			return all, nil
+		}
+	}
+}
+
+func (p *jiraProvider) AddComment(number int, body string) (*TrackerComment, error) {
+	return nil, errJiraReadOnly
+}
+
+// FindAttachments returns the Jira attachments linked from converted Markdown
+func (p *jiraProvider) FindAttachments(body string) (string, []AttachmentInfo) {
+	var attachments []AttachmentInfo
+	for _, link := range attachmentLinks(body, p.attachmentRegex) {
+		filename := sanitizeFilename(link.Text)
+		if filename == "" {
+			filename = "attachment"
+		}
+		attachments = append(attachments, AttachmentInfo{
+			URL:          link.URL,
+			Filename:     filename,
+			IsImage:      link.Image || isImageURL(link.Text),
+			OriginalText: link.Source,
+		})
+		fmt.Printf("[ATTACH] Found Jira attachment: %s\n", link.URL)
+	}
+	return body, attachments
+}
+
+func (p *jiraProvider) DownloadAttachment(attachment AttachmentInfo) ([]byte, error) {
+	req, err := http.NewRequest("GET", attachment.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (p *jiraProvider) UploadAttachment(number int, data []byte, filename string) (string, error) {
+	return "", errJiraReadOnly
+}
+
+// jiraLabels maps the issue type, priority and components to labels next
+// to the issue's own labels
+func jiraLabels(issue *jiraIssue) []string {
+	labels := append([]string{}, issue.Fields.Labels...)
+	if issue.Fields.IssueType != nil && issue.Fields.IssueType.Name != "" {
+		labels = append(labels, "type: "+issue.Fields.IssueType.Name)
+	}
+	if issue.Fields.Priority != nil && issue.Fields.Priority.Name != "" {
+		labels = append(labels, "priority: "+issue.Fields.Priority.Name)
+	}
+	for _, component := range issue.Fields.Components {
+		labels = append(labels, "component: "+component.Name)
+	}
+	return labels
+}
+
+func (p *jiraProvider) trackerIssue(issue *jiraIssue) *TrackerIssue {
+	number := jiraIssueNumber(issue.Key)
+	p.attachments[number] = issue.Fields.Attachment
+
+	links := newJiraAttachmentLinks(issue.Fields.Attachment)
+	body := p.markdown(issue.Fields.Description, links)
+	body = appendAttachmentList(body, links.unreferenced())
+
+	tracked := &TrackerIssue{
+		Number:    number,
+		Title:     issue.Fields.Summary,
+		Body:      body,
+		State:     "open",
+		Labels:    jiraLabels(issue),
+		Author:    jiraUserName(issue.Fields.Reporter),
+		URL:       p.endpoint.BaseURL + "/browse/" + issue.Key,
+		CreatedAt: issue.Fields.Created.Time,
+		UpdatedAt: issue.Fields.Updated.Time,
+	}
+	if issue.Fields.Assignee != nil {
+		tracked.Assignees = []string{jiraUserName(issue.Fields.Assignee)}
+	}
+	if dueDate, err := time.Parse("2006-01-02", issue.Fields.DueDate); err == nil {
+		tracked.DueDate = &dueDate
+	}
+	if issue.Fields.Status.StatusCategory.Key == "done" {
+		tracked.State = "closed"
+		if issue.Fields.ResolutionDate != nil {
+			closedAt := issue.Fields.ResolutionDate.Time
+			tracked.ClosedAt = &closedAt
+		}
+	}
+	return tracked
+}
+
+func (p *jiraProvider) trackerComment(number int, comment *jiraComment, attachments []jiraAttachment) *TrackerComment {
+	id, _ := strconv.ParseInt(comment.ID, 10, 64)
+	return &TrackerComment{
+		ID:        id,
+		Body:      p.markdown(comment.Body, newJiraAttachmentLinks(attachments)),
+		Author:    jiraUserName(comment.Author),
+		URL:       fmt.Sprintf("%s/browse/%s?focusedCommentId=%s", p.endpoint.BaseURL, p.key(number), comment.ID),
+		CreatedAt: comment.Created.Time,
+		UpdatedAt: comment.Updated.Time,
+	}
+}
+
+// markdown converts a description or comment body, which is wiki markup on
+// REST API v2 and an ADF document on v3
+func (p *jiraProvider) markdown(raw json.RawMessage, links *jiraAttachmentLinks) string {
+	var markup string
+	if err := json.Unmarshal(raw, &markup); err == nil {
+		return jiraWikiToMarkdown(markup, links)
+	}
+	var doc adfNode
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return ""
+	}
+	return adfToMarkdown(&doc, links)
+}
+
+func (p *jiraProvider) key(number int) string {
+	return fmt.Sprintf("%s-%d", p.endpoint.ProjectKey, number)
+}
+
+func (p *jiraProvider) apiPath(path string) string {
+	if p.cloud {
+		return "/rest/api/3" + path
+	}
+	return "/rest/api/2" + path
+}
+
+func (p *jiraProvider) authorize(req *http.Request) {
+	if p.cloud {
+		req.SetBasicAuth(p.endpoint.Email, p.endpoint.Token)
+	} else if p.endpoint.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.endpoint.Token)
+	}
+}
+
+// do sends a request without a body to the API and decodes the response into out
+func (p *jiraProvider) do(method string, path string, out interface{}) error {
+	req, err := http.NewRequest(method, p.endpoint.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	p.authorize(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("jira API %s %s returned status %d: %s", req.Method, req.URL.Path, resp.StatusCode, string(respBody))
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// jiraIssueNumber returns the numeric part of an issue key such as PROJ-42
+func jiraIssueNumber(key string) int {
+	number, _ := strconv.Atoi(key[strings.LastIndex(key, "-")+1:])
+	return number
+}
+
+func jiraUserName(user *jiraUser) string {
+	if user == nil {
+		return "unknown"
+	}
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.DisplayName
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/issue-migrator/backend/markdown"
+	"github.com/issue-migrator/backend/models"
+)
+
+// issuePathRegex matches the issue part at the end of a GitHub, GitLab or
+// Gitea issue URL
+var issuePathRegex = regexp.MustCompile(`(?:/-)?/issues/\d+/?$`)
+
+// projectURL returns the project address of an issue URL, or "" if it is not
+// a GitHub, GitLab or Gitea issue URL
+func projectURL(issueURL string) string {
+	loc := issuePathRegex.FindStringIndex(issueURL)
+	if loc == nil {
+		return ""
+	}
+	return issueURL[:loc[0]]
+}
+
+// writtenText is a body written to the target during a run. Only its content
+// is rewritten; the header carries the marker and the link to the source issue.
+type writtenText struct {
+	number    int   // target issue
+	commentID int64 // 0 for the issue body
+	header    string
+	content   string
+}
+
+// referenceRewriter collects the bodies written to the target during a run
+// and, once every issue of the batch has its target number, rewrites the
+// references between issues in them: #123, owner/repo#123, GitLab !67 merge
+// request references and issue URLs of the source project.
+type referenceRewriter struct {
+	req     models.MigrationRequest
+	source  Provider
+	target  Provider
+	written []writtenText
+}
+
+func newReferenceRewriter(req models.MigrationRequest, source Provider, target Provider) *referenceRewriter {
+	return &referenceRewriter{req: req, source: source, target: target}
+}
+
+// issueWritten records the body of a created or updated target issue
+func (r *referenceRewriter) issueWritten(number int, header string, content string) {
+	r.written = append(r.written, writtenText{number: number, header: header, content: content})
+}
+
+// commentWritten records a comment added to a target issue
+func (r *referenceRewriter) commentWritten(number int, commentID int64, header string, content string) {
+	r.written = append(r.written, writtenText{number: number, commentID: commentID, header: header, content: content})
+}
+
+// rewrite updates the recorded bodies whose references change. Issues are
+// looked up in the results of the batch and in the records of earlier runs;
+// references to other issues and to merge requests link to the source.
+func (r *referenceRewriter) rewrite(result models.MigrationResult) {
+	if len(r.written) == 0 {
+		return
+	}
+	refs, err := r.references(result)
+	if err != nil {
+		fmt.Printf("[WARNING] Issue references are not rewritten: %v\n", err)
+		return
+	}
+	editor, canEdit := r.target.(commentEditor)
+	if !canEdit {
+		fmt.Printf("[INFO] %s comments cannot be edited; references are only rewritten in issue bodies\n", r.target.Name())
+	}
+
+	fmt.Printf("[REFS] Rewriting issue references in %d body(ies)\n", len(r.written))
+	updated := 0
+	for _, text := range r.written {
+		content := refs.rewrite(text.content)
+		if content == text.content {
+			continue
+		}
+		if text.commentID == 0 {
+			if _, err := r.target.UpdateIssue(text.number, IssueInput{Body: text.header + content}); err != nil {
+				fmt.Printf("[WARNING] Failed to rewrite the references of %s issue #%d: %v\n", r.target.Name(), text.number, err)
+				continue
+			}
+		} else {
+			if !canEdit {
+				continue
+			}
+			if err := editor.UpdateComment(text.number, text.commentID, text.header+content); err != nil {
+				fmt.Printf("[WARNING] Failed to rewrite the references of comment %d on %s issue #%d: %v\n", text.commentID, r.target.Name(), text.number, err)
+				continue
+			}
+		}
+		updated++
+	}
+	fmt.Printf("[REFS] Rewrote references in %d body(ies)\n", updated)
+}
+
+// references prepares the rewriting of references of the source project
+func (r *referenceRewriter) references(result models.MigrationResult) (*references, error) {
+	linker, ok := r.source.(projectLinker)
+	if !ok {
+		return nil, fmt.Errorf("%s issues have no Markdown references", r.source.Name())
+	}
+	sourceURL, err := linker.WebURL()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %s project address: %w", r.source.Name(), err)
+	}
+	parsed, err := url.Parse(sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s project address %q: %w", r.source.Name(), sourceURL, err)
+	}
+	sourceURL = strings.TrimSuffix(sourceURL, "/")
+
+	refs := &references{
+		source:     r.source.Platform(),
+		target:     r.target.Platform(),
+		sourceURL:  sourceURL,
+		sourcePath: strings.Trim(parsed.Path, "/"),
+		issues:     r.issueMap(result),
+	}
+	if target, ok := r.target.(projectLinker); ok && refs.source == refs.target {
+		if targetURL, err := target.WebURL(); err == nil {
+			if parsedTarget, err := url.Parse(targetURL); err == nil {
+				refs.sameHost = strings.EqualFold(parsed.Host, parsedTarget.Host)
+			}
+		}
+	}
+	refs.compile()
+	return refs, nil
+}
+
+// issueMap returns the target issues of source issues, from the records of
+// earlier runs and the results of this one
+func (r *referenceRewriter) issueMap(result models.MigrationResult) map[int]models.MigrationStatus {
+	issues := make(map[int]models.MigrationStatus)
+	if stateStore != nil {
+		records, err := stateStore.ListIssues(migrationKey(r.req))
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to read the issues of earlier runs, only this run is used to rewrite references: %v\n", err)
+		}
+		for _, record := range records {
+			if record.TargetID != 0 {
+				issues[record.SourceID] = models.MigrationStatus{OriginalID: record.SourceID, NewID: record.TargetID, NewURL: record.TargetURL}
+			}
+		}
+	}
+	for _, status := range result.Success {
+		issues[status.OriginalID] = status
+	}
+	return issues
+}
+
+// references rewrites the issue references of a source project for the target
+type references struct {
+	source     string // platform of the source
+	target     string // platform of the target
+	sourceURL  string
+	sourcePath string // e.g. "owner/repo" or "group/subgroup/project"
+	// sameHost is set when the target is on the same instance as the source,
+	// so qualified references to the source keep working there
+	sameHost bool
+	issues   map[int]models.MigrationStatus // source issue number -> target issue
+
+	urlRegex *regexp.Regexp // issue URLs of the source project
+	// refRegex matches #123 and !67, optionally qualified with the source
+	// path as in owner/repo#123
+	refRegex *regexp.Regexp
+}
+
+// compile prepares the expressions matching the issue URLs and references
+// of the source project
+func (refs *references) compile() {
+	prefixes := "#"
+	if refs.source == "gitlab" {
+		prefixes = "#!"
+	}
+	refs.urlRegex = regexp.MustCompile(`^` + regexp.QuoteMeta(refs.sourceURL) + `(?:/-)?/issues/(\d+)/?(#[\w-]+)?$`)
+	refs.refRegex = regexp.MustCompile(`(?m)(^|[^\w&/#!.-])((?:` + regexp.QuoteMeta(refs.sourcePath) + `)?)([` + prefixes + `])(\d+)\b`)
+}
+
+// rewrite returns a Markdown text with the issue URLs of its links and the
+// references in its prose rewritten; code is left as it is
+func (refs *references) rewrite(text string) string {
+	text = markdown.ReplaceLinks(text, func(link markdown.LinkRef) string {
+		match := refs.urlRegex.FindStringSubmatch(link.URL)
+		if match == nil {
+			return link.URL
+		}
+		issue, ok := refs.issues[refNumber(match[1])]
+		if !ok || issue.NewURL == "" || match[2] != "" {
+			// Unknown issues and links to a comment keep pointing to the source
+			return link.URL
+		}
+		return issue.NewURL
+	})
+	return markdown.ReplaceText(text, func(prose string) string {
+		return refs.refRegex.ReplaceAllStringFunc(prose, func(ref string) string {
+			match := refs.refRegex.FindStringSubmatch(ref)
+			prefix, issueNumber := match[3], refNumber(match[4])
+			if target, ok := refs.targetRef(prefix, issueNumber); ok {
+				return match[1] + target
+			}
+			if match[2] != "" && refs.sameHost {
+				// Qualified references to the source keep working on its instance
+				return ref
+			}
+			return match[1] + refs.sourceRef(prefix, issueNumber)
+		})
+	})
+}
+
+// targetRef returns the reference to the target issue of a migrated source
+// issue; trackers without #123 references get its URL
+func (refs *references) targetRef(prefix string, number int) (string, bool) {
+	issue, ok := refs.issues[number]
+	if prefix != "#" || !ok {
+		return "", false
+	}
+	switch {
+	case refs.target == "github" || refs.target == "gitlab" || refs.target == "gitea" || issue.NewURL == "":
+		return fmt.Sprintf("#%d", issue.NewID), true
+	default:
+		return issue.NewURL, true
+	}
+}
+
+// sourceRef returns a reference to an issue or merge request that stays on
+// the source: a qualified reference on the same instance, its URL otherwise
+func (refs *references) sourceRef(prefix string, number int) string {
+	if refs.sameHost {
+		return fmt.Sprintf("%s%s%d", refs.sourcePath, prefix, number)
+	}
+	switch {
+	case prefix == "!":
+		return fmt.Sprintf("%s/-/merge_requests/%d", refs.sourceURL, number)
+	case refs.source == "gitlab":
+		return fmt.Sprintf("%s/-/issues/%d", refs.sourceURL, number)
+	default:
+		return fmt.Sprintf("%s/issues/%d", refs.sourceURL, number)
+	}
+}
+
+// refNumber converts a matched issue number
+func refNumber(digits string) int {
+	n, _ := strconv.Atoi(digits)
+	return n
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+func TestReferencesRewrite(t *testing.T) {
+	issues := map[int]models.MigrationStatus{
+		1: {OriginalID: 1, NewID: 11, NewURL: "https://gitlab.example.com/group/app/-/issues/11"},
+		2: {OriginalID: 2, NewID: 12, NewURL: "https://gitlab.example.com/group/app/-/issues/12"},
+	}
+	fromGitHub := &references{
+		source:     "github",
+		target:     "gitlab",
+		sourceURL:  "https://github.com/owner/repo",
+		sourcePath: "owner/repo",
+		issues:     issues,
+	}
+	fromGitLab := &references{
+		source:     "gitlab",
+		target:     "gitlab",
+		sourceURL:  "https://gitlab.example.com/group/old",
+		sourcePath: "group/old",
+		sameHost:   true,
+		issues:     issues,
+	}
+	toJira := &references{
+		source:     "github",
+		target:     "jira",
+		sourceURL:  "https://github.com/owner/repo",
+		sourcePath: "owner/repo",
+		issues:     issues,
+	}
+	for _, refs := range []*references{fromGitHub, fromGitLab, toJira} {
+		refs.compile()
+	}
+
+	tests := []struct {
+		name string
+		refs *references
+		text string
+		want string
+	}{
+		{"issue", fromGitHub, "Fixes #1 and #2.", "Fixes #11 and #12."},
+		{"unknown issue", fromGitHub, "See #3", "See https://github.com/owner/repo/issues/3"},
+		{"qualified issue", fromGitHub, "owner/repo#1", "#11"},
+		{"other project", fromGitHub, "other/repo#1", "other/repo#1"},
+		{"not a reference", fromGitHub, "a&#1; x#1 C#1", "a&#1; x#1 C#1"},
+		{"heading", fromGitHub, "## Part #1", "## Part #11"},
+		{"link text", fromGitHub, "[#1](https://example.com)", "[#11](https://example.com)"},
+		{"issue URL", fromGitHub, "https://github.com/owner/repo/issues/1", "https://gitlab.example.com/group/app/-/issues/11"},
+		{"issue link", fromGitHub, "[the bug](https://github.com/owner/repo/issues/2)", "[the bug](https://gitlab.example.com/group/app/-/issues/12)"},
+		{"comment URL", fromGitHub, "https://github.com/owner/repo/issues/1#issuecomment-5", "https://github.com/owner/repo/issues/1#issuecomment-5"},
+		{"other URL", fromGitHub, "https://github.com/owner/repo/issues/1/edit", "https://github.com/owner/repo/issues/1/edit"},
+		{"code span", fromGitHub, "`#1` #1", "`#1` #11"},
+		{"code span across lines", fromGitHub, "`a\n#1` #1", "`a\n#1` #11"},
+		{"fenced code", fromGitHub, "```\n#1\n```\n#1", "```\n#1\n```\n#11"},
+		{"indented code", fromGitHub, "    #1\n\n#1", "    #1\n\n#11"},
+		{"html comment", fromGitHub, "<!-- #1 --> #1", "<!-- #1 --> #11"},
+		{"merge request", fromGitLab, "!1 and #1", "group/old!1 and #11"},
+		{"qualified on the same host", fromGitLab, "group/old#3", "group/old#3"},
+		{"GitLab issue URL", fromGitLab, "https://gitlab.example.com/group/old/-/issues/2", "https://gitlab.example.com/group/app/-/issues/12"},
+		{"tracker without references", toJira, "#1", "https://gitlab.example.com/group/app/-/issues/11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.refs.rewrite(tt.text); got != tt.want {
+				t.Errorf("rewrite(%q)\n got: %q\nwant: %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
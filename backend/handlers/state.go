@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/issue-migrator/backend/models"
+	"github.com/issue-migrator/backend/store"
+)
+
+// stateStore persists per-issue migration progress; nil disables persistence
+var stateStore *store.Store
+
+// SetStateStore configures the store used to record and resume migrations
+func SetStateStore(s *store.Store) {
+	stateStore = s
+}
+
+// migrationKey identifies a source/target pair so records from different
+// migrations never collide
+func migrationKey(req models.MigrationRequest) string {
+	return fmt.Sprintf("%s->%s", endpointKey(req.Source), endpointKey(req.Target))
+}
+
+func endpointKey(endpoint models.Endpoint) string {
+	if endpoint.ProjectID != 0 {
+		return fmt.Sprintf("%s:%s/%d", endpoint.Type, endpoint.BaseURL, endpoint.ProjectID)
+	}
+	if endpoint.ArchivePath != "" {
+		return fmt.Sprintf("%s:%s", endpoint.Type, endpoint.ArchivePath)
+	}
+	if endpoint.ProjectKey != "" {
+		return fmt.Sprintf("%s:%s/%s", endpoint.Type, endpoint.BaseURL, endpoint.ProjectKey)
+	}
+	if endpoint.BaseURL != "" && endpoint.Type != "github" {
+		return fmt.Sprintf("%s:%s/%s/%s", endpoint.Type, endpoint.BaseURL, endpoint.Owner, endpoint.Repo)
+	}
+	return fmt.Sprintf("%s:%s/%s", endpoint.Type, endpoint.Owner, endpoint.Repo)
+}
+
+// issueState tracks the persisted progress of one source issue during a run
+type issueState struct {
+	migration string
+	record    *store.IssueRecord
+}
+
+// loadIssueState returns the stored progress of a source issue. Migrations
+// without resume ignore and overwrite the previous record; syncs always use it.
+func loadIssueState(req models.MigrationRequest, issueID int) *issueState {
+	state := &issueState{
+		migration: migrationKey(req),
+		record:    store.NewIssueRecord(issueID),
+	}
+	if stateStore == nil || (!req.Resume && req.Mode != ModeSync) {
+		return state
+	}
+
+	record, err := stateStore.GetIssue(state.migration, issueID)
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to read state for issue #%d: %v\n", issueID, err)
+		return state
+	}
+	if record != nil {
+		fmt.Printf("[STATE] Loaded issue #%d (target #%d, %d comments, %d attachments done)\n",
+			issueID, record.TargetID, len(record.Comments), len(record.Attachments))
+		state.record = record
+	}
+	return state
+}
+
+// created reports whether the target issue already exists
+func (s *issueState) created() bool {
+	return s.record.TargetID != 0
+}
+
+// completed reports whether the issue was fully migrated by an earlier run
+func (s *issueState) completed() bool {
+	return s.record.Completed
+}
+
+// status returns the migration status for an issue finished by an earlier run
+func (s *issueState) status() models.MigrationStatus {
+	return models.MigrationStatus{
+		OriginalID: s.record.SourceID,
+		NewID:      s.record.TargetID,
+		NewURL:     s.record.TargetURL,
+		Skipped:    true,
+	}
+}
+
+// targetCreated records the target issue created for the source issue
+func (s *issueState) targetCreated(targetID int, targetURL string) {
+	s.record.TargetID = targetID
+	s.record.TargetURL = targetURL
+	s.save()
+}
+
+// bodyDone reports whether the target body was written with its attachments
+func (s *issueState) bodyDone() bool {
+	return s.record.BodyDone
+}
+
+// bodyWritten records that the target body has its attachments
+func (s *issueState) bodyWritten() {
+	s.record.BodyDone = true
+	s.save()
+}
+
+// commentDone reports whether a source comment was already copied
+func (s *issueState) commentDone(sourceCommentID int64) bool {
+	_, ok := s.record.Comments[sourceCommentID]
+	return ok
+}
+
+// commentCreated records a copied comment
+func (s *issueState) commentCreated(sourceCommentID int64, targetCommentID int64) {
+	s.record.Comments[sourceCommentID] = targetCommentID
+	s.save()
+}
+
+// attachmentUploaded records a re-uploaded attachment
+func (s *issueState) attachmentUploaded(originalURL string, newURL string) {
+	s.record.Attachments[originalURL] = newURL
+	s.save()
+}
+
+// complete marks the issue as fully migrated
+func (s *issueState) complete() {
+	s.record.Completed = true
+	s.save()
+}
+
+func (s *issueState) save() {
+	if stateStore == nil {
+		return
+	}
+	if err := stateStore.PutIssue(s.migration, s.record); err != nil {
+		fmt.Printf("[WARNING] Failed to persist state for issue #%d: %v\n", s.record.SourceID, err)
+	}
+}
+
+// attachmentTracker reuses uploads recorded by earlier runs and reports new ones
+type attachmentTracker struct {
+	uploaded map[string]string // original URL -> uploaded URL
+	onUpload func(originalURL, newURL string)
+}
+
+// newAttachmentTracker ties attachment uploads of an issue to its state and job events
+func newAttachmentTracker(state *issueState, job *Job, issueID int) *attachmentTracker {
+	return &attachmentTracker{
+		uploaded: state.record.Attachments,
+		onUpload: func(originalURL, newURL string) {
+			state.attachmentUploaded(originalURL, newURL)
+			job.attachmentUploaded(issueID, originalURL, newURL)
+		},
+	}
+}
+
+// lookup returns the URL of an attachment uploaded earlier
+func (t *attachmentTracker) lookup(originalURL string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	newURL, ok := t.uploaded[originalURL]
+	return newURL, ok
+}
+
+// record reports a freshly uploaded attachment
+func (t *attachmentTracker) record(originalURL string, newURL string) {
+	if t == nil || t.onUpload == nil {
+		return
+	}
+	t.onUpload(originalURL, newURL)
+}
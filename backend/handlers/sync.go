@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+// Migration modes
+const (
+	ModeMigrate = "migrate"
+	ModeSync    = "sync"
+)
+
+// syncSince returns the watermark a sync starts from: the one in the request,
+// otherwise the one stored by the previous sync, otherwise the beginning of time
+func syncSince(req models.MigrationRequest, migration string) time.Time {
+	if req.Since != nil {
+		return req.Since.UTC()
+	}
+	if stateStore != nil {
+		watermark, found, err := stateStore.GetWatermark(migration)
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to read sync watermark: %v\n", err)
+		} else if found {
+			return watermark
+		}
+	}
+	fmt.Println("[SYNC] No watermark found, synchronizing all changes")
+	return time.Time{}
+}
+
+// saveWatermark stores the next watermark: the start of the sync run, or
+// just before the oldest change that could not be synced
+func saveWatermark(migration string, watermark time.Time) {
+	if stateStore == nil {
+		fmt.Println("[WARNING] No state store configured, sync watermark not saved")
+		return
+	}
+	if err := stateStore.PutWatermark(migration, watermark); err != nil {
+		fmt.Printf("[WARNING] Failed to save sync watermark: %v\n", err)
+		return
+	}
+	fmt.Printf("[SYNC] Next sync will start from %s\n", watermark.Format(time.RFC3339))
+}
+
+// issueFilter returns the requested issue IDs as a set; an empty set selects all issues
+func issueFilter(issueIDs []int) map[int]bool {
+	filter := make(map[int]bool, len(issueIDs))
+	for _, id := range issueIDs {
+		filter[id] = true
+	}
+	return filter
+}
+
+// notMigrated reports an updated source issue that has no target yet
+func notMigrated(issueID int) models.MigrationStatus {
+	return models.MigrationStatus{
+		OriginalID: issueID,
+		Error:      "issue has not been migrated yet; run a migration before syncing it",
+	}
+}
+
+// syncIssues pushes changes made on the source since the watermark to issues
+// that were migrated before, appending comments that are not on the target yet
+func syncIssues(req models.MigrationRequest, job *Job, source Provider, target Provider) models.MigrationResult {
+	result := models.MigrationResult{
+		Success: []models.MigrationStatus{},
+		Failed:  []models.MigrationStatus{},
+	}
+
+	migration := migrationKey(req)
+	since := syncSince(req, migration)
+	runStarted := time.Now().UTC()
+	filter := issueFilter(req.IssueIDs)
+	users := newUserMap(req, source, target)
+	milestones := newMilestoneSync(source, target)
+	translation := newTranslator(req, source, target)
+	references := newReferenceRewriter(req, source, target)
+	if req.SyncLabels {
+		syncLabels(source, target, translation, false)
+	}
+
+	fmt.Printf("[SYNC] Listing %s issues updated since %s\n", source.Name(), since.Format(time.RFC3339))
+	issues, err := source.ListIssues(since)
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to list %s issues: %v\n", source.Name(), err)
+		job.setError(err)
+		return result
+	}
+	var updated []*TrackerIssue
+	for _, issue := range issues {
+		if len(filter) > 0 && !filter[issue.Number] {
+			continue
+		}
+		updated = append(updated, issue)
+	}
+	fmt.Printf("[SYNC] Found %d updated %s issue(s)\n", len(updated), source.Name())
+	job.setTotal(len(updated))
+
+	// The watermark stays before issues that failed, so the next run retries them
+	watermark := runStarted
+	fail := func(issue *TrackerIssue, failure models.MigrationStatus) {
+		result.Failed = append(result.Failed, failure)
+		job.issueFailed(failure)
+		if retry := issue.UpdatedAt.Add(-time.Second); retry.Before(watermark) {
+			watermark = retry
+		}
+	}
+
+	for _, issue := range updated {
+		issueID := issue.Number
+		fmt.Printf("[SYNC] Processing %s issue #%d\n", source.Name(), issueID)
+		job.issueStarted(issueID)
+
+		state := loadIssueState(req, issueID)
+		tracker := newAttachmentTracker(state, job, issueID)
+		marker := issueMarker(source.Platform(), source.Project(), issueID)
+
+		if !state.created() {
+			existing, err := target.FindIssueByMarker(marker)
+			if err != nil {
+				fmt.Printf("[ERROR] Failed to search %s for issue #%d: %v\n", target.Name(), issueID, err)
+				fail(issue, models.MigrationStatus{OriginalID: issueID, Error: fmt.Sprintf("failed to search %s: %v", target.Name(), err)})
+				continue
+			}
+			if existing == nil {
+				// A later migration copies the whole issue, so the watermark
+				// does not need to wait for it
+				fmt.Printf("[SYNC] %s issue #%d has not been migrated, skipping\n", source.Name(), issueID)
+				failure := notMigrated(issueID)
+				result.Failed = append(result.Failed, failure)
+				job.issueFailed(failure)
+				continue
+			}
+			state.targetCreated(existing.Number, existing.URL)
+		}
+		targetNumber := state.record.TargetID
+		targetURL := state.record.TargetURL
+
+		// Update title, body, labels and state
+		issue, frontMatter := translation.translate(issue)
+		processedBody := transferAttachments(users.rewriteMentions(convertMarkdown(issue.Body, source, target)), source, target, targetNumber, tracker)
+		header := frontMatter + issueHeader(marker, source, issue, users)
+		input := issueInput(issue, header+processedBody, users, milestones)
+		if _, err := target.UpdateIssue(targetNumber, input); err != nil {
+			fmt.Printf("[ERROR] Failed to update %s issue #%d: %v\n", target.Name(), targetNumber, err)
+			fail(issue, models.MigrationStatus{OriginalID: issueID, Error: err.Error()})
+			continue
+		}
+		fmt.Printf("[SUCCESS] Updated %s issue #%d from %s issue #%d\n", target.Name(), targetNumber, source.Name(), issueID)
+		references.issueWritten(targetNumber, header, processedBody)
+
+		// Append comments that are not on the target yet
+		var commentErrors []string
+		comments, err := source.ListComments(issueID, since)
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to list comments of %s issue #%d: %v\n", source.Name(), issueID, err)
+			commentErrors = append(commentErrors, fmt.Sprintf("failed to list comments: %v", err))
+		}
+		// Replies to comments from before the watermark are not quoted, and
+		// new system notes get an activity log of their own
+		comments = prepareComments(comments, req.SystemNotes, source, users)
+		targetMarkers, err := commentMarkers(target, targetNumber)
+		if err != nil {
+			fmt.Printf("[WARNING] Failed to list existing comments of %s issue #%d: %v\n", target.Name(), targetNumber, err)
+		}
+		for _, comment := range comments {
+			noteMarker := commentMarker(source.Platform(), source.Project(), issueID, comment.ID)
+			if state.commentDone(comment.ID) || targetMarkers[markerToken(noteMarker)] {
+				continue
+			}
+			processedComment := transferAttachments(users.rewriteMentions(convertMarkdown(comment.Body, source, target)), source, target, targetNumber, tracker)
+			header := commentHeader(noteMarker, source, comment, users)
+			newComment, err := users.addComment(target, targetNumber, comment, header+processedComment)
+			if err != nil {
+				fmt.Printf("[WARNING] Failed to create comment: %v\n", err)
+				commentErrors = append(commentErrors, fmt.Sprintf("failed to create comment %d: %v", comment.ID, err))
+				continue
+			}
+			state.commentCreated(comment.ID, newComment.ID)
+			references.commentWritten(targetNumber, newComment.ID, header, processedComment)
+			commentURL := newComment.URL
+			if commentURL == "" {
+				commentURL = targetURL
+			}
+			job.commentCreated(issueID, int(newComment.ID), commentURL)
+		}
+		if req.History {
+			copyHistory(source, target, issueID, targetNumber, since, targetMarkers, users, references)
+		}
+		if len(commentErrors) > 0 {
+			fmt.Printf("[ERROR] %d comment(s) of issue #%d were not copied to %s issue #%d\n", len(commentErrors), issueID, target.Name(), targetNumber)
+			fail(issue, models.MigrationStatus{
+				OriginalID: issueID,
+				NewID:      targetNumber,
+				NewURL:     targetURL,
+				Error:      strings.Join(commentErrors, "; "),
+			})
+			continue
+		}
+
+		state.complete()
+		success := models.MigrationStatus{
+			OriginalID: issueID,
+			NewID:      targetNumber,
+			NewURL:     targetURL,
+		}
+		result.Success = append(result.Success, success)
+		job.issueSucceeded(success)
+	}
+
+	references.rewrite(result)
+	saveWatermark(migration, watermark)
+	users.report(&result)
+	return result
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncIssuesWatermark(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	held := updated.Add(-time.Second)
+
+	tests := []struct {
+		name       string
+		migrated   bool             // whether the source issue has a target issue
+		failures   map[string]error // failures of the target
+		stored     *time.Time       // watermark of the previous sync
+		wantHeld   bool             // whether the watermark is held before the issue
+		wantFailed int
+		wantWrites int
+	}{
+		{
+			name:       "synced issue moves the watermark to the start of the run",
+			migrated:   true,
+			wantWrites: 1,
+		},
+		{
+			name:       "failed update holds the watermark",
+			migrated:   true,
+			failures:   map[string]error{"UpdateIssue": errors.New("unavailable")},
+			wantHeld:   true,
+			wantFailed: 1,
+		},
+		{
+			name:       "failed comment holds the watermark",
+			migrated:   true,
+			failures:   map[string]error{"AddComment": errors.New("unavailable")},
+			wantHeld:   true,
+			wantFailed: 1,
+			wantWrites: 1,
+		},
+		{
+			name:       "issue that was never migrated does not hold the watermark",
+			wantFailed: 1,
+		},
+		{
+			name:       "issues older than the stored watermark are not synced",
+			migrated:   true,
+			stored:     func() *time.Time { at := updated.Add(time.Hour); return &at }(),
+			wantWrites: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := useTestStore(t)
+			source, target, req := testMigration()
+			req.Mode = ModeSync
+			req.IssueIDs = nil
+			source.issues[1].UpdatedAt = updated
+			source.comments[1] = source.comments[1][:1]
+			source.comments[1][0].UpdatedAt = updated
+			if tt.migrated {
+				target.putIssue(&TrackerIssue{Number: 1, Body: issueMarker("github", "owner/repo", 1)})
+			}
+			for method, err := range tt.failures {
+				target.failures[method] = err
+			}
+			migration := migrationKey(req)
+			if tt.stored != nil {
+				if err := s.PutWatermark(migration, *tt.stored); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			started := time.Now().UTC()
+			result := syncIssues(req, newJob(req), source, target)
+
+			if len(result.Failed) != tt.wantFailed {
+				t.Errorf("%d failed, want %d: %+v", len(result.Failed), tt.wantFailed, result.Failed)
+			}
+			updates := 0
+			for _, write := range target.writes {
+				if write == "update #1" {
+					updates++
+				}
+			}
+			if updates != tt.wantWrites {
+				t.Errorf("target writes %v, want %d update(s)", target.writes, tt.wantWrites)
+			}
+			watermark, found, err := s.GetWatermark(migration)
+			if err != nil || !found {
+				t.Fatalf("no watermark stored: %v", err)
+			}
+			if tt.wantHeld {
+				if !watermark.Equal(held) {
+					t.Errorf("watermark %s, want %s", watermark, held)
+				}
+			} else if watermark.Before(started) {
+				t.Errorf("watermark %s, want the start of the run (%s)", watermark, started)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// System note handling values
+const (
+	SystemNotesLog  = "log"  // collected into one activity log comment (default)
+	SystemNotesSkip = "skip" // left out
+)
+
+// quoteLines is the number of lines of a comment quoted by a reply to it
+const quoteLines = 4
+
+// prepareComments readies the comments of a source issue for a tracker with
+// flat comments. Replies start with a quote of the comment they follow in
+// their thread, the first comment of a resolved thread says so, and system
+// notes are left out or, with SystemNotesLog, collected into an activity log
+// returned last. The log is a system comment with the ID of its first note,
+// so markers and resume records work as for any other comment.
+func prepareComments(comments []*TrackerComment, systemNotes string, source Provider, users *userMap) []*TrackerComment {
+	byID := make(map[int64]*TrackerComment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	prepared := make([]*TrackerComment, 0, len(comments))
+	var notes []*TrackerComment
+	for _, comment := range comments {
+		if comment.System {
+			notes = append(notes, comment)
+			continue
+		}
+		copied := *comment
+		if parent, ok := byID[comment.InReplyTo]; ok && !parent.System {
+			copied.Body = replyQuote(parent, source, users) + copied.Body
+		}
+		if comment.Resolved {
+			resolved := "✅ _Resolved thread_"
+			if comment.ResolvedBy != "" {
+				resolved = fmt.Sprintf("✅ _Thread resolved by %s_", users.credit(comment.ResolvedBy, source))
+			}
+			copied.Body = resolved + "\n\n" + copied.Body
+		}
+		prepared = append(prepared, &copied)
+	}
+
+	if len(notes) > 0 && systemNotes != SystemNotesSkip {
+		prepared = append(prepared, activityLog(notes, source, users))
+	}
+	return prepared
+}
+
+// replyQuote quotes the first lines of the comment a reply follows
+func replyQuote(parent *TrackerComment, source Provider, users *userMap) string {
+	lines := strings.Split(strings.TrimSpace(parent.Body), "\n")
+	if len(lines) > quoteLines {
+		lines = append(lines[:quoteLines], "…")
+	}
+	quote := fmt.Sprintf("> **%s** wrote:\n>\n", users.credit(parent.Author, source))
+	for _, line := range lines {
+		quote += strings.TrimRight("> "+line, " ") + "\n"
+	}
+	return quote + "\n"
+}
+
+// activityLog condenses system notes, such as label changes and mentions in
+// commits, into one comment with a line per note
+func activityLog(notes []*TrackerComment, source Provider, users *userMap) *TrackerComment {
+	var log strings.Builder
+	for _, note := range notes {
+		text := strings.Join(strings.Fields(note.Body), " ")
+		fmt.Fprintf(&log, "- %s %s %s\n", note.CreatedAt.Format("2006-01-02 15:04 UTC"), users.credit(note.Author, source), text)
+	}
+	first, last := notes[0], notes[len(notes)-1]
+	return &TrackerComment{
+		ID:        first.ID,
+		Body:      log.String(),
+		URL:       first.URL,
+		CreatedAt: first.CreatedAt,
+		UpdatedAt: last.UpdatedAt,
+		System:    true,
+	}
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/issue-migrator/backend/models"
+)
+
+// Translation rules for the GitLab-only weight, health status and epic of
+// issues copied to other trackers
+const (
+	TranslateHeader      = "header"       // listed in the migration header (default)
+	TranslateLabel       = "label"        // added as a "weight: 3" label
+	TranslateFrontMatter = "front_matter" // written as a front matter field at the top of the body
+	TranslateNone        = "none"         // dropped
+)
+
+// Translation rules for GitLab scoped labels copied to other trackers
+const (
+	ScopedKeep    = "keep"    // copied unchanged (default)
+	ScopedFlatten = "flatten" // "priority::high" becomes "priority: high"
+	ScopedValue   = "value"   // "priority::high" becomes "high"
+)
+
+// frontMatterRegex matches a front matter block at the top of a body
+var frontMatterRegex = regexp.MustCompile(`\A---\r?\n((?:.*\r?\n)*?)---\r?\n(?:\r?\n)?`)
+
+// translator carries GitLab-only issue fields to trackers without them and
+// back, following the translation rules of a request. Labels are renamed
+// through the label mapping before the rules apply.
+type translator struct {
+	rules  models.TranslationRules
+	labels labelMap
+	// write is set for targets without the GitLab fields, read for GitLab
+	// targets of other trackers, where labels and front matter written by
+	// the rules are turned back into fields
+	write bool
+	read  bool
+}
+
+func newTranslator(req models.MigrationRequest, source Provider, target Provider) *translator {
+	rules := req.Translation
+	if rules.ScopedLabels == "" {
+		rules.ScopedLabels = ScopedKeep
+	}
+	for _, rule := range []*string{&rules.Weight, &rules.HealthStatus, &rules.Epic} {
+		if *rule == "" {
+			*rule = TranslateHeader
+		}
+	}
+	return &translator{
+		rules:  rules,
+		labels: newLabelMap(req.LabelMapping),
+		write:  target.Platform() != "gitlab",
+		read:   target.Platform() == "gitlab" && source.Platform() != "gitlab",
+	}
+}
+
+// validateTranslation checks the rule values of a request
+func validateTranslation(rules models.TranslationRules) error {
+	switch rules.ScopedLabels {
+	case "", ScopedKeep, ScopedFlatten, ScopedValue:
+	default:
+		return fmt.Errorf("unknown scoped_labels rule %q", rules.ScopedLabels)
+	}
+	fields := map[string]string{"weight": rules.Weight, "health_status": rules.HealthStatus, "epic": rules.Epic}
+	for field, rule := range fields {
+		switch rule {
+		case "", TranslateHeader, TranslateLabel, TranslateFrontMatter, TranslateNone:
+		default:
+			return fmt.Errorf("unknown %s rule %q", field, rule)
+		}
+	}
+	return nil
+}
+
+// translate returns a copy of a source issue with its labels and GitLab-only
+// fields rewritten for the target, and the front matter to put at the top of
+// the target body
+func (t *translator) translate(issue *TrackerIssue) (*TrackerIssue, string) {
+	translated := *issue
+	translated.Labels = t.labels.rename(issue.Labels)
+	switch {
+	case t.write:
+		return &translated, t.writeFields(&translated)
+	case t.read:
+		t.readFields(&translated)
+	}
+	return &translated, ""
+}
+
+// labelName returns the name a source label gets on the target, as translate
+// names the labels of issues
+func (t *translator) labelName(label string) string {
+	label = t.labels.name(label)
+	switch {
+	case t.write:
+		label = t.scopedLabel(label)
+	case t.read && t.rules.ScopedLabels == ScopedFlatten:
+		label = scopeLabel(label)
+	}
+	return label
+}
+
+// writeFields moves the weight, health status and epic of an issue to labels
+// or front matter and rewrites its scoped labels. Fields that are not listed
+// in the migration header are cleared.
+func (t *translator) writeFields(issue *TrackerIssue) string {
+	if issue.Labels != nil {
+		labels := make([]string, 0, len(issue.Labels))
+		for _, label := range issue.Labels {
+			labels = append(labels, t.scopedLabel(label))
+		}
+		issue.Labels = labelMap(nil).rename(labels)
+	}
+
+	var frontMatter []string
+	if issue.Weight != nil {
+		value := strconv.Itoa(*issue.Weight)
+		frontMatter = writeField(issue, t.rules.Weight, "weight: "+value, "weight: "+value, frontMatter)
+		if t.rules.Weight != TranslateHeader {
+			issue.Weight = nil
+		}
+	}
+	if issue.HealthStatus != "" {
+		label := "health: " + strings.ReplaceAll(issue.HealthStatus, "_", " ")
+		frontMatter = writeField(issue, t.rules.HealthStatus, label, "health_status: "+issue.HealthStatus, frontMatter)
+		if t.rules.HealthStatus != TranslateHeader {
+			issue.HealthStatus = ""
+		}
+	}
+	if issue.Epic != "" {
+		frontMatter = writeField(issue, t.rules.Epic, "epic: "+issue.Epic, "epic: "+strconv.Quote(issue.Epic), frontMatter)
+		if t.rules.Epic != TranslateHeader {
+			issue.Epic = ""
+		}
+	}
+
+	if len(frontMatter) == 0 {
+		return ""
+	}
+	return "---\n" + strings.Join(frontMatter, "\n") + "\n---\n\n"
+}
+
+// writeField adds a field to the labels of an issue or to the front matter
+// lines, depending on its rule
+func writeField(issue *TrackerIssue, rule string, label string, line string, frontMatter []string) []string {
+	switch rule {
+	case TranslateLabel:
+		issue.Labels = append(issue.Labels, label)
+	case TranslateFrontMatter:
+		frontMatter = append(frontMatter, line)
+	}
+	return frontMatter
+}
+
+// scopedLabel rewrites a GitLab scoped label for a tracker without them
+func (t *translator) scopedLabel(label string) string {
+	i := strings.LastIndex(label, "::")
+	if i <= 0 || i+2 == len(label) {
+		return label
+	}
+	switch t.rules.ScopedLabels {
+	case ScopedFlatten:
+		return label[:i] + ": " + label[i+2:]
+	case ScopedValue:
+		return label[i+2:]
+	}
+	return label
+}
+
+// readFields reverses writeFields for an issue copied to GitLab from another
+// tracker: labels and front matter fields written by the rules set the
+// weight, health status and epic again, and flattened labels become scoped
+// labels. Labels and front matter of rules that are not in use are kept.
+func (t *translator) readFields(issue *TrackerIssue) {
+	if body, fields, ok := parseFrontMatter(issue.Body); ok {
+		used := false
+		if value, ok := fields["weight"]; ok && t.rules.Weight == TranslateFrontMatter {
+			used = setWeight(issue, value) || used
+		}
+		if value, ok := fields["health_status"]; ok && t.rules.HealthStatus == TranslateFrontMatter {
+			issue.HealthStatus, used = healthStatus(value), true
+		}
+		if value, ok := fields["epic"]; ok && t.rules.Epic == TranslateFrontMatter {
+			issue.Epic, used = value, true
+		}
+		if used {
+			issue.Body = body
+		}
+	}
+
+	if issue.Labels == nil {
+		return
+	}
+	labels := make([]string, 0, len(issue.Labels))
+	for _, label := range issue.Labels {
+		key, value, _ := strings.Cut(label, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case strings.EqualFold(key, "weight") && t.rules.Weight == TranslateLabel && setWeight(issue, value):
+			continue
+		case strings.EqualFold(key, "health") && t.rules.HealthStatus == TranslateLabel && value != "":
+			issue.HealthStatus = healthStatus(value)
+			continue
+		case strings.EqualFold(key, "epic") && t.rules.Epic == TranslateLabel && value != "":
+			issue.Epic = value
+			continue
+		case t.rules.ScopedLabels == ScopedFlatten:
+			label = scopeLabel(label)
+		}
+		labels = append(labels, label)
+	}
+	issue.Labels = labels
+}
+
+// scopeLabel turns a flattened label such as "priority: high" back into a
+// scoped label
+func scopeLabel(label string) string {
+	i := strings.LastIndex(label, ":")
+	if i <= 0 || strings.HasSuffix(label[:i], ":") {
+		return label
+	}
+	scope, value := strings.TrimSpace(label[:i]), strings.TrimSpace(label[i+1:])
+	if scope == "" || value == "" {
+		return label
+	}
+	return scope + "::" + value
+}
+
+// parseFrontMatter splits the front matter off the top of a body and returns
+// its "key: value" fields; quoted values are unquoted
+func parseFrontMatter(body string) (string, map[string]string, bool) {
+	match := frontMatterRegex.FindStringSubmatchIndex(body)
+	if match == nil {
+		return body, nil, false
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(body[match[2]:match[3]], "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return body[match[1]:], fields, true
+}
+
+// setWeight sets the weight of an issue from a label or front matter value
+func setWeight(issue *TrackerIssue, value string) bool {
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 0 {
+		return false
+	}
+	issue.Weight = &weight
+	return true
+}
+
+// healthStatus converts a health status written by the rules, e.g. "at risk",
+// back to its GitLab value
+func healthStatus(value string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), " ", "_")
+}
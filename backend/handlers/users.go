@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/markdown"
+	"github.com/issue-migrator/backend/models"
+)
+
+// mentionRegex matches @username mentions; the character before the "@"
+// keeps email addresses and paths from matching
+var mentionRegex = regexp.MustCompile("(^|[^\\w@/.`])@([A-Za-z0-9](?:[\\w.-]*\\w)?)")
+
+// userMap translates source usernames to target usernames, taken from the
+// request's mapping or matched by email address. A nil userMap maps nobody.
+type userMap struct {
+	users map[string]string // lower-case source username -> target username, "" if unmatched
+	// source and target are set when users are matched by email
+	source    userDirectory
+	target    userDirectory
+	missing   map[string]bool // source authors and assignees without a target user
+	mentioned map[string]bool // @mentioned source users without a target user
+	// impersonator is set when issues and comments are written as their
+	// mapped authors
+	impersonator impersonator
+}
+
+func newUserMap(req models.MigrationRequest, source Provider, target Provider) *userMap {
+	m := &userMap{
+		users:     make(map[string]string),
+		missing:   make(map[string]bool),
+		mentioned: make(map[string]bool),
+	}
+	for from, to := range req.UserMapping {
+		from = strings.TrimPrefix(strings.TrimSpace(from), "@")
+		to = strings.TrimPrefix(strings.TrimSpace(to), "@")
+		if from != "" && to != "" {
+			m.users[strings.ToLower(from)] = to
+		}
+	}
+
+	if req.MatchUsersByEmail {
+		sourceDirectory, sourceOK := source.(userDirectory)
+		targetDirectory, targetOK := target.(userDirectory)
+		if sourceOK && targetOK {
+			m.source, m.target = sourceDirectory, targetDirectory
+		} else {
+			fmt.Printf("[WARNING] Users cannot be matched by email between %s and %s\n", source.Name(), target.Name())
+		}
+	}
+
+	if impersonator, ok := target.(impersonator); ok && (len(m.users) > 0 || m.source != nil) && impersonator.CanImpersonate() {
+		fmt.Printf("[USERS] The %s token can act as other users; writing issues and comments as their mapped authors\n", target.Name())
+		m.impersonator = impersonator
+	}
+	return m
+}
+
+// lookup returns the target username of a source user
+func (m *userMap) lookup(user string) (string, bool) {
+	if m == nil || user == "" {
+		return "", false
+	}
+	key := strings.ToLower(user)
+	target, ok := m.users[key]
+	if !ok && m.source != nil {
+		target = m.matchEmail(user)
+		m.users[key] = target
+	}
+	return target, target != ""
+}
+
+// matchEmail finds the target user with the email address of a source user
+func (m *userMap) matchEmail(user string) string {
+	email, err := m.source.UserEmail(user)
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to look up the email address of %s: %v\n", user, err)
+		return ""
+	}
+	if email == "" {
+		return ""
+	}
+	target, err := m.target.FindUserByEmail(email)
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to find a user with the email address of %s: %v\n", user, err)
+		return ""
+	}
+	if target != "" {
+		fmt.Printf("[USERS] Matched %s to %s by email\n", user, target)
+	}
+	return target
+}
+
+// require looks up an author or assignee and remembers it if it has no target user
+func (m *userMap) require(user string) (string, bool) {
+	target, ok := m.lookup(user)
+	if !ok && m != nil && user != "" {
+		m.missing[user] = true
+	}
+	return target, ok
+}
+
+// credit names a source user in migration headers: the target user followed
+// by the source username, or the source username alone if it is not mapped
+func (m *userMap) credit(user string, source Provider) string {
+	target, ok := m.require(user)
+	if !ok {
+		return "@" + user
+	}
+	if strings.EqualFold(target, user) {
+		return "@" + target
+	}
+	return fmt.Sprintf("@%s (%s on %s)", target, user, source.Name())
+}
+
+// assignees returns the target users of the assignees of a source issue.
+// Assignees without a target user are left out; nil is returned if none remain.
+func (m *userMap) assignees(users []string) []string {
+	var assignees []string
+	for _, user := range users {
+		if target, ok := m.require(user); ok {
+			assignees = append(assignees, target)
+		}
+	}
+	return assignees
+}
+
+// createIssue creates a target issue as the mapped author of the source
+// issue if the target can impersonate users, and as the token owner otherwise
+func (m *userMap) createIssue(target Provider, issue *TrackerIssue, input IssueInput) (*TrackerIssue, error) {
+	if author, ok := m.lookup(issue.Author); ok && m.impersonator != nil {
+		return m.impersonator.CreateIssueAs(author, issue.CreatedAt, input)
+	}
+	return target.CreateIssue(input)
+}
+
+// addComment adds a comment as the mapped author of the source comment like createIssue
+func (m *userMap) addComment(target Provider, number int, comment *TrackerComment, body string) (*TrackerComment, error) {
+	if author, ok := m.lookup(comment.Author); ok && m.impersonator != nil {
+		return m.impersonator.AddCommentAs(number, author, comment.CreatedAt, body)
+	}
+	return target.AddComment(number, body)
+}
+
+// rewriteMentions replaces @mentions of mapped users outside of code and
+// remembers the mentioned users without a target user
+func (m *userMap) rewriteMentions(body string) string {
+	if m == nil || body == "" {
+		return body
+	}
+	return markdown.ReplaceText(body, func(text string) string {
+		return mentionRegex.ReplaceAllStringFunc(text, func(match string) string {
+			groups := mentionRegex.FindStringSubmatch(match)
+			target, ok := m.lookup(groups[2])
+			if !ok {
+				m.mentioned[groups[2]] = true
+				return match
+			}
+			return groups[1] + "@" + target
+		})
+	})
+}
+
+// unmapped returns the source authors and assignees without a target user
+func (m *userMap) unmapped() []string {
+	if m == nil {
+		return []string{}
+	}
+	return sortedUsers(m.missing)
+}
+
+// unmappedMentions returns the @mentioned source users without a target user
+// that are not authors or assignees as well
+func (m *userMap) unmappedMentions() []string {
+	if m == nil {
+		return []string{}
+	}
+	mentioned := make(map[string]bool)
+	for user := range m.mentioned {
+		if !m.missing[user] {
+			mentioned[user] = true
+		}
+	}
+	return sortedUsers(mentioned)
+}
+
+// sortedUsers returns the users of a set in order
+func sortedUsers(set map[string]bool) []string {
+	users := []string{}
+	for user := range set {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// report warns about the users without a target user and lists them on the result
+func (m *userMap) report(result *models.MigrationResult) {
+	users := m.unmapped()
+	mentions := m.unmappedMentions()
+	if len(users) > 0 {
+		fmt.Printf("[WARNING] %d user(s) have no target user and are only credited by name: %s\n",
+			len(users), strings.Join(users, ", "))
+		result.UnmappedUsers = users
+	}
+	if len(mentions) > 0 {
+		fmt.Printf("[WARNING] %d mentioned user(s) have no target user and keep their source username: %s\n",
+			len(mentions), strings.Join(mentions, ", "))
+		result.UnmappedMentions = mentions
+	}
+	if len(users) > 0 || len(mentions) > 0 {
+		fmt.Printf("[INFO] Add them to user_mapping to assign issues to them and rewrite their mentions\n")
+	}
+}
+
+// ParseUserMapping converts an uploaded user mapping (multipart field "file")
+// into the user_mapping of a migration request. CSV files have a source and
+// a target username per row; JSON files hold an object from source to target
+// usernames or a list of {"source", "target"} objects.
+func ParseUserMapping(c *gin.Context) {
+	mapping, filename, ok := readMappingUpload(c)
+	if !ok {
+		return
+	}
+	fmt.Printf("[USERS] Parsed a mapping of %d user(s) from %s\n", len(mapping), filename)
+	c.JSON(http.StatusOK, gin.H{
+		"user_mapping": mapping,
+		"count":        len(mapping),
+	})
+}
+
+// readMappingUpload parses the mapping file uploaded as the multipart field
+// "file" and returns it with the file name. It responds with an error and
+// returns false if the file is missing or invalid.
+func readMappingUpload(c *gin.Context) (map[string]string, string, bool) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return nil, "", false
+	}
+	reader, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	mapping, err := parseMapping(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping: " + err.Error()})
+		return nil, "", false
+	}
+	return mapping, file.Filename, true
+}
+
+// parseMapping reads a mapping from source to target names in JSON or CSV form
+func parseMapping(data []byte) (map[string]string, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\xef\xbb\xbf"))
+	mapping := make(map[string]string)
+
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		if err := json.Unmarshal(data, &mapping); err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(data, []byte("[")):
+		var entries []struct {
+			Source string `json:"source"`
+			Target string `json:"target"`
+		}
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			mapping[entry.Source] = entry.Target
+		}
+	default:
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			if len(row) < 2 {
+				return nil, fmt.Errorf("row %d needs a source and a target name", i+1)
+			}
+			if i == 0 && strings.EqualFold(row[0], "source") {
+				continue // header
+			}
+			mapping[row[0]] = row[1]
+		}
+	}
+
+	trimmed := make(map[string]string, len(mapping))
+	for source, target := range mapping {
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if source != "" && target != "" {
+			trimmed[source] = target
+		}
+	}
+	return trimmed, nil
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v57/github"
+	"github.com/issue-migrator/backend/models"
+	"github.com/xanzy/go-gitlab"
+)
+
+// GitHubWebhook receives issue and comment events from GitHub and mirrors the
+// affected issue to GitLab. The payload must be signed with the webhook secret
+// of a mirror registered for the repository.
+func GitHubWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	eventType := github.WebHookType(c.Request)
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var repo *github.Repository
+	var number int
+	switch e := event.(type) {
+	case *github.IssuesEvent:
+		repo, number = e.GetRepo(), e.GetIssue().GetNumber()
+	case *github.IssueCommentEvent:
+		if e.GetIssue().IsPullRequest() {
+			c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+			return
+		}
+		if hasMarker(e.GetComment().GetBody()) {
+			// Comment written by the migrator itself
+			c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+			return
+		}
+		repo, number = e.GetRepo(), e.GetIssue().GetNumber()
+	case *github.PingEvent:
+		c.JSON(http.StatusOK, gin.H{"status": "pong"})
+		return
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	signature := c.GetHeader(github.SHA256SignatureHeader)
+	if signature == "" {
+		signature = c.GetHeader(github.SHA1SignatureHeader)
+	}
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	matched := webhookMirrors(func(mirror models.MirrorConfig) bool {
+		return strings.EqualFold(mirror.GitHub.Owner, owner) && strings.EqualFold(mirror.GitHub.Repo, name) &&
+			github.ValidateSignature(signature, payload, []byte(mirror.WebhookSecret)) == nil
+	})
+	if len(matched) == 0 {
+		fmt.Printf("[WEBHOOK] Rejected GitHub %s event for %s/%s: no mirror with a matching secret\n", eventType, owner, name)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	fmt.Printf("[WEBHOOK] GitHub %s event for %s/%s#%d (delivery %s)\n", eventType, owner, name, number, github.DeliveryID(c.Request))
+	jobIDs := make([]string, 0, len(matched))
+	for _, mirror := range matched {
+		jobID, err := startWebhookRun(mirror, "github", number)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to start mirror %s: %v\n", mirror.ID, err)
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"job_ids": jobIDs})
+}
+
+// GitLabWebhook receives issue and note events from GitLab and mirrors the
+// affected issue to GitHub. The X-Gitlab-Token header must match the webhook
+// secret of a mirror registered for the project.
+func GitLabWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	eventType := gitlab.HookEventType(c.Request)
+	event, err := gitlab.ParseWebhook(eventType, payload)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	var projectID, iid int
+	switch e := event.(type) {
+	case *gitlab.IssueEvent:
+		projectID, iid = e.Project.ID, e.ObjectAttributes.IID
+	case *gitlab.IssueCommentEvent:
+		if e.ObjectAttributes.System || hasMarker(e.ObjectAttributes.Note) {
+			// System note or note written by the migrator itself
+			c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+			return
+		}
+		projectID, iid = e.ProjectID, e.Issue.IID
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	token := c.GetHeader("X-Gitlab-Token")
+	matched := webhookMirrors(func(mirror models.MirrorConfig) bool {
+		return mirror.GitLab.ProjectID == projectID &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(mirror.WebhookSecret)) == 1
+	})
+	if len(matched) == 0 {
+		fmt.Printf("[WEBHOOK] Rejected GitLab %s for project %d: no mirror with a matching secret\n", eventType, projectID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	fmt.Printf("[WEBHOOK] GitLab %s for project %d issue #%d\n", eventType, projectID, iid)
+	jobIDs := make([]string, 0, len(matched))
+	for _, mirror := range matched {
+		jobID, err := startWebhookRun(mirror, "gitlab", iid)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to start mirror %s: %v\n", mirror.ID, err)
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"job_ids": jobIDs})
+}
+
+// webhookMirrors returns the mirrors with a webhook secret that match the event
+func webhookMirrors(match func(models.MirrorConfig) bool) []models.MirrorConfig {
+	if stateStore == nil {
+		return nil
+	}
+	list, err := stateStore.ListMirrors()
+	if err != nil {
+		fmt.Printf("[WARNING] Failed to load mirrors: %v\n", err)
+		return nil
+	}
+	var matched []models.MirrorConfig
+	for _, mirror := range list {
+		if mirror.WebhookSecret != "" && match(mirror) {
+			matched = append(matched, mirror)
+		}
+	}
+	return matched
+}
+
+// startWebhookRun mirrors a single issue in the background. It waits for any
+// run of the same mirror to finish so that the mirror's own writes are recorded
+// before the events they trigger are processed.
+func startWebhookRun(mirror models.MirrorConfig, platform string, number int) (string, error) {
+	job := newJob(models.MigrationRequest{Direction: "mirror"})
+	run, err := newMirrorRun(&mirror, job)
+	if err != nil {
+		job.finish(models.MigrationResult{}, err)
+		return "", err
+	}
+
+	go func() {
+		lock := mirrors.lock(mirror.ID)
+		lock.Lock()
+		defer lock.Unlock()
+
+		runMigrationJob(job, models.MigrationRequest{}, func(models.MigrationRequest, *Job) models.MigrationResult {
+			run.mirrorIssue(platform, number)
+			return run.result
+		})
+	}()
+	return job.ID(), nil
+}
+
+// mirrorIssue fetches the current state of one issue and mirrors it
+func (r *mirrorRun) mirrorIssue(platform string, number int) {
+	side := r.side(platform)
+	issue, err := side.GetIssue(number)
+	if err != nil {
+		r.failIssue(side, number, err)
+		return
+	}
+	r.handleIssue(side, issue)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/issue-migrator/backend/models"
+)
+
+const testWebhookSecret = "s3cret"
+
+// useWebhookMirror stores a mirror with a webhook secret. Its GitLab side has
+// no base URL, so accepted events do not start a run that contacts a tracker.
+func useWebhookMirror(t *testing.T) {
+	t.Helper()
+	s := useTestStore(t)
+	mirror := models.MirrorConfig{
+		ID:            "mirror",
+		GitHub:        models.Endpoint{Type: "github", Owner: "owner", Repo: "repo"},
+		GitLab:        models.Endpoint{Type: "gitlab", ProjectID: 7},
+		WebhookSecret: testWebhookSecret,
+	}
+	if err := s.PutMirror(mirror); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveWebhook sends a webhook request to a handler and returns the response status
+func serveWebhook(handler gin.HandlerFunc, payload string, headers map[string]string) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhook", handler)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func githubSignature(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubWebhookSignature(t *testing.T) {
+	issueEvent := `{"action":"edited","issue":{"number":1},"repository":{"name":"repo","owner":{"login":"owner"}}}`
+	otherRepo := `{"action":"edited","issue":{"number":1},"repository":{"name":"other","owner":{"login":"owner"}}}`
+	markedComment := `{"action":"created","issue":{"number":1},"comment":{"body":"` + "<!-- issue-migrator:comment=gitlab:group/project#5/9 -->" + `"},"repository":{"name":"repo","owner":{"login":"owner"}}}`
+
+	tests := []struct {
+		name      string
+		event     string
+		payload   string
+		signature string
+		want      int
+	}{
+		{"valid signature", "issues", issueEvent, githubSignature(testWebhookSecret, issueEvent), http.StatusAccepted},
+		{"wrong secret", "issues", issueEvent, githubSignature("other", issueEvent), http.StatusUnauthorized},
+		{"missing signature", "issues", issueEvent, "", http.StatusUnauthorized},
+		{"signature of another payload", "issues", issueEvent, githubSignature(testWebhookSecret, otherRepo), http.StatusUnauthorized},
+		{"repository without a mirror", "issues", otherRepo, githubSignature(testWebhookSecret, otherRepo), http.StatusUnauthorized},
+		{"comment written by the migrator", "issue_comment", markedComment, "", http.StatusOK},
+		{"ping", "ping", `{"zen":"hi"}`, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useWebhookMirror(t)
+			headers := map[string]string{"X-GitHub-Event": tt.event}
+			if tt.signature != "" {
+				headers["X-Hub-Signature-256"] = tt.signature
+			}
+			if got := serveWebhook(GitHubWebhook, tt.payload, headers); got != tt.want {
+				t.Errorf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitLabWebhookToken(t *testing.T) {
+	issueEvent := `{"object_kind":"issue","project":{"id":7},"object_attributes":{"iid":5}}`
+	otherProject := `{"object_kind":"issue","project":{"id":8},"object_attributes":{"iid":5}}`
+	note := `{"object_kind":"note","project_id":7,"object_attributes":{"note":"Same here","noteable_type":"Issue"},"issue":{"iid":5}}`
+	systemNote := `{"object_kind":"note","project_id":7,"object_attributes":{"note":"changed the title","system":true,"noteable_type":"Issue"},"issue":{"iid":5}}`
+
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		token   string
+		want    int
+	}{
+		{"valid token", "Issue Hook", issueEvent, testWebhookSecret, http.StatusAccepted},
+		{"wrong token", "Issue Hook", issueEvent, "other", http.StatusUnauthorized},
+		{"missing token", "Issue Hook", issueEvent, "", http.StatusUnauthorized},
+		{"project without a mirror", "Issue Hook", otherProject, testWebhookSecret, http.StatusUnauthorized},
+		{"note", "Note Hook", note, testWebhookSecret, http.StatusAccepted},
+		{"system note", "Note Hook", systemNote, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useWebhookMirror(t)
+			headers := map[string]string{"X-Gitlab-Event": tt.event}
+			if tt.token != "" {
+				headers["X-Gitlab-Token"] = tt.token
+			}
+			if got := serveWebhook(GitLabWebhook, tt.payload, headers); got != tt.want {
+				t.Errorf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
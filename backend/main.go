@@ -7,6 +7,7 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/issue-migrator/backend/handlers"
+	"github.com/issue-migrator/backend/store"
 	"github.com/joho/godotenv"
 )
 
@@ -16,6 +17,20 @@ func main() {
 		fmt.Println("[INFO] No .env file found")
 	}
 
+	statePath := os.Getenv("STATE_DB_PATH")
+	if statePath == "" {
+		statePath = "migrator-state.db"
+	}
+	stateStore, err := store.Open(statePath)
+	if err != nil {
+		fmt.Printf("[WARNING] Migration state will not be persisted: %v\n", err)
+	} else {
+		defer stateStore.Close()
+		handlers.SetStateStore(stateStore)
+		fmt.Printf("[INFO] Migration state stored in %s\n", statePath)
+		handlers.StartMirrors()
+	}
+
 	// Keep Gin in debug mode to see all logs
 	// gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
@@ -30,7 +45,7 @@ func main() {
 
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:3000"}
-	config.AllowMethods = []string{"GET", "POST", "OPTIONS"}
+	config.AllowMethods = []string{"GET", "POST", "DELETE", "OPTIONS"}
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
 	r.Use(cors.New(config))
 
@@ -39,7 +54,25 @@ func main() {
 		api.GET("/health", handlers.HealthCheck)
 		api.POST("/github/issues", handlers.GetGitHubIssues)
 		api.POST("/gitlab/issues", handlers.GetGitLabIssues)
-		api.POST("/migrate", handlers.MigrateWithFiles) // Version with full file support
+		api.POST("/gitea/issues", handlers.GetGiteaIssues)
+		api.POST("/jira/issues", handlers.GetJiraIssues)
+		api.POST("/bitbucket/issues", handlers.GetBitbucketIssues)
+		api.POST("/azure/issues", handlers.GetAzureIssues)
+		api.POST("/archive/issues", handlers.GetArchiveIssues)
+		api.POST("/archives", handlers.UploadArchive)   // stores an uploaded archive for an "archive" source
+		api.POST("/migrate", handlers.MigrateWithFiles) // Version with full file support, runs as a background job
+		api.POST("/export", handlers.ExportIssues)      // writes an offline archive in a background job
+		api.GET("/exports/:id", handlers.DownloadExport)
+		api.POST("/user-mapping", handlers.ParseUserMapping)
+		api.POST("/label-mapping", handlers.ParseLabelMapping)
+		api.GET("/jobs/:id", handlers.GetJob)
+		api.GET("/jobs/:id/events", handlers.StreamJobEvents)
+		api.POST("/mirrors", handlers.CreateMirror)
+		api.GET("/mirrors", handlers.ListMirrors)
+		api.DELETE("/mirrors/:id", handlers.DeleteMirror)
+		api.POST("/mirrors/:id/run", handlers.RunMirror)
+		api.POST("/webhooks/github", handlers.GitHubWebhook)
+		api.POST("/webhooks/gitlab", handlers.GitLabWebhook)
 	}
 
 	port := os.Getenv("PORT")
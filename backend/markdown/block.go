@@ -0,0 +1,357 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	atxHeadingRegex    = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]|$)`)
+	setextRegex        = regexp.MustCompile(`^ {0,3}(?:=+|-+)[ \t]*$`)
+	thematicBreakRegex = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRegex         = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})([^`]*)$")
+	quoteRegex         = regexp.MustCompile(`^ {0,3}> ?`)
+	multiQuoteRegex    = regexp.MustCompile(`^ {0,3}>>>[ \t]*$`)
+	listItemRegex      = regexp.MustCompile(`^( {0,3})([-+*]|\d{1,9}[.)])([ \t]+|$)`)
+	tableDelimiter     = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	definitionRegex    = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:[ \t]*(<[^<>]*>|\S+)((?:[ \t]+(?:"[^"]*"|'[^']*'|\([^()]*\)))?[ \t]*)$`)
+	htmlBlockRegex     = regexp.MustCompile(`^ {0,3}</?([A-Za-z][A-Za-z0-9-]*)(?:[\s/>]|$)`)
+	htmlTagLineRegex   = regexp.MustCompile(`^ {0,3}(?:<[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][\w.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>)[ \t]*$`)
+)
+
+// htmlBlockTags are the tags that start an HTML block even inside a paragraph
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"caption": true, "center": true, "dd": true, "details": true, "dialog": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true,
+	"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"summary": true, "table": true, "tbody": true, "td": true, "tfoot": true,
+	"th": true, "thead": true, "tr": true, "ul": true,
+}
+
+// Parse parses a Markdown text written in the given dialect
+func Parse(source string, dialect Dialect) *Document {
+	doc := &Document{Dialect: dialect}
+	if source == "" {
+		return doc
+	}
+	doc.newline = strings.HasSuffix(source, "\n")
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	doc.Blocks = (&parser{dialect: dialect}).blocks(lines)
+	return doc
+}
+
+type parser struct {
+	dialect Dialect
+}
+
+// blocks parses the content lines of a document or container
+func (p *parser) blocks(lines []string) []*Block {
+	var blocks []*Block
+	for i := 0; i < len(lines); {
+		block := p.block(lines[i:])
+		blocks = append(blocks, block)
+		i += block.span
+	}
+	return blocks
+}
+
+// block parses the block starting at the first line
+func (p *parser) block(lines []string) *Block {
+	line := lines[0]
+	switch {
+	case isBlank(line):
+		return leaf(Blank, lines[:1])
+	case p.dialect == GLFM && multiQuoteRegex.MatchString(line):
+		return p.multiQuote(lines)
+	case fenceRegex.MatchString(line):
+		return fencedCode(lines)
+	case isMathFence(line):
+		return mathBlock(lines)
+	case atxHeadingRegex.MatchString(line):
+		return leaf(Heading, lines[:1])
+	case thematicBreakRegex.MatchString(line):
+		return leaf(ThematicBreak, lines[:1])
+	case quoteRegex.MatchString(line):
+		return p.quote(lines)
+	case listItemRegex.MatchString(line):
+		return p.listItem(lines)
+	case indentation(line) >= 4:
+		return indentedCode(lines)
+	case startsHTMLBlock(line, false):
+		return htmlBlock(lines)
+	case definitionRegex.MatchString(line):
+		return leaf(Definition, lines[:1])
+	case len(lines) > 1 && strings.Contains(line, "|") && tableDelimiter.MatchString(lines[1]):
+		return table(lines)
+	}
+	return paragraph(lines)
+}
+
+func leaf(kind BlockKind, lines []string) *Block {
+	return &Block{Kind: kind, Lines: append([]string(nil), lines...), span: len(lines)}
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// indentation returns the width of the leading whitespace of a line, with
+// tabs counted as four columns
+func indentation(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// interrupts reports whether a line starts a block that ends a paragraph
+func interrupts(line string) bool {
+	if isBlank(line) || atxHeadingRegex.MatchString(line) || fenceRegex.MatchString(line) ||
+		isMathFence(line) || thematicBreakRegex.MatchString(line) || quoteRegex.MatchString(line) ||
+		startsHTMLBlock(line, true) {
+		return true
+	}
+	if match := listItemRegex.FindStringSubmatch(line); match != nil {
+		// Only items with content, and ordered lists starting at 1, interrupt
+		marker := match[2]
+		return !isBlank(line[len(match[0]):]) && (len(marker) == 1 || marker[:len(marker)-1] == "1")
+	}
+	return false
+}
+
+func paragraph(lines []string) *Block {
+	end := 1
+	for end < len(lines) {
+		if setextRegex.MatchString(lines[end]) {
+			return leaf(Heading, lines[:end+1])
+		}
+		if interrupts(lines[end]) {
+			break
+		}
+		end++
+	}
+	return leaf(Paragraph, lines[:end])
+}
+
+func table(lines []string) *Block {
+	end := 2
+	for end < len(lines) && !interrupts(lines[end]) {
+		end++
+	}
+	return leaf(Table, lines[:end])
+}
+
+func fencedCode(lines []string) *Block {
+	fence := strings.TrimLeft(fenceRegex.FindStringSubmatch(lines[0])[1], " ")
+	for end := 1; end < len(lines); end++ {
+		closing := strings.TrimSpace(lines[end])
+		if indentation(lines[end]) < 4 && len(closing) >= len(fence) && strings.Trim(closing, fence[:1]) == "" {
+			return leaf(CodeBlock, lines[:end+1])
+		}
+	}
+	// An unclosed fence runs to the end of its container
+	return leaf(CodeBlock, lines)
+}
+
+// isMathFence reports whether a line starts a "$$" display math block
+func isMathFence(line string) bool {
+	return indentation(line) < 4 && strings.HasPrefix(strings.TrimSpace(line), "$$")
+}
+
+func mathBlock(lines []string) *Block {
+	first := strings.TrimSpace(lines[0])
+	if len(first) > 4 && strings.HasSuffix(first, "$$") {
+		return leaf(MathBlock, lines[:1])
+	}
+	for end := 1; end < len(lines); end++ {
+		if strings.HasSuffix(strings.TrimSpace(lines[end]), "$$") {
+			return leaf(MathBlock, lines[:end+1])
+		}
+	}
+	return leaf(MathBlock, lines)
+}
+
+func indentedCode(lines []string) *Block {
+	end := 1
+	for i := 1; i < len(lines); i++ {
+		if isBlank(lines[i]) {
+			continue
+		}
+		if indentation(lines[i]) < 4 {
+			break
+		}
+		end = i + 1
+	}
+	return leaf(CodeBlock, lines[:end])
+}
+
+// startsHTMLBlock reports whether a line starts an HTML block; inside a
+// paragraph only comments and block-level tags do
+func startsHTMLBlock(line string, inParagraph bool) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if indentation(line) >= 4 {
+		return false
+	}
+	if strings.HasPrefix(trimmed, "<!--") {
+		return true
+	}
+	if match := htmlBlockRegex.FindStringSubmatch(line); match != nil && htmlBlockTags[strings.ToLower(match[1])] {
+		return true
+	}
+	return !inParagraph && htmlTagLineRegex.MatchString(line)
+}
+
+func htmlBlock(lines []string) *Block {
+	if strings.HasPrefix(strings.TrimLeft(lines[0], " "), "<!--") {
+		for end := 0; end < len(lines); end++ {
+			if strings.Contains(lines[end], "-->") {
+				return leaf(HTMLBlock, lines[:end+1])
+			}
+		}
+		return leaf(HTMLBlock, lines)
+	}
+	end := 1
+	for end < len(lines) && !isBlank(lines[end]) {
+		end++
+	}
+	return leaf(HTMLBlock, lines[:end])
+}
+
+// quote parses a block quote with its lazy continuation lines
+func (p *parser) quote(lines []string) *Block {
+	var content, prefixes []string
+	lazy := false
+	for _, line := range lines {
+		if match := quoteRegex.FindString(line); match != "" {
+			rest := line[len(match):]
+			content = append(content, rest)
+			if isBlank(rest) {
+				prefixes = append(prefixes, line)
+				content[len(content)-1] = ""
+			} else {
+				prefixes = append(prefixes, match)
+			}
+			lazy = !isBlank(rest) && !fenceRegex.MatchString(rest)
+			continue
+		}
+		if !lazy || interrupts(line) || listItemRegex.MatchString(line) {
+			break
+		}
+		content = append(content, line)
+		prefixes = append(prefixes, "")
+	}
+	block := &Block{Kind: BlockQuote, first: "> ", cont: "> ", span: len(content)}
+	block.Children = p.children(content, prefixes)
+	return block
+}
+
+// multiQuote parses a GitLab ">>>" multi-line block quote
+func (p *parser) multiQuote(lines []string) *Block {
+	block := &Block{Kind: BlockQuote, Open: lines[0]}
+	end := len(lines)
+	for i := 1; i < len(lines); i++ {
+		if multiQuoteRegex.MatchString(lines[i]) {
+			block.Close = lines[i]
+			end = i
+			break
+		}
+	}
+	block.span = end + 1
+	if block.Close == "" {
+		block.span = end
+	}
+	content := lines[1:end]
+	block.Children = p.children(content, make([]string, len(content)))
+	return block
+}
+
+// listItem parses a list item with the lines indented below it
+func (p *parser) listItem(lines []string) *Block {
+	match := listItemRegex.FindStringSubmatch(lines[0])
+	width := len(match[0])
+	if isBlank(lines[0][width:]) || indentation(match[3]) > 4 {
+		// Empty items and content indented as code start after one space
+		width = len(match[1]) + len(match[2]) + 1
+	}
+	indent := strings.Repeat(" ", width)
+	start := width
+	if start > len(lines[0]) {
+		start = len(lines[0])
+	}
+	content := []string{lines[0][start:]}
+	prefixes := []string{lines[0][:start]}
+
+	lazy := !isBlank(content[0])
+	end := 1
+scan:
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			content = append(content, "")
+			prefixes = append(prefixes, line)
+			lazy = false
+			continue
+		case indentation(line) >= width:
+			cut := columnOffset(line, width)
+			content = append(content, line[cut:])
+			prefixes = append(prefixes, line[:cut])
+			lazy = !fenceRegex.MatchString(line[cut:])
+		case lazy && !interrupts(line) && !listItemRegex.MatchString(line):
+			content = append(content, line)
+			prefixes = append(prefixes, "")
+		default:
+			break scan
+		}
+		end = i + 1
+	}
+	// Trailing blank lines belong to the enclosing container
+	content, prefixes = content[:end], prefixes[:end]
+
+	block := &Block{Kind: ListItem, Marker: match[2], first: match[1] + match[2] + indent[len(match[1])+len(match[2]):], cont: indent, span: end}
+	block.Children = p.children(content, prefixes)
+	return block
+}
+
+// columnOffset returns the byte offset of a column in the leading whitespace
+// of a line
+func columnOffset(line string, column int) int {
+	width := 0
+	for i, c := range line {
+		if width >= column {
+			return i
+		}
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return i
+		}
+	}
+	return len(line)
+}
+
+// children parses the content of a container and hands the prefixes of its
+// lines to the child blocks
+func (p *parser) children(content []string, prefixes []string) []*Block {
+	children := p.blocks(content)
+	line := 0
+	for _, child := range children {
+		child.prefixes = prefixes[line : line+child.span]
+		line += child.span
+	}
+	return children
+}
@@ -0,0 +1,454 @@
+package markdown
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	tocRegex          = regexp.MustCompile(`(?i)^[ \t]*(?:\[\[_toc_\]\]|\[toc\])[ \t]*$`)
+	quickActionRegex  = regexp.MustCompile(`^([ \t]*)/([a-z_]+)(?:[ \t].*)?$`)
+	alertRegex        = regexp.MustCompile(`(?i)^[ \t]*\[!(note|tip|important|warning|caution)\][ \t]*$`)
+	inapplicableRegex = regexp.MustCompile(`^\[~\]([ \t]+)(.*)$`)
+	atxMarkerRegex    = regexp.MustCompile(`^ {0,3}#{1,6}[ \t]*|[ \t]+#+[ \t]*$`)
+	videoRegex        = regexp.MustCompile(`(?i)\.(?:mp4|m4v|mov|webm|ogv)$`)
+)
+
+// quickActions are the GitLab quick actions; GitLab runs them when a line of
+// a new issue or comment starts with one
+var quickActions = map[string]bool{
+	"add_contacts": true, "approve": true, "assign": true, "assign_reviewer": true,
+	"award": true, "blocked_by": true, "blocks": true, "cc": true, "child_epic": true,
+	"clone": true, "close": true, "confidential": true, "copy_metadata": true,
+	"create_merge_request": true, "done": true, "draft": true, "due": true,
+	"duplicate": true, "epic": true, "estimate": true, "health_status": true,
+	"iteration": true, "label": true, "lock": true, "merge": true, "milestone": true,
+	"move": true, "parent_epic": true, "promote": true, "promote_to_incident": true,
+	"publish": true, "react": true, "ready": true, "rebase": true, "relabel": true,
+	"relate": true, "remove_child_epic": true, "remove_contacts": true,
+	"remove_due_date": true, "remove_epic": true, "remove_estimate": true,
+	"remove_health_status": true, "remove_iteration": true, "remove_milestone": true,
+	"remove_parent": true, "remove_parent_epic": true, "remove_time_spent": true,
+	"remove_weight": true, "reopen": true, "reviewer": true, "set_parent": true,
+	"severity": true, "shrug": true, "spend": true, "status": true,
+	"submit_review": true, "subscribe": true, "tableflip": true, "target_branch": true,
+	"timeline": true, "title": true, "todo": true, "type": true, "unapprove": true,
+	"unassign": true, "unassign_reviewer": true, "unlabel": true, "unlink": true,
+	"unlock": true, "unsubscribe": true, "weight": true,
+}
+
+// alertTitles are the GitHub alert types with the title they get on GitLab
+var alertTitles = map[string]string{
+	"note":      "ℹ️ **Note**",
+	"tip":       "💡 **Tip**",
+	"important": "❗ **Important**",
+	"warning":   "⚠️ **Warning**",
+	"caution":   "🛑 **Caution**",
+}
+
+// Convert translates a Markdown text from one dialect to another. Texts in
+// the same dialect are returned unchanged.
+func Convert(source string, from Dialect, to Dialect) string {
+	if from == to || strings.TrimSpace(source) == "" {
+		return source
+	}
+	doc := Parse(source, from)
+	switch to {
+	case GFM:
+		toGitHub(doc)
+	case GLFM:
+		toGitLab(doc)
+	}
+	doc.Dialect = to
+	return doc.String()
+}
+
+// toGitHub rewrites GitLab-only syntax: ">>>" quotes become "> " quotes, the
+// table of contents is written out, quick actions become code, inapplicable
+// tasks are struck through and embedded videos become links
+func toGitHub(doc *Document) {
+	doc.Blocks = splitDetails(doc.Blocks, GLFM)
+	headings := collectHeadings(doc)
+	doc.Walk(func(b *Block) {
+		switch b.Kind {
+		case BlockQuote:
+			if b.Open != "" {
+				flattenQuote(b)
+			}
+		case ListItem:
+			strikeInapplicableTask(b)
+		case Paragraph:
+			if len(b.Lines) == 1 && tocRegex.MatchString(b.Lines[0]) {
+				b.Lines = tableOfContents(headings)
+				return
+			}
+			for i, line := range b.Lines {
+				if match := quickActionRegex.FindStringSubmatch(line); match != nil && quickActions[match[2]] {
+					b.Lines[i] = match[1] + codeSpanOf(strings.TrimLeft(line, " \t"))
+				}
+			}
+			convertInlines(b, func(nodes []*Inline) []*Inline {
+				return linkVideos(renameInlineEmoji(nodes, gitlabEmoji))
+			})
+		case Heading, Table:
+			convertInlines(b, func(nodes []*Inline) []*Inline {
+				return renameInlineEmoji(nodes, gitlabEmoji)
+			})
+		}
+	})
+}
+
+// toGitLab rewrites syntax that GitLab renders differently or acts on:
+// alerts become quotes with a title, display math becomes a math code block,
+// inline math gets GitLab's $`x`$ form, quick actions are escaped and video
+// URLs become embeds
+func toGitLab(doc *Document) {
+	doc.Blocks = splitDetails(doc.Blocks, GFM)
+	doc.Walk(func(b *Block) {
+		switch b.Kind {
+		case BlockQuote:
+			titleAlert(b)
+		case MathBlock:
+			b.Lines = mathCodeBlock(b.Lines)
+		case Paragraph:
+			for i, line := range b.Lines {
+				if match := quickActionRegex.FindStringSubmatch(line); match != nil && quickActions[match[2]] {
+					b.Lines[i] = match[1] + `\` + strings.TrimLeft(line, " \t")
+				}
+			}
+			convertInlines(b, func(nodes []*Inline) []*Inline {
+				return embedVideo(gitlabMath(renameInlineEmoji(nodes, githubEmoji)))
+			})
+		case Heading, Table:
+			convertInlines(b, func(nodes []*Inline) []*Inline {
+				return gitlabMath(renameInlineEmoji(nodes, githubEmoji))
+			})
+		}
+	})
+}
+
+// convertInlines rewrites the inline nodes of a block, leaving blocks whose
+// nodes do not change as they are
+func convertInlines(b *Block, convert func([]*Inline) []*Inline) {
+	nodes := b.Inlines()
+	before := RenderInlines(nodes)
+	if after := RenderInlines(convert(nodes)); after != before {
+		b.SetText(after)
+	}
+}
+
+// renameInlineEmoji renames the emoji shortcodes in text nodes
+func renameInlineEmoji(nodes []*Inline, names map[string]string) []*Inline {
+	WalkInlines(nodes, func(node *Inline) {
+		if node.Kind == Text {
+			node.Raw = renameEmoji(node.Raw, names)
+		}
+	})
+	return nodes
+}
+
+// codeSpanOf wraps text in a code span
+func codeSpanOf(text string) string {
+	if !strings.Contains(text, "`") {
+		return "`" + text + "`"
+	}
+	return "`` " + text + " ``"
+}
+
+// flattenQuote turns a GitLab ">>>" multi-line quote into a "> " quote
+func flattenQuote(b *Block) {
+	if len(b.prefixes) > 0 {
+		// The fence lines had prefixes of their own
+		end := len(b.prefixes)
+		if b.Close != "" {
+			end--
+		}
+		b.prefixes = b.prefixes[1:end]
+	}
+	b.Open, b.Close = "", ""
+	b.first, b.cont = "> ", "> "
+	for _, child := range b.Children {
+		child.prefixes = nil
+	}
+}
+
+// strikeInapplicableTask turns a GitLab inapplicable task, "- [~] task",
+// into a checked task with struck through text
+func strikeInapplicableTask(b *Block) {
+	if len(b.Children) == 0 || b.Children[0].Kind != Paragraph {
+		return
+	}
+	paragraph := b.Children[0]
+	if match := inapplicableRegex.FindStringSubmatch(paragraph.Lines[0]); match != nil && strings.TrimSpace(match[2]) != "" {
+		paragraph.Lines[0] = "[x]" + match[1] + "~~" + strings.TrimRight(match[2], " \t") + "~~"
+	}
+}
+
+// linkVideos turns images of videos, which GitLab embeds as players, into
+// links. A video alone on its line becomes its bare URL, which GitHub embeds
+// when it hosts the file.
+func linkVideos(nodes []*Inline) []*Inline {
+	for i, node := range nodes {
+		if node.Kind != Image || !isVideo(node.Dest) {
+			continue
+		}
+		alone := (i == 0 || strings.HasSuffix(strings.TrimRight(nodes[i-1].String(), " \t"), "\n")) &&
+			(i == len(nodes)-1 || strings.HasPrefix(strings.TrimLeft(nodes[i+1].String(), " \t"), "\n"))
+		if alone && (strings.HasPrefix(node.Dest, "https://") || strings.HasPrefix(node.Dest, "http://")) {
+			nodes[i] = &Inline{Kind: URL, Dest: node.Dest}
+			continue
+		}
+		node.Kind = Link
+	}
+	return nodes
+}
+
+// embedVideo turns a paragraph that is only a video URL, which GitHub embeds
+// as a player, into an image, which GitLab embeds
+func embedVideo(nodes []*Inline) []*Inline {
+	var video *Inline
+	for _, node := range nodes {
+		switch {
+		case node.Kind == Text && strings.TrimSpace(node.Raw) == "":
+		case video == nil && (node.Kind == URL || node.Kind == Autolink) && (isVideo(node.Dest) || isGitHubAsset(node.Dest)):
+			video = node
+		default:
+			return nodes
+		}
+	}
+	if video != nil {
+		name := path.Base(strings.SplitN(video.Dest, "?", 2)[0])
+		*video = Inline{Kind: Image, Children: []*Inline{{Kind: Text, Raw: name}}, Dest: video.Dest}
+	}
+	return nodes
+}
+
+func isVideo(url string) bool {
+	url = strings.SplitN(strings.SplitN(url, "#", 2)[0], "?", 2)[0]
+	return videoRegex.MatchString(url)
+}
+
+// isGitHubAsset reports whether a URL is a file uploaded to GitHub; on a
+// line of its own GitHub shows it as a video
+func isGitHubAsset(url string) bool {
+	return strings.HasPrefix(url, "https://github.com/user-attachments/assets/")
+}
+
+// gitlabMath writes inline $x$ math in GitLab's $`x`$ form, which every
+// GitLab version renders
+func gitlabMath(nodes []*Inline) []*Inline {
+	WalkInlines(nodes, func(node *Inline) {
+		if node.Kind == Math && !strings.HasPrefix(node.Raw, "$`") && !strings.HasPrefix(node.Raw, "$$") {
+			node.Raw = "$`" + node.Raw[1:len(node.Raw)-1] + "`$"
+		}
+	})
+	return nodes
+}
+
+// mathCodeBlock turns "$$" display math into a math code block
+func mathCodeBlock(lines []string) []string {
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "$$"), "$$")
+	body := strings.Split(strings.Trim(text, "\n"), "\n")
+	return append(append([]string{"```math"}, body...), "```")
+}
+
+// titleAlert turns a GitHub alert, a quote starting with "[!NOTE]", into a
+// quote starting with a title line
+func titleAlert(b *Block) {
+	if len(b.Children) == 0 || b.Children[0].Kind != Paragraph {
+		return
+	}
+	paragraph := b.Children[0]
+	match := alertRegex.FindStringSubmatch(paragraph.Lines[0])
+	if match == nil {
+		return
+	}
+	title := &Block{Kind: Paragraph, Lines: []string{alertTitles[strings.ToLower(match[1])]}}
+	if len(paragraph.prefixes) > 0 {
+		title.prefixes = paragraph.prefixes[:1]
+	}
+	if len(paragraph.Lines) == 1 {
+		b.Children[0] = title
+		return
+	}
+	// The text of the alert becomes a paragraph of its own below the title
+	paragraph.Lines = paragraph.Lines[1:]
+	if len(paragraph.prefixes) > 0 {
+		paragraph.prefixes = paragraph.prefixes[1:]
+	}
+	blank := &Block{Kind: Blank, Lines: []string{""}}
+	b.Children = append([]*Block{title, blank}, b.Children...)
+}
+
+// heading is a heading of the document for the table of contents
+type heading struct {
+	level int
+	text  string
+}
+
+func collectHeadings(doc *Document) []heading {
+	var headings []heading
+	doc.Walk(func(b *Block) {
+		if b.Kind != Heading {
+			return
+		}
+		if level := headingLevel(b); level > 0 {
+			headings = append(headings, heading{level: level, text: headingText(b)})
+		}
+	})
+	return headings
+}
+
+// headingLevel returns the level of an ATX or setext heading
+func headingLevel(b *Block) int {
+	if len(b.Lines) > 1 {
+		if strings.HasPrefix(strings.TrimSpace(b.Lines[len(b.Lines)-1]), "=") {
+			return 1
+		}
+		return 2
+	}
+	return strings.Count(strings.Fields(b.Lines[0])[0], "#")
+}
+
+// headingText returns the text of a heading without its Markdown
+func headingText(b *Block) string {
+	text := strings.Join(b.Lines[:max(len(b.Lines)-1, 1)], " ")
+	if len(b.Lines) == 1 {
+		text = atxMarkerRegex.ReplaceAllString(text, "")
+	}
+	return plainText(ParseInlines(text))
+}
+
+// plainText returns the text of inline nodes as it is displayed
+func plainText(nodes []*Inline) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.Kind {
+		case Text:
+			b.WriteString(strings.NewReplacer("\\", "", "*", "", "~~", "", "\n", " ").Replace(node.Raw))
+		case CodeSpan:
+			b.WriteString(strings.TrimSpace(strings.Trim(node.Raw, "`")))
+		case Link, Image:
+			b.WriteString(plainText(node.Children))
+		case URL, Autolink:
+			b.WriteString(node.Dest)
+		case Math:
+			b.WriteString(node.Raw)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// tableOfContents lists the headings of a document as links to their anchors
+func tableOfContents(headings []heading) []string {
+	if len(headings) == 0 {
+		return []string{""}
+	}
+	top := headings[0].level
+	for _, h := range headings {
+		top = min(top, h.level)
+	}
+	used := make(map[string]int)
+	var lines []string
+	for _, h := range headings {
+		anchor := headingAnchor(h.text)
+		if n := used[anchor]; n > 0 {
+			used[anchor] = n + 1
+			anchor = fmt.Sprintf("%s-%d", anchor, n)
+		} else {
+			used[anchor] = 1
+		}
+		text := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(h.text)
+		lines = append(lines, fmt.Sprintf("%s- [%s](#%s)", strings.Repeat("  ", h.level-top), text, anchor))
+	}
+	return lines
+}
+
+// headingAnchor returns the anchor GitHub gives a heading: its lower-case
+// text without punctuation, with spaces replaced by hyphens
+func headingAnchor(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitDetails puts blank lines after the summary of a <details> block and
+// before its closing tag, so both trackers render the Markdown inside it
+// instead of treating it as part of the HTML
+func splitDetails(blocks []*Block, dialect Dialect) []*Block {
+	var out []*Block
+	for _, b := range blocks {
+		if b.Container() {
+			b.Children = splitDetails(b.Children, dialect)
+		}
+		if b.Kind != HTMLBlock {
+			out = append(out, b)
+			continue
+		}
+		out = append(out, splitDetailsBlock(b, dialect)...)
+	}
+	return out
+}
+
+func splitDetailsBlock(b *Block, dialect Dialect) []*Block {
+	summary := -1
+	for i, line := range b.Lines {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "</summary>") && strings.Contains(strings.ToLower(strings.Join(b.Lines[:i+1], "\n")), "<details") {
+			summary = i
+			break
+		}
+	}
+	if summary < 0 {
+		return []*Block{b}
+	}
+	rest := b.Lines[summary+1:]
+	if len(rest) == 0 || (len(rest) == 1 && isDetailsEnd(rest[0])) {
+		return []*Block{b}
+	}
+
+	head := &Block{Kind: HTMLBlock, Lines: b.Lines[:summary+1], prefixes: prefixRange(b.prefixes, 0, summary+1)}
+	out := []*Block{head, {Kind: Blank, Lines: []string{""}}}
+	content, closing := rest, []string(nil)
+	if isDetailsEnd(rest[len(rest)-1]) {
+		content, closing = rest[:len(rest)-1], rest[len(rest)-1:]
+	}
+	p := &parser{dialect: dialect}
+	children := p.blocks(content)
+	line := summary + 1
+	for _, child := range children {
+		child.prefixes = prefixRange(b.prefixes, line, line+child.span)
+		line += child.span
+	}
+	out = append(out, splitDetails(children, dialect)...)
+	if closing != nil {
+		out = append(out,
+			&Block{Kind: Blank, Lines: []string{""}},
+			&Block{Kind: HTMLBlock, Lines: closing, prefixes: prefixRange(b.prefixes, line, line+1)})
+	}
+	return out
+}
+
+func isDetailsEnd(line string) bool {
+	return strings.EqualFold(strings.TrimSpace(line), "</details>")
+}
+
+// prefixRange returns the container prefixes of some lines of a block, or
+// nil for blocks without container prefixes
+func prefixRange(prefixes []string, start int, end int) []string {
+	if len(prefixes) < end {
+		return nil
+	}
+	return prefixes[start:end]
+}
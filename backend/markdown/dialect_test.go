@@ -0,0 +1,106 @@
+package markdown
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestConvert converts every testdata/<direction>/<construct>.md file and
+// compares the result with <construct>.golden.md
+func TestConvert(t *testing.T) {
+	directions := []struct {
+		dir      string
+		from, to Dialect
+	}{
+		{"to_github", GLFM, GFM},
+		{"to_gitlab", GFM, GLFM},
+	}
+	for _, direction := range directions {
+		inputs, err := filepath.Glob(filepath.Join("testdata", direction.dir, "*.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, input := range inputs {
+			if strings.HasSuffix(input, ".golden.md") {
+				continue
+			}
+			name := direction.dir + "/" + strings.TrimSuffix(filepath.Base(input), ".md")
+			t.Run(name, func(t *testing.T) {
+				source, err := os.ReadFile(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := Convert(string(source), direction.from, direction.to)
+
+				golden := strings.TrimSuffix(input, ".md") + ".golden.md"
+				if *update {
+					if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != string(want) {
+					t.Errorf("converted Markdown differs from %s:\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+				}
+			})
+		}
+	}
+}
+
+// TestParseLossless checks that rendering a parsed document returns its
+// source unchanged
+func TestParseLossless(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := []string{
+		"",
+		"no newline at the end",
+		"* a\n\n  b\n*  c\n\n      code\n",
+		"> quote\nlazy line\n>\n> - item\n>   more\n",
+		"1. one\n2) two\n\n\t- tab item\n",
+		"| a | b |\n|---|:-:|\n| `|` | [x](y(z)) |\n",
+		"[ref]: <https://example.com/a b> \"title\"\n\n<div>\n*html*\n</div>\n",
+	}
+	for _, input := range inputs {
+		source, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, string(source))
+	}
+	for _, dialect := range []Dialect{GFM, GLFM} {
+		for _, source := range sources {
+			if got := Parse(source, dialect).String(); got != source {
+				t.Errorf("rendering the parsed document changed it:\n--- got ---\n%s\n--- want ---\n%s", got, source)
+			}
+		}
+	}
+}
+
+// TestParseInlinesLossless checks that rendering parsed inline nodes returns
+// their source unchanged
+func TestParseInlinesLossless(t *testing.T) {
+	sources := []string{
+		"plain *text* with \\[escapes\\]",
+		"a [link](https://example.com/a_(b) \"title\") and ![image](</a b.png>)",
+		"`code [x](y)` and ``double ` tick`` and a lone ` tick",
+		"<https://example.com> <b>bold</b> <!-- note -->",
+		"see https://example.com/wiki/Go_(language)). or www.example.com.",
+		"math $x^2$, $`y`$ and $$z$$ but $5 and $10",
+	}
+	for _, source := range sources {
+		if got := RenderInlines(ParseInlines(source)); got != source {
+			t.Errorf("rendering the parsed inlines changed them:\ngot  %q\nwant %q", got, source)
+		}
+	}
+}
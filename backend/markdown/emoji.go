@@ -0,0 +1,53 @@
+package markdown
+
+import "regexp"
+
+var emojiRegex = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+
+// gitlabEmoji maps GitLab emoji names to the GitHub names of the same emoji.
+// Names the trackers share are not listed.
+var gitlabEmoji = map[string]string{
+	"slight_smile":                    "slightly_smiling_face",
+	"slight_frown":                    "slightly_frowning_face",
+	"upside_down":                     "upside_down_face",
+	"hugging":                         "hugs",
+	"nerd":                            "nerd_face",
+	"zipper_mouth":                    "zipper_mouth_face",
+	"money_mouth":                     "money_mouth_face",
+	"thermometer_face":                "face_with_thermometer",
+	"head_bandage":                    "face_with_head_bandage",
+	"spy":                             "detective",
+	"skull_crossbones":                "skull_and_crossbones",
+	"hammer_pick":                     "hammer_and_pick",
+	"tools":                           "hammer_and_wrench",
+	"speech_left":                     "left_speech_bubble",
+	"white_sun_small_cloud":           "sun_behind_small_cloud",
+	"desktop":                         "desktop_computer",
+	"construction_site":               "building_construction",
+	"man_in_business_suit_levitating": "business_suit_levitating",
+	"flag_us":                         "us",
+	"flag_gb":                         "gb",
+	"flag_de":                         "de",
+	"flag_fr":                         "fr",
+	"flag_jp":                         "jp",
+	"flag_cn":                         "cn",
+}
+
+// githubEmoji maps GitHub emoji names to their GitLab names
+var githubEmoji = func() map[string]string {
+	m := make(map[string]string, len(gitlabEmoji))
+	for gitlab, github := range gitlabEmoji {
+		m[github] = gitlab
+	}
+	return m
+}()
+
+// renameEmoji rewrites the :emoji: shortcodes of a text with the given names
+func renameEmoji(text string, names map[string]string) string {
+	return emojiRegex.ReplaceAllStringFunc(text, func(code string) string {
+		if name, ok := names[code[1:len(code)-1]]; ok {
+			return ":" + name + ":"
+		}
+		return code
+	})
+}
@@ -0,0 +1,378 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// InlineKind is the type of an inline node
+type InlineKind int
+
+const (
+	Text     InlineKind = iota // plain text, including line breaks
+	CodeSpan                   // `code`
+	Link                       // [text](url "title")
+	Image                      // ![alt](url "title")
+	Autolink                   // <https://example.com>
+	URL                        // https://example.com or www.example.com written as text
+	HTML                       // inline tag or comment
+	Math                       // $x$, $`x`$ or $$x$$
+)
+
+var (
+	autolinkRegex   = regexp.MustCompile(`^<(?:[A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*|[A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)*)>`)
+	inlineHTMLRegex = regexp.MustCompile(`^(?:<[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][\w.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>|<!--[\s\S]*?-->)`)
+	bareURLRegex    = regexp.MustCompile(`^(?:https?://|www\.)[^\s<]+`)
+)
+
+// Inline is a node of the text of a paragraph, heading or table
+type Inline struct {
+	Kind InlineKind
+	// Raw is the source of text, code spans, HTML and math
+	Raw string
+	// Children are the link text or image description
+	Children []*Inline
+	// Dest is the destination of links, images, autolinks and URLs
+	Dest string
+	// Title is the source between the destination of a link or image and
+	// its closing parenthesis, e.g. ` "title"`
+	Title string
+
+	lead  string // whitespace after the opening parenthesis
+	angle bool   // the destination is written in angle brackets
+}
+
+// String renders the node as Markdown
+func (n *Inline) String() string {
+	switch n.Kind {
+	case Link, Image:
+		open := "["
+		if n.Kind == Image {
+			open = "!["
+		}
+		dest := n.Dest
+		if n.angle || strings.ContainsAny(dest, " \t\n") || !balancedParens(dest) {
+			dest = "<" + dest + ">"
+		}
+		return open + RenderInlines(n.Children) + "](" + n.lead + dest + n.Title + ")"
+	case Autolink:
+		return "<" + n.Dest + ">"
+	case URL:
+		return n.Dest
+	}
+	return n.Raw
+}
+
+// RenderInlines renders inline nodes as Markdown
+func RenderInlines(nodes []*Inline) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		b.WriteString(node.String())
+	}
+	return b.String()
+}
+
+// ParseInlines parses the text of a paragraph, heading or table; both
+// dialects share the inline syntax
+func ParseInlines(text string) []*Inline {
+	p := &inlineParser{text: text}
+	p.parse()
+	return p.nodes
+}
+
+type inlineParser struct {
+	text  string
+	nodes []*Inline
+	plain strings.Builder
+}
+
+func (p *inlineParser) parse() {
+	s := p.text
+	for i := 0; i < len(s); {
+		if node, n := p.node(i); node != nil {
+			p.flush()
+			p.nodes = append(p.nodes, node)
+			i += n
+			continue
+		}
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			p.plain.WriteString(s[i : i+2])
+			i += 2
+			continue
+		}
+		if s[i] == '`' {
+			// An unmatched backtick run is text
+			run := backtickRun(s[i:])
+			p.plain.WriteString(s[i : i+run])
+			i += run
+			continue
+		}
+		p.plain.WriteByte(s[i])
+		i++
+	}
+	p.flush()
+}
+
+func (p *inlineParser) flush() {
+	if p.plain.Len() > 0 {
+		p.nodes = append(p.nodes, &Inline{Kind: Text, Raw: p.plain.String()})
+		p.plain.Reset()
+	}
+}
+
+// node parses the node starting at position i and returns it with its length
+func (p *inlineParser) node(i int) (*Inline, int) {
+	s := p.text
+	switch s[i] {
+	case '`':
+		return codeSpan(s[i:])
+	case '!':
+		if i+1 < len(s) && s[i+1] == '[' {
+			if node, n := link(s[i+1:]); node != nil {
+				node.Kind = Image
+				return node, n + 1
+			}
+		}
+	case '[':
+		return link(s[i:])
+	case '<':
+		if match := autolinkRegex.FindString(s[i:]); match != "" {
+			return &Inline{Kind: Autolink, Dest: match[1 : len(match)-1]}, len(match)
+		}
+		if match := inlineHTMLRegex.FindString(s[i:]); match != "" {
+			return &Inline{Kind: HTML, Raw: match}, len(match)
+		}
+	case '$':
+		return inlineMath(s[i:], i > 0 && (isWordByte(s[i-1]) || s[i-1] == '\\'))
+	case 'h', 'w':
+		if i > 0 && !strings.ContainsRune(" \t\n*_~(\"'", rune(s[i-1])) {
+			return nil, 0
+		}
+		if url := bareURL(s[i:]); url != "" {
+			return &Inline{Kind: URL, Dest: url}, len(url)
+		}
+	}
+	return nil, 0
+}
+
+// codeSpan parses a code span; an unmatched backtick run is not one
+func codeSpan(s string) (*Inline, int) {
+	run := backtickRun(s)
+	for i := run; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		closing := backtickRun(s[i:])
+		if closing == run {
+			return &Inline{Kind: CodeSpan, Raw: s[:i+run]}, i + run
+		}
+		i += closing
+	}
+	return nil, 0
+}
+
+func backtickRun(s string) int {
+	n := 0
+	for n < len(s) && s[n] == '`' {
+		n++
+	}
+	return n
+}
+
+// link parses an inline link starting at "["
+func link(s string) (*Inline, int) {
+	end := labelEnd(s)
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return nil, 0
+	}
+	node := &Inline{Kind: Link, Children: ParseInlines(s[1:end])}
+
+	i := end + 2
+	start := i
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	node.lead = s[start:i]
+
+	if i < len(s) && s[i] == '<' {
+		closing := strings.IndexAny(s[i+1:], "<>\n")
+		if closing < 0 || s[i+1+closing] != '>' {
+			return nil, 0
+		}
+		node.Dest, node.angle = s[i+1:i+1+closing], true
+		i += closing + 2
+	} else {
+		destStart, depth := i, 0
+	dest:
+		for i < len(s) {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s):
+				i += 2
+				continue
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break dest
+				}
+				depth--
+			case c <= ' ':
+				break dest
+			}
+			i++
+		}
+		if depth != 0 {
+			return nil, 0
+		}
+		node.Dest = s[destStart:i]
+	}
+
+	titleStart := i
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	if i < len(s) && i > titleStart && strings.IndexByte(`"'(`, s[i]) >= 0 {
+		closer := s[i]
+		if closer == '(' {
+			closer = ')'
+		}
+		closing := strings.IndexByte(s[i+1:], closer)
+		if closing < 0 {
+			return nil, 0
+		}
+		i += closing + 2
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+			i++
+		}
+	}
+	if i >= len(s) || s[i] != ')' {
+		return nil, 0
+	}
+	node.Title = s[titleStart:i]
+	return node, i + 1
+}
+
+// labelEnd returns the index of the "]" closing the link text that starts
+// at "[", skipping nested brackets, escapes and code spans, or -1
+func labelEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			if _, n := codeSpan(s[i:]); n > 0 {
+				i += n - 1
+			} else {
+				i += backtickRun(s[i:]) - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// inlineMath parses inline math: $`x`$, $$x$$ or $x$. A "$" after a word is not
+// math, and neither is $x$ with spaces inside its delimiters or a digit
+// after it, so prices such as "$5 and $10" stay text.
+func inlineMath(s string, afterWord bool) (*Inline, int) {
+	switch {
+	case strings.HasPrefix(s, "$`"):
+		if end := strings.Index(s[2:], "`$"); end >= 0 {
+			return &Inline{Kind: Math, Raw: s[:end+4]}, end + 4
+		}
+	case strings.HasPrefix(s, "$$"):
+		if end := strings.Index(s[2:], "$$"); end > 0 {
+			return &Inline{Kind: Math, Raw: s[:end+4]}, end + 4
+		}
+	case !afterWord && len(s) > 2 && s[1] != ' ' && s[1] != '\n' && s[1] != '$':
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '\n':
+				return nil, 0
+			case '$':
+				if s[i-1] == ' ' || (i+1 < len(s) && (isWordByte(s[i+1]) || s[i+1] == '$')) {
+					return nil, 0
+				}
+				return &Inline{Kind: Math, Raw: s[:i+1]}, i + 1
+			}
+		}
+	}
+	return nil, 0
+}
+
+// bareURL returns the URL at the start of s, without trailing punctuation
+// and unbalanced closing parentheses, as GitHub and GitLab link them
+func bareURL(s string) string {
+	url := bareURLRegex.FindString(s)
+	for url != "" {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(`?!.,:*_~'"`, last) >= 0:
+			url = url[:len(url)-1]
+		case last == ')' && !balancedParens(url):
+			url = url[:len(url)-1]
+		default:
+			return url
+		}
+	}
+	return ""
+}
+
+func balancedParens(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return false
+			}
+			depth--
+		}
+	}
+	return depth == 0
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Inlines returns the inline nodes of a paragraph, heading or table, or nil
+// for other blocks
+func (b *Block) Inlines() []*Inline {
+	switch b.Kind {
+	case Paragraph, Heading, Table:
+		return ParseInlines(b.Text())
+	}
+	return nil
+}
+
+// SetInlines replaces the content of a paragraph, heading or table
+func (b *Block) SetInlines(nodes []*Inline) {
+	b.SetText(RenderInlines(nodes))
+}
+
+// WalkInlines calls fn for every inline node and the nodes in its children
+func WalkInlines(nodes []*Inline, fn func(*Inline)) {
+	for _, node := range nodes {
+		fn(node)
+		WalkInlines(node.Children, fn)
+	}
+}
@@ -0,0 +1,267 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// LinkKind is the syntax a URL is written in
+type LinkKind int
+
+const (
+	InlineLink  LinkKind = iota // [text](url)
+	InlineImage                 // ![alt](url)
+	Reference                   // [label]: url, the target of [text][label] and ![alt][label]
+	PlainURL                    // https://example.com or <https://example.com>
+	HTMLLink                    // <a href="url">
+	HTMLImage                   // <img src="url">
+)
+
+var (
+	imageReferenceRegex = regexp.MustCompile(`!\[((?:[^\]\\]|\\.)*)\](?:\[((?:[^\]\\]|\\.)*)\])?`)
+	htmlURLAttrRegex    = regexp.MustCompile(`(?i)(\s(?:src|href)\s*=\s*)("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+)`)
+)
+
+// LinkRef is a URL in a text
+type LinkRef struct {
+	Kind LinkKind
+	URL  string
+	// Text is the link text, the image description or alt attribute, or the
+	// label of a reference
+	Text string
+	// Image is set for images and for references used by an image
+	Image bool
+	// Source is the Markdown or HTML tag the URL is written in
+	Source string
+}
+
+// Links returns the URLs of a text in the order they appear. URLs in code
+// blocks, code spans and HTML comments are left out.
+func Links(source string) []LinkRef {
+	var links []LinkRef
+	ReplaceLinks(source, func(link LinkRef) string {
+		links = append(links, link)
+		return link.URL
+	})
+	return links
+}
+
+// ReplaceLinks calls fn for every URL of a text in the order they appear and
+// replaces the URL with the one fn returns. Code and everything other than
+// the URLs is left as it is.
+func ReplaceLinks(source string, fn func(LinkRef) string) string {
+	if source == "" {
+		return source
+	}
+	// The dialects only differ in constructs that hold no URLs, so a text of
+	// either one can be parsed as GitLab Flavored Markdown
+	doc := Parse(source, GLFM)
+	images := imageLabels(doc)
+	doc.Walk(func(b *Block) {
+		switch b.Kind {
+		case Definition:
+			b.Lines[0] = replaceDefinition(b.Lines[0], images, fn)
+		case HTMLBlock:
+			b.SetText(replaceHTML(b.Text(), "", fn))
+		case Paragraph, Heading, Table:
+			nodes := b.Inlines()
+			if replaceInlines(nodes, fn) {
+				b.SetInlines(nodes)
+			}
+		}
+	})
+	return doc.String()
+}
+
+// replaceInlines replaces the URLs of inline nodes and reports whether any
+// changed
+func replaceInlines(nodes []*Inline, fn func(LinkRef) string) bool {
+	changed := false
+	for i, node := range nodes {
+		switch node.Kind {
+		case Link, Image:
+			link := LinkRef{Kind: InlineLink, URL: node.Dest, Text: plainText(node.Children), Source: node.String()}
+			if node.Kind == Image {
+				link.Kind, link.Image = InlineImage, true
+			}
+			if url := fn(link); url != node.Dest {
+				node.Dest, changed = url, true
+			}
+			// Images inside links, as in [![alt](image)](link)
+			if replaceInlines(node.Children, fn) {
+				changed = true
+			}
+		case URL, Autolink:
+			if url := fn(LinkRef{Kind: PlainURL, URL: node.Dest, Text: node.Dest, Source: node.String()}); url != node.Dest {
+				node.Dest, changed = url, true
+			}
+		case HTML:
+			if raw := replaceHTML(node.Raw, htmlLinkText(nodes[i:]), fn); raw != node.Raw {
+				node.Raw, changed = raw, true
+			}
+		}
+	}
+	return changed
+}
+
+// htmlLinkText returns the text between an inline <a> tag and its closing tag
+func htmlLinkText(nodes []*Inline) string {
+	if !strings.HasPrefix(strings.ToLower(nodes[0].Raw), "<a") {
+		return ""
+	}
+	for end := 1; end < len(nodes); end++ {
+		if nodes[end].Kind == HTML && strings.HasPrefix(strings.ToLower(nodes[end].Raw), "</a") {
+			return plainText(nodes[1:end])
+		}
+	}
+	return ""
+}
+
+// htmlTag is an <a> or <img> tag of an HTML fragment
+type htmlTag struct {
+	start, end int
+	link       LinkRef
+}
+
+// replaceHTML replaces the href of <a> tags and the src of <img> tags outside
+// <code> and <pre>; linkText is the text of an <a> tag whose closing tag is
+// not part of the fragment
+func replaceHTML(source string, linkText string, fn func(LinkRef) string) string {
+	var tags []*htmlTag
+	var open *htmlTag
+	code, offset := 0, 0
+	z := html.NewTokenizer(strings.NewReader(source))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		start := offset
+		offset += len(raw)
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, more := z.TagName()
+			switch tag := string(name); {
+			case tag == "code" || tag == "pre":
+				if tt == html.StartTagToken {
+					code++
+				}
+			case code > 0:
+			case tag == "a" || tag == "img":
+				attrs := make(map[string]string)
+				for more {
+					var key, value []byte
+					key, value, more = z.TagAttr()
+					attrs[string(key)] = string(value)
+				}
+				link := LinkRef{Kind: HTMLLink, URL: attrs["href"], Text: linkText, Source: raw}
+				if tag == "img" {
+					link = LinkRef{Kind: HTMLImage, URL: attrs["src"], Text: attrs["alt"], Image: true, Source: raw}
+				}
+				if link.URL == "" {
+					continue
+				}
+				parsed := &htmlTag{start: start, end: offset, link: link}
+				tags = append(tags, parsed)
+				if tag == "a" && tt == html.StartTagToken {
+					open = parsed
+					open.link.Text = ""
+				}
+			}
+		case html.EndTagToken:
+			switch name, _ := z.TagName(); string(name) {
+			case "code", "pre":
+				code = max(code-1, 0)
+			case "a":
+				open = nil
+			}
+		case html.TextToken:
+			if open != nil {
+				open.link.Text += string(z.Text())
+			}
+		}
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, tag := range tags {
+		if tag.link.Kind == HTMLLink && tag.link.Text == "" {
+			tag.link.Text = linkText
+		}
+		tag.link.Text = strings.TrimSpace(tag.link.Text)
+		url := fn(tag.link)
+		if url == tag.link.URL {
+			continue
+		}
+		raw := source[tag.start:tag.end]
+		replaced := false
+		raw = htmlURLAttrRegex.ReplaceAllStringFunc(raw, func(attr string) string {
+			match := htmlURLAttrRegex.FindStringSubmatch(attr)
+			name := strings.ToLower(strings.TrimSpace(strings.TrimRight(match[1], " \t\n=")))
+			if replaced || (tag.link.Kind == HTMLLink) != (name == "href") {
+				return attr
+			}
+			replaced = true
+			return match[1] + `"` + html.EscapeString(url) + `"`
+		})
+		b.WriteString(source[last:tag.start])
+		b.WriteString(raw)
+		last = tag.end
+	}
+	if last == 0 {
+		return source
+	}
+	b.WriteString(source[last:])
+	return b.String()
+}
+
+// replaceDefinition replaces the URL of a link reference definition
+func replaceDefinition(line string, images map[string]bool, fn func(LinkRef) string) string {
+	match := definitionRegex.FindStringSubmatchIndex(line)
+	if match == nil {
+		return line
+	}
+	label, dest := line[match[2]:match[3]], line[match[4]:match[5]]
+	angle := strings.HasPrefix(dest, "<")
+	if angle {
+		dest = dest[1 : len(dest)-1]
+	}
+	url := fn(LinkRef{Kind: Reference, URL: dest, Text: label, Image: images[referenceLabel(label)], Source: line})
+	if url == dest {
+		return line
+	}
+	if angle || strings.ContainsAny(url, " \t") {
+		url = "<" + url + ">"
+	}
+	return line[:match[4]] + url + line[match[5]:]
+}
+
+// imageLabels returns the labels of the references used by images
+func imageLabels(doc *Document) map[string]bool {
+	labels := make(map[string]bool)
+	doc.Walk(func(b *Block) {
+		WalkInlines(b.Inlines(), func(node *Inline) {
+			if node.Kind != Text {
+				return
+			}
+			for _, match := range imageReferenceRegex.FindAllStringSubmatch(node.Raw, -1) {
+				label := match[2]
+				if label == "" {
+					label = match[1]
+				}
+				labels[referenceLabel(label)] = true
+			}
+		})
+	})
+	return labels
+}
+
+// referenceLabel normalizes a reference label, which matches case-insensitively
+// and with any whitespace
+func referenceLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
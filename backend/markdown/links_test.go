@@ -0,0 +1,79 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const linkSource = "See [the log](https://files.example.com/build(1).log \"log\") and " +
+	"![screenshot](https://files.example.com/a.png).\n" +
+	"\n" +
+	"Inline `![not](https://files.example.com/a.png)` code.\n" +
+	"\n" +
+	"```\n" +
+	"[code](https://files.example.com/a.png)\n" +
+	"```\n" +
+	"\n" +
+	"![diagram][d] and [notes][] at https://files.example.com/notes.txt.\n" +
+	"\n" +
+	"[d]: https://files.example.com/d.svg\n" +
+	"[notes]: <https://files.example.com/my notes.txt>\n" +
+	"\n" +
+	"<p><img width=\"200\" alt=\"chart\" src=\"https://files.example.com/a.png\"></p>\n" +
+	"\n" +
+	"Download <a href='https://files.example.com/b.zip'>the archive</a>.\n" +
+	"<!-- https://files.example.com/hidden.png -->\n"
+
+func TestLinks(t *testing.T) {
+	want := []LinkRef{
+		{Kind: InlineLink, URL: "https://files.example.com/build(1).log", Text: "the log"},
+		{Kind: InlineImage, URL: "https://files.example.com/a.png", Text: "screenshot", Image: true},
+		{Kind: PlainURL, URL: "https://files.example.com/notes.txt", Text: "https://files.example.com/notes.txt"},
+		{Kind: Reference, URL: "https://files.example.com/d.svg", Text: "d", Image: true},
+		{Kind: Reference, URL: "https://files.example.com/my notes.txt", Text: "notes"},
+		{Kind: HTMLImage, URL: "https://files.example.com/a.png", Text: "chart", Image: true},
+		{Kind: HTMLLink, URL: "https://files.example.com/b.zip", Text: "the archive"},
+	}
+	got := Links(linkSource)
+	if len(got) != len(want) {
+		t.Fatalf("found %d links, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		got[i].Source = ""
+		if got[i] != want[i] {
+			t.Errorf("link %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReplaceLinks(t *testing.T) {
+	got := ReplaceLinks(linkSource, func(link LinkRef) string {
+		return strings.Replace(link.URL, "https://files.example.com/", "/uploads/", 1)
+	})
+	want := "See [the log](/uploads/build(1).log \"log\") and " +
+		"![screenshot](/uploads/a.png).\n" +
+		"\n" +
+		"Inline `![not](https://files.example.com/a.png)` code.\n" +
+		"\n" +
+		"```\n" +
+		"[code](https://files.example.com/a.png)\n" +
+		"```\n" +
+		"\n" +
+		"![diagram][d] and [notes][] at /uploads/notes.txt.\n" +
+		"\n" +
+		"[d]: /uploads/d.svg\n" +
+		"[notes]: </uploads/my notes.txt>\n" +
+		"\n" +
+		"<p><img width=\"200\" alt=\"chart\" src=\"/uploads/a.png\"></p>\n" +
+		"\n" +
+		"Download <a href=\"/uploads/b.zip\">the archive</a>.\n" +
+		"<!-- https://files.example.com/hidden.png -->\n"
+	if got != want {
+		t.Errorf("replaced links:\n%s\nwant:\n%s", got, want)
+	}
+
+	unchanged := ReplaceLinks(linkSource, func(link LinkRef) string { return link.URL })
+	if unchanged != linkSource {
+		t.Errorf("keeping every URL changed the text:\n%s", unchanged)
+	}
+}
@@ -0,0 +1,146 @@
+// Package markdown parses issue bodies and comments into a tree of blocks and
+// inline nodes and converts them between GitHub Flavored Markdown and GitLab
+// Flavored Markdown.
+//
+// The tree is lossless: rendering an unchanged document returns its source
+// byte for byte, so conversions only touch the constructs they rewrite.
+package markdown
+
+import "strings"
+
+// Dialect is a Markdown flavor
+type Dialect int
+
+const (
+	GFM  Dialect = iota // GitHub Flavored Markdown, also used by Gitea and as the common form of other trackers
+	GLFM                // GitLab Flavored Markdown
+)
+
+// BlockKind is the type of a block
+type BlockKind int
+
+const (
+	Blank         BlockKind = iota // empty line
+	Paragraph                      // text lines
+	Heading                        // ATX ("## Title") or setext heading
+	ThematicBreak                  // "---"
+	CodeBlock                      // fenced or indented code
+	MathBlock                      // "$$" display math
+	HTMLBlock                      // raw HTML up to a blank line
+	Table                          // GFM table
+	Definition                     // link reference definition, "[label]: url"
+	BlockQuote                     // "> " lines, or a GitLab ">>>" multi-line quote
+	ListItem                       // "- " or "1. " item with its content
+)
+
+// Block is a node of the block tree. Leaf blocks hold their lines without
+// the prefixes of the containers they are in; block quotes and list items
+// hold child blocks.
+type Block struct {
+	Kind BlockKind
+	// Lines are the content lines of a leaf block, including fences and
+	// heading markers
+	Lines []string
+	// Children are the blocks of a block quote or list item
+	Children []*Block
+	// Open and Close are the fence lines of a GitLab multi-line quote
+	Open  string
+	Close string
+	// Marker is the marker of a list item, e.g. "-" or "1."
+	Marker string
+
+	// prefixes are the container prefixes of the lines of this block as in
+	// the source, such as "> " or the indentation of a list item
+	prefixes []string
+	// first and cont are the prefixes of lines added to a container: first
+	// for its first line, cont for the others
+	first string
+	cont  string
+	// span is the number of source lines of the block
+	span int
+}
+
+// Container reports whether the block holds child blocks
+func (b *Block) Container() bool {
+	return b.Kind == BlockQuote || b.Kind == ListItem
+}
+
+// Text returns the content lines of a leaf block joined by newlines
+func (b *Block) Text() string {
+	return strings.Join(b.Lines, "\n")
+}
+
+// SetText replaces the content of a leaf block
+func (b *Block) SetText(text string) {
+	b.Lines = strings.Split(text, "\n")
+}
+
+// Document is a parsed Markdown text
+type Document struct {
+	Blocks  []*Block
+	Dialect Dialect
+	// newline is set when the source ends with a line break
+	newline bool
+}
+
+// String renders the document as Markdown
+func (d *Document) String() string {
+	out := strings.Join(renderBlocks(d.Blocks, "", ""), "\n")
+	if d.newline {
+		out += "\n"
+	}
+	return out
+}
+
+// Walk calls fn for every block of the document, parents before their children
+func (d *Document) Walk(fn func(*Block)) {
+	walk(d.Blocks, fn)
+}
+
+func walk(blocks []*Block, fn func(*Block)) {
+	for _, block := range blocks {
+		fn(block)
+		walk(block.Children, fn)
+	}
+}
+
+// lines renders a block without the prefixes of its container
+func (b *Block) lines() []string {
+	if !b.Container() {
+		return b.Lines
+	}
+	var out []string
+	if b.Open != "" {
+		out = append(out, b.Open)
+	}
+	out = append(out, renderBlocks(b.Children, b.first, b.cont)...)
+	if b.Close != "" {
+		out = append(out, b.Close)
+	}
+	return out
+}
+
+// renderBlocks renders sibling blocks with their container prefixes. Lines
+// without a prefix from the source get first or cont; on empty lines their
+// trailing spaces are left out.
+func renderBlocks(blocks []*Block, first string, cont string) []string {
+	var out []string
+	for _, block := range blocks {
+		for i, line := range block.lines() {
+			var prefix string
+			switch {
+			case i < len(block.prefixes):
+				prefix = block.prefixes[i]
+			case len(out) == 0:
+				prefix = first
+			default:
+				prefix = cont
+			}
+			if i >= len(block.prefixes) && line == "" {
+				prefix = strings.TrimRight(prefix, " ")
+			}
+			out = append(out, prefix+line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// codeTagRegex matches an inline <code> or <pre> tag and captures the slash
+// of a closing tag
+var codeTagRegex = regexp.MustCompile(`(?i)^<(/?)(?:code|pre)\b`)
+
+// ReplaceText calls fn for the prose of a text and replaces it with what fn
+// returns. Prose is the text of paragraphs, headings, tables and link texts,
+// and the text between the tags of HTML. Code blocks, code spans, <code> and
+// <pre> elements, HTML tags and comments, math and URLs are left as they are.
+func ReplaceText(source string, fn func(string) string) string {
+	if source == "" {
+		return source
+	}
+	// As with links, the dialects do not differ in where prose is
+	doc := Parse(source, GLFM)
+	doc.Walk(func(b *Block) {
+		switch b.Kind {
+		case HTMLBlock:
+			if text := replaceHTMLText(b.Text(), fn); text != b.Text() {
+				b.SetText(text)
+			}
+		case Paragraph, Heading, Table:
+			nodes := b.Inlines()
+			if replaceTextNodes(nodes, fn) {
+				b.SetInlines(nodes)
+			}
+		}
+	})
+	return doc.String()
+}
+
+// replaceTextNodes replaces the text nodes outside inline <code> and <pre>
+// elements and reports whether any changed
+func replaceTextNodes(nodes []*Inline, fn func(string) string) bool {
+	changed := false
+	code := 0
+	for _, node := range nodes {
+		switch node.Kind {
+		case Text:
+			if code > 0 {
+				continue
+			}
+			if raw := fn(node.Raw); raw != node.Raw {
+				node.Raw, changed = raw, true
+			}
+		case Link:
+			if replaceTextNodes(node.Children, fn) {
+				changed = true
+			}
+		case HTML:
+			if match := codeTagRegex.FindStringSubmatch(node.Raw); match != nil {
+				if match[1] == "" {
+					code++
+				} else {
+					code = max(code-1, 0)
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// replaceHTMLText replaces the text of an HTML fragment outside <code> and
+// <pre> elements
+func replaceHTMLText(source string, fn func(string) string) string {
+	var b strings.Builder
+	code := 0
+	z := html.NewTokenizer(strings.NewReader(source))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		switch tt {
+		case html.StartTagToken:
+			if name, _ := z.TagName(); string(name) == "code" || string(name) == "pre" {
+				code++
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "code" || string(name) == "pre" {
+				code = max(code-1, 0)
+			}
+		case html.TextToken:
+			if code == 0 {
+				raw = fn(raw)
+			}
+		}
+		b.WriteString(raw)
+	}
+	return b.String()
+}
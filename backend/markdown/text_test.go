@@ -0,0 +1,39 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplaceText(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"paragraph", "hi @bob", "hi @BOB"},
+		{"heading", "## @bob", "## @BOB"},
+		{"table", "| a |\n| - |\n| @bob |", "| a |\n| - |\n| @BOB |"},
+		{"link text", "[@bob](https://example.com/@bob)", "[@BOB](https://example.com/@bob)"},
+		{"url", "see https://example.com/@bob", "see https://example.com/@bob"},
+		{"code span", "`@bob` @bob", "`@bob` @BOB"},
+		{"code span across lines", "a `x\ny @bob` @bob", "a `x\ny @bob` @BOB"},
+		{"fenced code", "```\n@bob\n```\n@bob", "```\n@bob\n```\n@BOB"},
+		{"indented code", "    @bob\n\n@bob", "    @bob\n\n@BOB"},
+		{"inline code element", "<code>@bob</code> @bob", "<code>@bob</code> @BOB"},
+		{"pre block", "<pre>\n@bob\n</pre>\n\n@bob", "<pre>\n@bob\n</pre>\n\n@BOB"},
+		{"html block", "<p>@bob <code>@bob</code></p>", "<p>@BOB <code>@bob</code></p>"},
+		{"html comment", "<!-- @bob --> @bob", "<!-- @bob --> @BOB"},
+		{"quote and list", "> @bob\n\n- @bob", "> @BOB\n\n- @BOB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReplaceText(tt.source, func(text string) string {
+				return strings.ReplaceAll(text, "@bob", "@BOB")
+			})
+			if got != tt.want {
+				t.Errorf("ReplaceText(%q)\n got: %q\nwant: %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,15 +8,18 @@ import (
 )
 
 type Issue struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	State       string    `json:"state"`
-	Labels      []string  `json:"labels"`
-	Author      string    `json:"author"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	URL         string    `json:"url"`
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	Labels      []string   `json:"labels"`
+	Milestone   string     `json:"milestone,omitempty"`
+	Author      string     `json:"author"`
+	Assignees   []string   `json:"assignees,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	URL         string     `json:"url"`
 }
 
 type GitHubRequest struct {
@@ -31,27 +34,120 @@ type GitLabRequest struct {
 	Token     string `json:"token" binding:"required"`
 }
 
+type GiteaRequest struct {
+	BaseURL string `json:"base_url" binding:"required"`
+	Owner   string `json:"owner" binding:"required"`
+	Repo    string `json:"repo" binding:"required"`
+	Token   string `json:"token"`
+}
+
+type JiraRequest struct {
+	BaseURL    string `json:"base_url" binding:"required"`
+	ProjectKey string `json:"project_key" binding:"required"`
+	JQL        string `json:"jql"`
+	Email      string `json:"email"`
+	Token      string `json:"token" binding:"required"`
+}
+
+type BitbucketRequest struct {
+	Owner    string `json:"owner" binding:"required"` // workspace
+	Repo     string `json:"repo" binding:"required"`  // repository slug
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+type AzureRequest struct {
+	BaseURL string `json:"base_url"`                 // Azure DevOps Server URL; defaults to https://dev.azure.com
+	Owner   string `json:"owner" binding:"required"` // organization or collection
+	Repo    string `json:"repo" binding:"required"`  // project
+	Token   string `json:"token" binding:"required"`
+}
+
+type ArchiveRequest struct {
+	ArchivePath string `json:"archive_path" binding:"required"`
+}
+
+// Endpoint describes one side of a migration: a repository or project on any
+// supported tracker
+type Endpoint struct {
+	Type      string `json:"type"` // "github", "gitlab", "gitea" (also used for Forgejo), "azure", or "jira", "bitbucket" and "archive" (source only)
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	ProjectID int    `json:"project_id"`
+	BaseURL   string `json:"base_url"`
+	Token     string `json:"token"`
+	Session   string `json:"session"` // browser session cookie for GitHub uploads or GitLab downloads
+	// Jira endpoints select issues of ProjectKey, optionally narrowed by JQL.
+	// Jira Cloud authenticates with Email and an API token; Jira Server and
+	// Data Center use a personal access token without Email.
+	ProjectKey string `json:"project_key,omitempty"`
+	JQL        string `json:"jql,omitempty"`
+	Email      string `json:"email,omitempty"`
+	// Username selects basic authentication with an app password for
+	// Bitbucket; without it the token is sent as a bearer access token
+	Username string `json:"username,omitempty"`
+	// WorkItemType is the Azure DevOps work item type created for issues
+	// without a "type:" label; defaults to "Issue"
+	WorkItemType string `json:"work_item_type,omitempty"`
+	// ArchivePath is the zip archive on the backend host that an "archive"
+	// source reads instead of a live tracker
+	ArchivePath string `json:"archive_path,omitempty"`
+}
+
 type MigrationRequest struct {
-	Direction string `json:"direction" binding:"required"`
-	Source    struct {
-		Type      string `json:"type"`
-		Owner     string `json:"owner"`
-		Repo      string `json:"repo"`
-		ProjectID int    `json:"project_id"`
-		BaseURL   string `json:"base_url"`
-		Token     string `json:"token"`
-		Session   string `json:"session"` // GitHub session cookie for uploads
-	} `json:"source" binding:"required"`
-	Target struct {
-		Type      string `json:"type"`
-		Owner     string `json:"owner"`
-		Repo      string `json:"repo"`
-		ProjectID int    `json:"project_id"`
-		BaseURL   string `json:"base_url"`
-		Token     string `json:"token"`
-		Session   string `json:"session"` // GitHub session cookie for uploads
-	} `json:"target" binding:"required"`
-	IssueIDs []int `json:"issue_ids" binding:"required"`
+	Direction string   `json:"direction"` // optional, e.g. "github-to-gitlab"; fills in missing source and target types
+	Source    Endpoint `json:"source" binding:"required"`
+	Target    Endpoint `json:"target" binding:"required"`
+	IssueIDs  []int    `json:"issue_ids"` // required for migrations; optional filter for syncs
+	Resume    bool     `json:"resume"`    // skip issues, comments and attachments recorded by an earlier run
+	// Mode is "migrate" (default) or "sync". A sync only updates issues that
+	// were migrated before and appends comments created since the watermark.
+	Mode  string     `json:"mode"`
+	Since *time.Time `json:"since"` // sync watermark; defaults to the one stored by the previous sync
+	// DryRun reads the source and the target and returns a plan of every
+	// write without performing any of them
+	DryRun bool `json:"dry_run"`
+	// UserMapping maps source usernames to target usernames for mentions,
+	// assignees and migration headers
+	UserMapping map[string]string `json:"user_mapping"`
+	// MatchUsersByEmail maps users missing from UserMapping whose email
+	// address is visible on both trackers
+	MatchUsersByEmail bool `json:"match_users_by_email"`
+	// LabelMapping renames source labels on the target; labels mapped to the
+	// same name are merged
+	LabelMapping map[string]string `json:"label_mapping"`
+	// SyncLabels creates the source labels on the target with their color and
+	// description, and updates target labels that differ, before any issue
+	SyncLabels bool `json:"sync_labels"`
+	// Translation carries GitLab scoped labels, weights, health statuses and
+	// epics to trackers without them and back
+	Translation TranslationRules `json:"translation"`
+	// SystemNotes is "log" (default) to collect system notes such as label
+	// changes into one activity log comment per issue, or "skip"
+	SystemNotes string `json:"system_notes"`
+	// History adds the label, state, assignee, milestone, title and
+	// reference events of every issue to it as a history comment
+	History bool `json:"history"`
+}
+
+// TranslationRules say how GitLab-only issue fields are written to other
+// trackers. Issues migrated to GitLab from other trackers are read with the
+// same rules, turning the labels and front matter they wrote back into fields.
+type TranslationRules struct {
+	// ScopedLabels is "keep" (default), "flatten" ("priority::high" becomes
+	// "priority: high") or "value" ("priority::high" becomes "high")
+	ScopedLabels string `json:"scoped_labels"`
+	// Weight, HealthStatus and Epic are "header" (default), "label" (e.g.
+	// "weight: 3"), "front_matter" (a field at the top of the body) or "none"
+	Weight       string `json:"weight"`
+	HealthStatus string `json:"health_status"`
+	Epic         string `json:"epic"`
+}
+
+// ExportRequest selects the issues written to an offline archive
+type ExportRequest struct {
+	Source   Endpoint `json:"source" binding:"required"`
+	IssueIDs []int    `json:"issue_ids"` // optional; every issue is exported when empty
 }
 
 type MigrationStatus struct {
@@ -59,11 +155,118 @@ type MigrationStatus struct {
 	NewID      int    `json:"new_id"`
 	NewURL     string `json:"new_url"`
 	Error      string `json:"error,omitempty"`
+	Skipped    bool   `json:"skipped,omitempty"` // already migrated by an earlier run
 }
 
 type MigrationResult struct {
-	Success []MigrationStatus `json:"success"`
-	Failed  []MigrationStatus `json:"failed"`
+	Success          []MigrationStatus `json:"success"`
+	Failed           []MigrationStatus `json:"failed"`
+	UnmappedUsers    []string          `json:"unmapped_users,omitempty"`    // source authors and assignees without a target user
+	UnmappedMentions []string          `json:"unmapped_mentions,omitempty"` // other @mentioned source users without a target user
+}
+
+// IssueProgress tracks the state of a single issue inside a migration job
+type IssueProgress struct {
+	OriginalID int    `json:"original_id"`
+	Status     string `json:"status"` // pending, running, success, failed
+	NewID      int    `json:"new_id,omitempty"`
+	NewURL     string `json:"new_url,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MigrationJob is the state of a background migration as returned by the jobs API
+type MigrationJob struct {
+	ID         string           `json:"id"`
+	Status     string           `json:"status"` // queued, running, completed
+	Direction  string           `json:"direction"`
+	Total      int              `json:"total"`
+	Completed  int              `json:"completed"`
+	Progress   []IssueProgress  `json:"progress"`
+	Result     *MigrationResult `json:"result,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Archive    string           `json:"archive,omitempty"` // download path of the archive written by an export job
+	Plan       *MigrationPlan   `json:"plan,omitempty"`    // writes previewed by a dry run
+	CreatedAt  time.Time        `json:"created_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// MigrationPlan lists the writes a migration would perform
+type MigrationPlan struct {
+	Issues             []PlannedIssue `json:"issues"`
+	LabelsToCreate     []string       `json:"labels_to_create"`     // labels missing on the target
+	LabelsToUpdate     []string       `json:"labels_to_update"`     // target labels whose color or description would be synced
+	MilestonesToCreate []string       `json:"milestones_to_create"` // milestones missing on the target
+	UnmappedUsers      []string       `json:"unmapped_users"`       // source authors and assignees without a target user
+	UnmappedMentions   []string       `json:"unmapped_mentions"`    // other @mentioned source users without a target user
+	AttachmentCount    int            `json:"attachment_count"`
+	AttachmentBytes    int64          `json:"attachment_bytes"`
+}
+
+// PlannedIssue is the preview of one source issue
+type PlannedIssue struct {
+	OriginalID  int                 `json:"original_id"`
+	Action      string              `json:"action"`              // "create", "update", "resume" or "skip"
+	TargetID    int                 `json:"target_id,omitempty"` // existing target issue
+	Title       string              `json:"title"`
+	Body        string              `json:"body"` // rendered body including the migration header
+	Labels      []string            `json:"labels"`
+	Assignees   []string            `json:"assignees"`           // target usernames
+	Milestone   string              `json:"milestone,omitempty"` // source milestone title
+	State       string              `json:"state"`
+	Comments    []PlannedComment    `json:"comments"`
+	History     string              `json:"history,omitempty"` // rendered history comment
+	Attachments []PlannedAttachment `json:"attachments"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// PlannedComment is the preview of one source comment
+type PlannedComment struct {
+	OriginalID int64  `json:"original_id"`
+	Action     string `json:"action"`         // "create" or "skip"
+	Body       string `json:"body,omitempty"` // rendered body of a created comment
+}
+
+// PlannedAttachment is a file that would be uploaded to the target
+type PlannedAttachment struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Error    string `json:"error,omitempty"` // the file could not be downloaded and would keep its source URL
+}
+
+// MirrorConfig keeps a GitHub repository and a GitLab project in sync in both directions
+type MirrorConfig struct {
+	ID     string   `json:"id"`
+	GitHub Endpoint `json:"github" binding:"required"`
+	GitLab Endpoint `json:"gitlab" binding:"required"`
+	// IntervalSeconds schedules periodic runs; 0 only runs on demand
+	IntervalSeconds int `json:"interval_seconds"`
+	// ConflictPolicy decides which side wins when both edited the same issue:
+	// "newest-wins" (default), "origin-wins", "github-wins", "gitlab-wins" or "skip"
+	ConflictPolicy string `json:"conflict_policy"`
+	// UserMapping maps GitHub usernames to GitLab usernames; mentions are
+	// rewritten with it in both directions
+	UserMapping map[string]string `json:"user_mapping,omitempty"`
+	// WebhookSecret verifies GitHub signatures and GitLab tokens; webhooks are
+	// ignored for mirrors without one
+	WebhookSecret string     `json:"webhook_secret,omitempty"`
+	Watermark     time.Time  `json:"watermark"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastJobID     string     `json:"last_job_id,omitempty"`
+}
+
+// MigrationEvent is a structured progress event streamed while a job runs
+type MigrationEvent struct {
+	Seq     int              `json:"seq"`
+	Type    string           `json:"type"`
+	JobID   string           `json:"job_id"`
+	IssueID int              `json:"issue_id,omitempty"`
+	NewID   int              `json:"new_id,omitempty"`
+	URL     string           `json:"url,omitempty"`
+	Message string           `json:"message,omitempty"`
+	Result  *MigrationResult `json:"result,omitempty"`
+	Time    time.Time        `json:"time"`
 }
 
 func ConvertGitHubIssue(issue *github.Issue) Issue {
@@ -72,13 +275,20 @@ func ConvertGitHubIssue(issue *github.Issue) Issue {
 		labels[i] = label.GetName()
 	}
 
+	var assignees []string
+	for _, assignee := range issue.Assignees {
+		assignees = append(assignees, assignee.GetLogin())
+	}
+
 	return Issue{
 		ID:          issue.GetNumber(),
 		Title:       issue.GetTitle(),
 		Description: issue.GetBody(),
 		State:       issue.GetState(),
 		Labels:      labels,
+		Milestone:   issue.GetMilestone().GetTitle(),
 		Author:      issue.User.GetLogin(),
+		Assignees:   assignees,
 		CreatedAt:   issue.GetCreatedAt().Time,
 		UpdatedAt:   issue.GetUpdatedAt().Time,
 		URL:         issue.GetHTMLURL(),
@@ -100,6 +310,19 @@ func ConvertGitLabIssue(issue *gitlab.Issue) Issue {
 		converted.Author = issue.Author.Username
 	}
 
+	for _, assignee := range issue.Assignees {
+		converted.Assignees = append(converted.Assignees, assignee.Username)
+	}
+
+	if issue.Milestone != nil {
+		converted.Milestone = issue.Milestone.Title
+	}
+
+	if issue.DueDate != nil {
+		dueDate := time.Time(*issue.DueDate)
+		converted.DueDate = &dueDate
+	}
+
 	if issue.CreatedAt != nil {
 		converted.CreatedAt = *issue.CreatedAt
 	}
@@ -109,4 +332,4 @@ func ConvertGitLabIssue(issue *gitlab.Issue) Issue {
 	}
 
 	return converted
-}
\ No newline at end of file
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/issue-migrator/backend/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// MirrorPair links a GitHub issue and a GitLab issue kept in sync by a mirror
+type MirrorPair struct {
+	GitHubNumber int    `json:"github_number"`
+	GitLabIID    int    `json:"gitlab_iid"`
+	Origin       string `json:"origin"` // platform the issue was first created on
+	// Last update times written or seen by the mirror; a side only counts as
+	// changed when its updated_at moves past these
+	GitHubSyncedAt time.Time `json:"github_synced_at"`
+	GitLabSyncedAt time.Time `json:"gitlab_synced_at"`
+	// Attachments maps the URL of an attachment on one side to the URL of its
+	// copy on the other, in both directions, so edits reuse the copies
+	Attachments map[string]string `json:"attachments,omitempty"`
+}
+
+// PutMirror stores a mirror configuration
+func (s *Store) PutMirror(mirror models.MirrorConfig) error {
+	data, err := json.Marshal(mirror)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(mirrorsBucket).Put([]byte(mirror.ID), data)
+	})
+}
+
+// GetMirror returns a mirror configuration, or nil if it does not exist
+func (s *Store) GetMirror(id string) (*models.MirrorConfig, error) {
+	var mirror *models.MirrorConfig
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(mirrorsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		mirror = &models.MirrorConfig{}
+		return json.Unmarshal(data, mirror)
+	})
+	return mirror, err
+}
+
+// UpdateMirror applies fn to a stored mirror configuration and saves it in one
+// transaction. It reports false, without calling fn, when the mirror does not
+// exist, so a run that ends after its mirror was deleted does not restore it.
+func (s *Store) UpdateMirror(id string, fn func(*models.MirrorConfig)) (bool, error) {
+	found := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(mirrorsBucket)
+		data := b.Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		var mirror models.MirrorConfig
+		if err := json.Unmarshal(data, &mirror); err != nil {
+			return err
+		}
+		fn(&mirror)
+		updated, err := json.Marshal(mirror)
+		if err != nil {
+			return err
+		}
+		found = true
+		return b.Put([]byte(id), updated)
+	})
+	return found, err
+}
+
+// ListMirrors returns every mirror configuration
+func (s *Store) ListMirrors() ([]models.MirrorConfig, error) {
+	var mirrors []models.MirrorConfig
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(mirrorsBucket).ForEach(func(_, v []byte) error {
+			var mirror models.MirrorConfig
+			if err := json.Unmarshal(v, &mirror); err != nil {
+				return err
+			}
+			mirrors = append(mirrors, mirror)
+			return nil
+		})
+	})
+	return mirrors, err
+}
+
+// DeleteMirror removes a mirror configuration and its issue pairs
+func (s *Store) DeleteMirror(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(mirrorsBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+		pairs := tx.Bucket(mirrorPairsBucket)
+		if pairs.Bucket([]byte(id)) == nil {
+			return nil
+		}
+		return pairs.DeleteBucket([]byte(id))
+	})
+}
+
+// GetMirrorPair looks up the pair containing the given issue of either platform
+func (s *Store) GetMirrorPair(mirrorID string, platform string, number int) (*MirrorPair, error) {
+	var pair *MirrorPair
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(mirrorPairsBucket).Bucket([]byte(mirrorID))
+		if b == nil {
+			return nil
+		}
+		data := b.Get(pairKey(platform, number))
+		if data == nil {
+			return nil
+		}
+		pair = &MirrorPair{}
+		return json.Unmarshal(data, pair)
+	})
+	return pair, err
+}
+
+// PutMirrorPair stores a pair under both of its issues; pairs of a mirror
+// that was deleted are not stored
+func (s *Store) PutMirrorPair(mirrorID string, pair *MirrorPair) error {
+	data, err := json.Marshal(pair)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(mirrorsBucket).Get([]byte(mirrorID)) == nil {
+			return nil
+		}
+		b, err := tx.Bucket(mirrorPairsBucket).CreateBucketIfNotExists([]byte(mirrorID))
+		if err != nil {
+			return err
+		}
+		if err := b.Put(pairKey("github", pair.GitHubNumber), data); err != nil {
+			return err
+		}
+		return b.Put(pairKey("gitlab", pair.GitLabIID), data)
+	})
+}
+
+func pairKey(platform string, number int) []byte {
+	return []byte(fmt.Sprintf("%s#%010d", platform, number))
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	issuesBucket      = []byte("issues")
+	watermarksBucket  = []byte("watermarks")
+	mirrorsBucket     = []byte("mirrors")
+	mirrorPairsBucket = []byte("mirror_pairs")
+)
+
+// IssueRecord is the persisted state of one source issue within a migration
+type IssueRecord struct {
+	SourceID    int               `json:"source_id"`
+	TargetID    int               `json:"target_id"`
+	TargetURL   string            `json:"target_url"`
+	Completed   bool              `json:"completed"`
+	BodyDone    bool              `json:"body_done"`   // the target body has its attachments and is queued for reference rewriting
+	Comments    map[int64]int64   `json:"comments"`    // source comment ID -> target comment ID
+	Attachments map[string]string `json:"attachments"` // source URL -> uploaded URL
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Store persists migration progress in an embedded BoltDB file so that
+// interrupted migrations can be resumed without creating duplicates
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the state database at path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{issuesBucket, watermarksBucket, mirrorsBucket, mirrorPairsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize state store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// GetIssue returns the record of a source issue, or nil if it was never migrated
+func (s *Store) GetIssue(migration string, sourceID int) (*IssueRecord, error) {
+	var record *IssueRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(issuesBucket).Bucket([]byte(migration))
+		if b == nil {
+			return nil
+		}
+		data := b.Get(issueKey(sourceID))
+		if data == nil {
+			return nil
+		}
+		record = &IssueRecord{}
+		return json.Unmarshal(data, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		record.ensureMaps()
+	}
+	return record, nil
+}
+
+// PutIssue stores the record of a source issue
+func (s *Store) PutIssue(migration string, record *IssueRecord) error {
+	record.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(issuesBucket).CreateBucketIfNotExists([]byte(migration))
+		if err != nil {
+			return err
+		}
+		return b.Put(issueKey(record.SourceID), data)
+	})
+}
+
+// ListIssues returns every record stored for a migration
+func (s *Store) ListIssues(migration string) ([]IssueRecord, error) {
+	var records []IssueRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(issuesBucket).Bucket([]byte(migration))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, v []byte) error {
+			var record IssueRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			record.ensureMaps()
+			records = append(records, record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// GetWatermark returns the time up to which a migration was last synchronized
+func (s *Store) GetWatermark(migration string) (time.Time, bool, error) {
+	var watermark time.Time
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(watermarksBucket).Get([]byte(migration))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return watermark.UnmarshalText(data)
+	})
+	return watermark, found, err
+}
+
+// PutWatermark stores the time up to which a migration was synchronized
+func (s *Store) PutWatermark(migration string, watermark time.Time) error {
+	data, err := watermark.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(watermarksBucket).Put([]byte(migration), data)
+	})
+}
+
+// NewIssueRecord returns an empty record for a source issue
+func NewIssueRecord(sourceID int) *IssueRecord {
+	record := &IssueRecord{SourceID: sourceID}
+	record.ensureMaps()
+	return record
+}
+
+func (r *IssueRecord) ensureMaps() {
+	if r.Comments == nil {
+		r.Comments = make(map[int64]int64)
+	}
+	if r.Attachments == nil {
+		r.Attachments = make(map[string]string)
+	}
+}
+
+// issueKey zero-pads IDs so records iterate in issue order
+func issueKey(sourceID int) []byte {
+	return []byte(fmt.Sprintf("%010d", sourceID))
+}
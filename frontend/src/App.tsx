@@ -4,8 +4,9 @@ import { Container, Tab, Tabs } from 'react-bootstrap';
 import SourceConfig from './components/SourceConfig';
 import IssueList from './components/IssueList';
 import MigrationProgress from './components/MigrationProgress';
-import type { Issue, MigrationConfig, MigrationResult } from './types';
-import { fetchGitHubIssues, fetchGitLabIssues, migrateIssues } from './services/api';
+import MigrationLog from './components/MigrationLog';
+import type { Issue, MigrationConfig, MigrationEvent, MigrationResult } from './types';
+import { fetchGitHubIssues, fetchGitLabIssues, fetchGiteaIssues, fetchJiraIssues, fetchBitbucketIssues, fetchAzureIssues, fetchArchiveIssues, migrateIssues } from './services/api';
 
 function App() {
   const [sourceConfig, setSourceConfig] = useState<MigrationConfig>({
@@ -31,6 +32,7 @@ function App() {
   const [sourceIssues, setSourceIssues] = useState<Issue[]>([]);
   const [selectedIssues, setSelectedIssues] = useState<number[]>([]);
   const [migrationResult, setMigrationResult] = useState<MigrationResult | null>(null);
+  const [migrationEvents, setMigrationEvents] = useState<MigrationEvent[]>([]);
   const [loading, setLoading] = useState(false);
   const [activeTab, setActiveTab] = useState<string>('configure');
 
@@ -40,6 +42,16 @@ function App() {
       let issues: Issue[];
       if (sourceConfig.type === 'github') {
         issues = await fetchGitHubIssues(sourceConfig);
+      } else if (sourceConfig.type === 'gitea') {
+        issues = await fetchGiteaIssues(sourceConfig);
+      } else if (sourceConfig.type === 'jira') {
+        issues = await fetchJiraIssues(sourceConfig);
+      } else if (sourceConfig.type === 'bitbucket') {
+        issues = await fetchBitbucketIssues(sourceConfig);
+      } else if (sourceConfig.type === 'azure') {
+        issues = await fetchAzureIssues(sourceConfig);
+      } else if (sourceConfig.type === 'archive') {
+        issues = await fetchArchiveIssues(sourceConfig);
       } else {
         issues = await fetchGitLabIssues(sourceConfig);
       }
@@ -61,13 +73,17 @@ function App() {
     }
 
     setLoading(true);
+    setMigrationEvents([]);
     try {
-      const result = await migrateIssues({
-        direction: `${sourceConfig.type}-to-${targetConfig.type}`,
-        source: sourceConfig,
-        target: targetConfig,
-        issueIds: selectedIssues,
-      });
+      const result = await migrateIssues(
+        {
+          direction: `${sourceConfig.type}-to-${targetConfig.type}`,
+          source: sourceConfig,
+          target: targetConfig,
+          issueIds: selectedIssues,
+        },
+        (event) => setMigrationEvents((events) => [...events, event]),
+      );
       setMigrationResult(result);
     } catch (error) {
       console.error('Migration failed:', error);
@@ -80,7 +96,7 @@ function App() {
   return (
     <Container className="py-2">
       <h1 className="mb-4">Issue Migrator</h1>
-      <p className="lead mb-4">Migrate issues between GitHub and GitLab platforms</p>
+      <p className="lead mb-4">Migrate issues from GitHub, GitLab, Gitea, Azure DevOps, Jira or Bitbucket to GitHub, GitLab, Gitea or Azure DevOps</p>
 
       <Tabs activeKey={activeTab} onSelect={(k) => k && setActiveTab(k)} className="mb-4">
         <Tab eventKey="configure" title="Configure">
@@ -114,6 +130,9 @@ function App() {
             onMigrate={handleMigrate}
             loading={loading}
           />
+          {migrationEvents.length > 0 && (
+            <MigrationLog events={migrationEvents} total={selectedIssues.length} />
+          )}
         </Tab>
 
         <Tab eventKey="results" title="Migration Results" disabled={!migrationResult}>
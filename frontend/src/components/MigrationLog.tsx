@@ -0,0 +1,68 @@
+import React from 'react';
+import { Card, ListGroup, ProgressBar } from 'react-bootstrap';
+import type { MigrationEvent } from '../types';
+
+interface MigrationLogProps {
+  events: MigrationEvent[];
+  total: number;
+}
+
+const MAX_VISIBLE_EVENTS = 200;
+
+const describeEvent = (event: MigrationEvent): string => {
+  switch (event.type) {
+    case 'issue_started':
+      return `Started issue #${event.issue_id}`;
+    case 'attachment_uploaded':
+      return `Uploaded attachment for issue #${event.issue_id}: ${event.url}`;
+    case 'comment_created':
+      return `Created comment on issue #${event.issue_id}`;
+    case 'issue_completed':
+      return `Migrated issue #${event.issue_id} to #${event.new_id}`;
+    case 'issue_failed':
+      return `Issue #${event.issue_id} failed: ${event.message}`;
+    case 'job_finished':
+      return 'Migration finished';
+    default:
+      return event.type;
+  }
+};
+
+const variantFor = (event: MigrationEvent): string | undefined => {
+  switch (event.type) {
+    case 'issue_completed':
+      return 'success';
+    case 'issue_failed':
+      return 'danger';
+    case 'job_finished':
+      return 'info';
+    default:
+      return undefined;
+  }
+};
+
+const MigrationLog: React.FC<MigrationLogProps> = ({ events, total }) => {
+  const done = events.filter((e) => e.type === 'issue_completed' || e.type === 'issue_failed').length;
+  const visible = events.slice(-MAX_VISIBLE_EVENTS).reverse();
+
+  return (
+    <Card className="mt-4">
+      <Card.Header>
+        Migration progress: {done} / {total} issues
+      </Card.Header>
+      <Card.Body>
+        <ProgressBar now={total > 0 ? (done / total) * 100 : 0} className="mb-3" />
+        <ListGroup variant="flush" style={{ maxHeight: '300px', overflowY: 'auto' }}>
+          {visible.map((event) => (
+            <ListGroup.Item key={event.seq} variant={variantFor(event)}>
+              <small className="text-muted me-2">{new Date(event.time).toLocaleTimeString()}</small>
+              {describeEvent(event)}
+            </ListGroup.Item>
+          ))}
+        </ListGroup>
+      </Card.Body>
+    </Card>
+  );
+};
+
+export default MigrationLog;
@@ -1,6 +1,7 @@
-import React from 'react';
+import React, { useState } from 'react';
 import { Form, Button } from 'react-bootstrap';
 import type { MigrationConfig } from '../types';
+import { uploadArchive } from '../services/api';
 
 interface SourceConfigProps {
   config: MigrationConfig;
@@ -17,10 +18,27 @@ const SourceConfig: React.FC<SourceConfigProps> = ({
   loading,
   isTarget = false,
 }) => {
+  const [uploading, setUploading] = useState(false);
+
   const handleChange = (field: keyof MigrationConfig, value: any) => {
     onChange({ ...config, [field]: value });
   };
 
+  const handleArchiveUpload = async (file: File | undefined) => {
+    if (!file) {
+      return;
+    }
+    setUploading(true);
+    try {
+      handleChange('archivePath', await uploadArchive(file));
+    } catch (error) {
+      console.error('Failed to upload archive:', error);
+      alert('Failed to upload archive. Please check that it was written by an export.');
+    } finally {
+      setUploading(false);
+    }
+  };
+
   return (
     <Form>
       <Form.Group className="mb-3">
@@ -31,13 +49,30 @@ const SourceConfig: React.FC<SourceConfigProps> = ({
         >
           <option value="github">GitHub</option>
           <option value="gitlab">GitLab</option>
+          <option value="gitea">Gitea / Forgejo</option>
+          <option value="azure">Azure DevOps Boards</option>
+          {!isTarget && <option value="jira">Jira</option>}
+          {!isTarget && <option value="bitbucket">Bitbucket Cloud</option>}
+          {!isTarget && <option value="archive">Offline Archive</option>}
         </Form.Select>
       </Form.Group>
 
-      {config.type === 'github' ? (
+      {config.type === 'gitea' && (
+        <Form.Group className="mb-3">
+          <Form.Label>Gitea URL</Form.Label>
+          <Form.Control
+            type="text"
+            placeholder="https://gitea.example.com"
+            value={config.baseUrl}
+            onChange={(e) => handleChange('baseUrl', e.target.value)}
+          />
+        </Form.Group>
+      )}
+
+      {(config.type === 'github' || config.type === 'gitea' || config.type === 'bitbucket') && (
         <>
           <Form.Group className="mb-3">
-            <Form.Label>Owner/Organization</Form.Label>
+            <Form.Label>{config.type === 'bitbucket' ? 'Workspace' : 'Owner/Organization'}</Form.Label>
             <Form.Control
               type="text"
               placeholder="e.g., octocat"
@@ -56,7 +91,9 @@ const SourceConfig: React.FC<SourceConfigProps> = ({
             />
           </Form.Group>
         </>
-      ) : (
+      )}
+
+      {config.type === 'gitlab' && (
         <>
           <Form.Group className="mb-3">
             <Form.Label>GitLab URL</Form.Label>
@@ -80,20 +117,159 @@ const SourceConfig: React.FC<SourceConfigProps> = ({
         </>
       )}
 
-      <Form.Group className="mb-3">
-        <Form.Label>Access Token</Form.Label>
-        <Form.Control
-          type="password"
-          placeholder="Personal access token"
-          value={config.token}
-          onChange={(e) => handleChange('token', e.target.value)}
-        />
-        <Form.Text className="text-muted">
-          {config.type === 'github' 
-            ? 'GitHub personal access token with repo scope'
-            : 'GitLab personal access token with api scope'}
-        </Form.Text>
-      </Form.Group>
+      {config.type === 'azure' && (
+        <>
+          <Form.Group className="mb-3">
+            <Form.Label>Azure DevOps Server URL (Optional)</Form.Label>
+            <Form.Control
+              type="text"
+              placeholder="Leave empty for https://dev.azure.com"
+              value={config.baseUrl}
+              onChange={(e) => handleChange('baseUrl', e.target.value)}
+            />
+          </Form.Group>
+
+          <Form.Group className="mb-3">
+            <Form.Label>Organization/Collection</Form.Label>
+            <Form.Control
+              type="text"
+              placeholder="e.g., contoso"
+              value={config.owner}
+              onChange={(e) => handleChange('owner', e.target.value)}
+            />
+          </Form.Group>
+
+          <Form.Group className="mb-3">
+            <Form.Label>Project</Form.Label>
+            <Form.Control
+              type="text"
+              placeholder="e.g., Fabrikam"
+              value={config.repo}
+              onChange={(e) => handleChange('repo', e.target.value)}
+            />
+          </Form.Group>
+
+          {isTarget && (
+            <Form.Group className="mb-3">
+              <Form.Label>Work Item Type (Optional)</Form.Label>
+              <Form.Control
+                type="text"
+                placeholder="Issue"
+                value={config.workItemType || ''}
+                onChange={(e) => handleChange('workItemType', e.target.value)}
+              />
+              <Form.Text className="text-muted">
+                Created for issues without a "type:" label naming a work item type of the project
+              </Form.Text>
+            </Form.Group>
+          )}
+        </>
+      )}
+
+      {config.type === 'jira' && (
+        <>
+          <Form.Group className="mb-3">
+            <Form.Label>Jira URL</Form.Label>
+            <Form.Control
+              type="text"
+              placeholder="https://your-team.atlassian.net"
+              value={config.baseUrl}
+              onChange={(e) => handleChange('baseUrl', e.target.value)}
+            />
+          </Form.Group>
+
+          <Form.Group className="mb-3">
+            <Form.Label>Project Key</Form.Label>
+            <Form.Control
+              type="text"
+              placeholder="e.g., PROJ"
+              value={config.projectKey || ''}
+              onChange={(e) => handleChange('projectKey', e.target.value)}
+            />
+          </Form.Group>
+
+          <Form.Group className="mb-3">
+            <Form.Label>JQL Filter (Optional)</Form.Label>
+            <Form.Control
+              type="text"
+              placeholder="e.g., status != Done AND labels = migrate"
+              value={config.jql || ''}
+              onChange={(e) => handleChange('jql', e.target.value)}
+            />
+          </Form.Group>
+
+          <Form.Group className="mb-3">
+            <Form.Label>Email (Jira Cloud)</Form.Label>
+            <Form.Control
+              type="email"
+              placeholder="Leave empty for Jira Server / Data Center"
+              value={config.email || ''}
+              onChange={(e) => handleChange('email', e.target.value)}
+            />
+          </Form.Group>
+        </>
+      )}
+
+      {config.type === 'bitbucket' && (
+        <Form.Group className="mb-3">
+          <Form.Label>Username (App Password)</Form.Label>
+          <Form.Control
+            type="text"
+            placeholder="Leave empty to use an access token"
+            value={config.username || ''}
+            onChange={(e) => handleChange('username', e.target.value)}
+          />
+        </Form.Group>
+      )}
+
+      {config.type === 'archive' && (
+        <>
+          <Form.Group className="mb-3">
+            <Form.Label>Upload Archive</Form.Label>
+            <Form.Control
+              type="file"
+              accept=".zip"
+              disabled={uploading}
+              onChange={(e) => handleArchiveUpload((e.target as HTMLInputElement).files?.[0])}
+            />
+          </Form.Group>
+
+          <Form.Group className="mb-3">
+            <Form.Label>Archive Path</Form.Label>
+            <Form.Control
+              type="text"
+              placeholder="Path of the archive on the backend host"
+              value={config.archivePath || ''}
+              onChange={(e) => handleChange('archivePath', e.target.value)}
+            />
+          </Form.Group>
+        </>
+      )}
+
+      {config.type !== 'archive' && (
+        <Form.Group className="mb-3">
+          <Form.Label>Access Token</Form.Label>
+          <Form.Control
+            type="password"
+            placeholder="Personal access token"
+            value={config.token}
+            onChange={(e) => handleChange('token', e.target.value)}
+          />
+          <Form.Text className="text-muted">
+            {config.type === 'github'
+              ? 'GitHub personal access token with repo scope'
+              : config.type === 'gitea'
+                ? 'Gitea access token with write:issue and read:repository scopes'
+                : config.type === 'jira'
+                  ? 'Jira Cloud API token, or a personal access token for Jira Server / Data Center'
+                  : config.type === 'bitbucket'
+                    ? 'Bitbucket app password, or a repository access token; optional for public repositories'
+                    : config.type === 'azure'
+                      ? 'Azure DevOps personal access token with Work Items (Read & Write) scope'
+                      : 'GitLab personal access token with api scope'}
+          </Form.Text>
+        </Form.Group>
+      )}
 
       {config.type === 'github' && (
         <Form.Group className="mb-3">
@@ -129,7 +305,13 @@ const SourceConfig: React.FC<SourceConfigProps> = ({
         <Button
           variant="primary"
           onClick={onFetch}
-          disabled={loading || !config.token || (config.type === 'github' ? !config.owner || !config.repo : !config.projectId)}
+          disabled={loading || uploading || (!config.token && config.type !== 'bitbucket' && config.type !== 'archive') || (config.type === 'gitlab'
+            ? !config.projectId
+            : config.type === 'jira'
+              ? !config.baseUrl || !config.projectKey
+              : config.type === 'archive'
+                ? !config.archivePath
+                : !config.owner || !config.repo)}
         >
           {loading ? 'Loading...' : 'Fetch Issues'}
         </Button>
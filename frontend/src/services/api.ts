@@ -1,7 +1,8 @@
 import axios from 'axios';
-import type { Issue, MigrationConfig, MigrationResult, MigrateRequest } from '../types';
+import type { Issue, MigrationConfig, MigrationEvent, MigrationEventType, MigrationJob, MigrationResult, MigrateRequest } from '../types';
 
 const API_BASE_URL = 'http://localhost:8080/api';
+const JOB_POLL_INTERVAL_MS = 2000;
 
 export const fetchGitHubIssues = async (config: MigrationConfig): Promise<Issue[]> => {
   const response = await axios.post(`${API_BASE_URL}/github/issues`, {
@@ -21,7 +22,91 @@ export const fetchGitLabIssues = async (config: MigrationConfig): Promise<Issue[
   return response.data.issues;
 };
 
-export const migrateIssues = async (request: MigrateRequest): Promise<MigrationResult> => {
+export const fetchGiteaIssues = async (config: MigrationConfig): Promise<Issue[]> => {
+  const response = await axios.post(`${API_BASE_URL}/gitea/issues`, {
+    base_url: config.baseUrl,
+    owner: config.owner,
+    repo: config.repo,
+    token: config.token,
+  });
+  return response.data.issues;
+};
+
+export const fetchJiraIssues = async (config: MigrationConfig): Promise<Issue[]> => {
+  const response = await axios.post(`${API_BASE_URL}/jira/issues`, {
+    base_url: config.baseUrl,
+    project_key: config.projectKey,
+    jql: config.jql || '',
+    email: config.email || '',
+    token: config.token,
+  });
+  return response.data.issues;
+};
+
+export const fetchBitbucketIssues = async (config: MigrationConfig): Promise<Issue[]> => {
+  const response = await axios.post(`${API_BASE_URL}/bitbucket/issues`, {
+    owner: config.owner,
+    repo: config.repo,
+    username: config.username || '',
+    token: config.token,
+  });
+  return response.data.issues;
+};
+
+export const fetchAzureIssues = async (config: MigrationConfig): Promise<Issue[]> => {
+  const response = await axios.post(`${API_BASE_URL}/azure/issues`, {
+    base_url: config.baseUrl,
+    owner: config.owner,
+    repo: config.repo,
+    token: config.token,
+  });
+  return response.data.issues;
+};
+
+export const fetchArchiveIssues = async (config: MigrationConfig): Promise<Issue[]> => {
+  const response = await axios.post(`${API_BASE_URL}/archive/issues`, {
+    archive_path: config.archivePath,
+  });
+  return response.data.issues;
+};
+
+// uploadArchive stores an exported archive on the backend and returns its path
+export const uploadArchive = async (file: File): Promise<string> => {
+  const form = new FormData();
+  form.append('file', file);
+  const response = await axios.post(`${API_BASE_URL}/archives`, form);
+  return response.data.archive_path;
+};
+
+// parseUserMapping converts a CSV or JSON user mapping file into a userMapping
+export const parseUserMapping = async (file: File): Promise<Record<string, string>> => {
+  const form = new FormData();
+  form.append('file', file);
+  const response = await axios.post(`${API_BASE_URL}/user-mapping`, form);
+  return response.data.user_mapping;
+};
+
+// parseLabelMapping converts a CSV or JSON label mapping file into a labelMapping
+export const parseLabelMapping = async (file: File): Promise<Record<string, string>> => {
+  const form = new FormData();
+  form.append('file', file);
+  const response = await axios.post(`${API_BASE_URL}/label-mapping`, form);
+  return response.data.label_mapping;
+};
+
+const MIGRATION_EVENT_TYPES: MigrationEventType[] = [
+  'issue_started',
+  'attachment_uploaded',
+  'comment_created',
+  'issue_completed',
+  'issue_failed',
+  'job_finished',
+];
+
+export const migrateIssues = async (
+  request: MigrateRequest,
+  onEvent?: (event: MigrationEvent) => void,
+): Promise<MigrationResult> => {
   const payload = {
     direction: request.direction,
     source: {
@@ -32,6 +117,11 @@ export const migrateIssues = async (request: MigrateRequest): Promise<MigrationR
       base_url: request.source.baseUrl,
       token: request.source.token,
       session: request.source.session || '',
+      project_key: request.source.projectKey,
+      jql: request.source.jql,
+      email: request.source.email,
+      username: request.source.username,
+      archive_path: request.source.archivePath,
     },
     target: {
       type: request.target.type,
@@ -41,11 +131,62 @@ export const migrateIssues = async (request: MigrateRequest): Promise<MigrationR
       base_url: request.target.baseUrl,
       token: request.target.token,
       session: request.target.session || '',
+      work_item_type: request.target.workItemType,
     },
     issue_ids: request.issueIds,
+    resume: request.resume ?? false,
+    mode: request.mode ?? 'migrate',
+    since: request.since,
+    dry_run: request.dryRun ?? false,
+    user_mapping: request.userMapping,
+    match_users_by_email: request.matchUsersByEmail ?? false,
+    label_mapping: request.labelMapping,
+    sync_labels: request.syncLabels ?? false,
+    translation: request.translation,
+    system_notes: request.systemNotes,
+    history: request.history ?? false,
   };
 
-  // Use main endpoint with image handling
+  // Use main endpoint with image handling; it returns a job ID right away
   const response = await axios.post(`${API_BASE_URL}/migrate`, payload);
+  const jobId: string = response.data.job_id;
+  const unsubscribe = onEvent ? subscribeToJobEvents(jobId, onEvent) : undefined;
+
+  try {
+    // Poll the job until the background migration has finished
+    for (;;) {
+      const job = await fetchMigrationJob(jobId);
+      if (job.status === 'completed') {
+        if (!job.result) {
+          throw new Error(job.error || 'Migration job finished without a result');
+        }
+        return job.result;
+      }
+      await new Promise((resolve) => setTimeout(resolve, JOB_POLL_INTERVAL_MS));
+    }
+  } finally {
+    unsubscribe?.();
+  }
+};
+
+// subscribeToJobEvents streams live progress events; returns a function that closes the stream
+export const subscribeToJobEvents = (
+  jobId: string,
+  onEvent: (event: MigrationEvent) => void,
+): (() => void) => {
+  const source = new EventSource(`${API_BASE_URL}/jobs/${jobId}/events`);
+  const handler = (message: MessageEvent) => {
+    const event: MigrationEvent = JSON.parse(message.data);
+    onEvent(event);
+    if (event.type === 'job_finished') {
+      source.close();
+    }
+  };
+  MIGRATION_EVENT_TYPES.forEach((type) => source.addEventListener(type, handler));
+  return () => source.close();
+};
+
+export const fetchMigrationJob = async (jobId: string): Promise<MigrationJob> => {
+  const response = await axios.get(`${API_BASE_URL}/jobs/${jobId}`);
   return response.data;
 };
\ No newline at end of file
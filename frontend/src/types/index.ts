@@ -4,20 +4,29 @@ export interface Issue {
   description: string;
   state: string;
   labels: string[];
+  milestone?: string;
   author: string;
+  assignees?: string[];
+  due_date?: string;
   created_at: string;
   updated_at: string;
   url: string;
 }
 
 export interface MigrationConfig {
-  type: 'github' | 'gitlab';
+  type: 'github' | 'gitlab' | 'gitea' | 'jira' | 'bitbucket' | 'azure' | 'archive';
   owner: string;
   repo: string;
   token: string;
   session?: string; // GitHub session cookie for file uploads
   baseUrl: string;
   projectId: number;
+  projectKey?: string; // Jira project key
+  jql?: string; // optional JQL narrowing the Jira issues
+  email?: string; // Jira Cloud account email; empty for Jira Server
+  username?: string; // Bitbucket username for app password authentication
+  workItemType?: string; // Azure DevOps work item type created for issues without a type label
+  archivePath?: string; // offline archive on the backend host
 }
 
 export interface MigrationStatus {
@@ -30,6 +39,91 @@ export interface MigrationStatus {
 export interface MigrationResult {
   success: MigrationStatus[];
   failed: MigrationStatus[];
+  unmapped_users?: string[]; // source authors and assignees without a target user
+  unmapped_mentions?: string[]; // other @mentioned source users without a target user
+}
+
+export interface IssueProgress {
+  original_id: number;
+  status: 'pending' | 'running' | 'success' | 'failed';
+  new_id?: number;
+  new_url?: string;
+  error?: string;
+}
+
+export interface MigrationJob {
+  id: string;
+  status: 'queued' | 'running' | 'completed';
+  direction: string;
+  total: number;
+  completed: number;
+  progress: IssueProgress[];
+  result?: MigrationResult;
+  error?: string;
+  plan?: MigrationPlan; // set by dry runs
+  created_at: string;
+  started_at?: string;
+  finished_at?: string;
+}
+
+export interface PlannedAttachment {
+  url: string;
+  filename: string;
+  size: number;
+  error?: string;
+}
+
+export interface PlannedComment {
+  original_id: number;
+  action: 'create' | 'skip';
+  body?: string;
+}
+
+export interface PlannedIssue {
+  original_id: number;
+  action: 'create' | 'update' | 'resume' | 'skip';
+  target_id?: number;
+  title: string;
+  body: string;
+  labels: string[];
+  assignees: string[];
+  milestone?: string;
+  state: string;
+  comments: PlannedComment[];
+  attachments: PlannedAttachment[];
+  history?: string; // rendered history comment
+  error?: string;
+}
+
+export interface MigrationPlan {
+  issues: PlannedIssue[];
+  labels_to_create: string[];
+  labels_to_update: string[];
+  milestones_to_create: string[];
+  unmapped_users: string[];
+  unmapped_mentions: string[];
+  attachment_count: number;
+  attachment_bytes: number;
+}
+
+export type MigrationEventType =
+  | 'issue_started'
+  | 'attachment_uploaded'
+  | 'comment_created'
+  | 'issue_completed'
+  | 'issue_failed'
+  | 'job_finished';
+
+export interface MigrationEvent {
+  seq: number;
+  type: MigrationEventType;
+  job_id: string;
+  issue_id?: number;
+  new_id?: number;
+  url?: string;
+  message?: string;
+  result?: MigrationResult;
+  time: string;
 }
 
 export interface MigrateRequest {
@@ -37,4 +131,25 @@ export interface MigrateRequest {
   source: MigrationConfig;
   target: MigrationConfig;
   issueIds: number[];
+  resume?: boolean; // skip work recorded by an interrupted earlier run
+  mode?: 'migrate' | 'sync';
+  since?: string; // RFC 3339 sync watermark; defaults to the stored one
+  dryRun?: boolean; // plan the migration without writing to the target
+  userMapping?: Record<string, string>; // source username -> target username
+  matchUsersByEmail?: boolean;
+  labelMapping?: Record<string, string>; // source label -> target label
+  syncLabels?: boolean; // copy label colors and descriptions before migrating
+  translation?: TranslationRules;
+  systemNotes?: 'log' | 'skip'; // GitLab system notes: one activity log comment per issue, or none
+  history?: boolean; // add a history comment with the timeline events of each issue
+}
+
+export type FieldTranslation = 'header' | 'label' | 'front_matter' | 'none';
+
+// TranslationRules carry GitLab-only issue fields to other trackers and back
+export interface TranslationRules {
+  scoped_labels?: 'keep' | 'flatten' | 'value';
+  weight?: FieldTranslation;
+  health_status?: FieldTranslation;
+  epic?: FieldTranslation;
 }
\ No newline at end of file